@@ -0,0 +1,99 @@
+package authz
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenLBrace
+	tokenRBrace
+	tokenColon
+	tokenPipe
+	tokenHash
+	tokenEquals
+	tokenPlus
+	tokenArrow
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of schema"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+var punctuation = map[string]tokenKind{
+	"->": tokenArrow,
+	"{":  tokenLBrace,
+	"}":  tokenRBrace,
+	":":  tokenColon,
+	"|":  tokenPipe,
+	"#":  tokenHash,
+	"=":  tokenEquals,
+	"+":  tokenPlus,
+}
+
+type lexer struct {
+	src  string
+	pos  int
+	line int
+}
+
+func newLexer(src string) *lexer {
+	return &lexer{src: src, line: 1}
+}
+
+// lex returns the next token, skipping white space and comments.
+func (l *lexer) lex() (token, error) {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == '\n':
+			l.line++
+			l.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			l.pos++
+		case strings.HasPrefix(l.src[l.pos:], "//"):
+			end := strings.IndexByte(l.src[l.pos:], '\n')
+			if end < 0 {
+				end = len(l.src) - l.pos
+			}
+			l.pos += end
+		default:
+			return l.token()
+		}
+	}
+	return token{kind: tokenEOF, line: l.line}, nil
+}
+
+func (l *lexer) token() (token, error) {
+	rest := l.src[l.pos:]
+	for text, kind := range punctuation {
+		if strings.HasPrefix(rest, text) {
+			l.pos += len(text)
+			return token{kind: kind, text: text, line: l.line}, nil
+		}
+	}
+	end := strings.IndexFunc(rest, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	if end < 0 {
+		end = len(rest)
+	}
+	if end == 0 {
+		return token{}, fmt.Errorf("line %d: unexpected %q", l.line, rest[:1])
+	}
+	l.pos += end
+	return token{kind: tokenIdent, text: rest[:end], line: l.line}, nil
+}
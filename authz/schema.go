@@ -0,0 +1,352 @@
+// Package authz parses the schema language that describes the namespaces of
+// the relationship-based authorization engine in services.AuthzService.
+//
+// A schema is a list of definitions, one per namespace:
+//
+//	definition folder {
+//	    relation owner: organisation
+//	    relation viewer: user | team#member
+//	    permission view = viewer + owner->view
+//	}
+//
+// A relation names the subjects its relationships may point at: objects of
+// a namespace, or with #, the subjects of another object's relation or
+// permission. A permission is the union (+) of relations and permissions of
+// the same object and, with ->, of a permission of the objects a relation
+// points at. Comments start with // and run to the end of the line.
+package authz
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+)
+
+// Default is the schema used until one is written. It describes the
+// organisation memberships, which every schema may build on.
+//
+//go:embed default.schema
+var Default string
+
+var identifier = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// IsIdentifier reports whether name may name a namespace, relation or
+// permission.
+func IsIdentifier(name string) bool {
+	return identifier.MatchString(name)
+}
+
+type Schema struct {
+	// Definitions are in the order they were written.
+	Definitions []*Definition
+	byName      map[string]*Definition
+}
+
+type Definition struct {
+	Name        string
+	Relations   []*Relation
+	Permissions []*Permission
+}
+
+type Relation struct {
+	Name  string
+	Types []SubjectType
+}
+
+// SubjectType is a namespace whose objects a relation may point at or, if
+// Relation is not empty, whose objects' relation or permission it may point
+// at.
+type SubjectType struct {
+	Namespace string
+	Relation  string
+}
+
+func (t SubjectType) String() string {
+	if t.Relation == "" {
+		return t.Namespace
+	}
+	return t.Namespace + "#" + t.Relation
+}
+
+type Permission struct {
+	Name  string
+	Terms []Term
+}
+
+// Term is a relation or permission of the same object or, if Arrow is not
+// empty, the permission or relation Arrow of the objects Relation points at.
+type Term struct {
+	Relation string
+	Arrow    string
+}
+
+func (t Term) String() string {
+	if t.Arrow == "" {
+		return t.Relation
+	}
+	return t.Relation + "->" + t.Arrow
+}
+
+// Definition returns the definition of namespace, or nil.
+func (s *Schema) Definition(namespace string) *Definition {
+	return s.byName[namespace]
+}
+
+// Relation returns the relation called name, or nil.
+func (d *Definition) Relation(name string) *Relation {
+	for _, r := range d.Relations {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// Permission returns the permission called name, or nil.
+func (d *Definition) Permission(name string) *Permission {
+	for _, p := range d.Permissions {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// Defines reports whether name is a relation or a permission of d.
+func (d *Definition) Defines(name string) bool {
+	return d.Relation(name) != nil || d.Permission(name) != nil
+}
+
+// Allows reports whether r's relationships may point at subjects of t.
+func (r *Relation) Allows(t SubjectType) bool {
+	for _, allowed := range r.Types {
+		if allowed == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse parses and checks a schema. Every name a schema uses must be
+// defined in it, and an arrow must lead to a permission or relation of at
+// least one of the namespaces its relation points at.
+func Parse(src string) (*Schema, error) {
+	p := parser{lexer: newLexer(src)}
+	schema, err := p.schema()
+	if err != nil {
+		return nil, err
+	}
+	if err := schema.check(); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func (s *Schema) check() error {
+	for _, def := range s.Definitions {
+		for _, r := range def.Relations {
+			for _, t := range r.Types {
+				target := s.Definition(t.Namespace)
+				if target == nil {
+					return fmt.Errorf("%s#%s: unknown namespace %q", def.Name, r.Name, t.Namespace)
+				}
+				if t.Relation != "" && !target.Defines(t.Relation) {
+					return fmt.Errorf("%s#%s: %s does not define %q", def.Name, r.Name, t.Namespace, t.Relation)
+				}
+			}
+		}
+		for _, p := range def.Permissions {
+			for _, term := range p.Terms {
+				if err := s.checkTerm(def, p, term); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) checkTerm(def *Definition, p *Permission, term Term) error {
+	if term.Arrow == "" {
+		if !def.Defines(term.Relation) {
+			return fmt.Errorf("%s#%s: %s does not define %q", def.Name, p.Name, def.Name, term.Relation)
+		}
+		return nil
+	}
+	r := def.Relation(term.Relation)
+	if r == nil {
+		return fmt.Errorf("%s#%s: %q before -> must be a relation of %s", def.Name, p.Name, term.Relation, def.Name)
+	}
+	for _, t := range r.Types {
+		if t.Relation == "" && s.Definition(t.Namespace).Defines(term.Arrow) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s#%s: no namespace %s points at defines %q", def.Name, p.Name, term.Relation, term.Arrow)
+}
+
+type parser struct {
+	*lexer
+	tok token
+}
+
+func (p *parser) next() error {
+	tok, err := p.lex()
+	p.tok = tok
+	return err
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", p.tok.line, fmt.Sprintf(format, args...))
+}
+
+// expect consumes a token of kind, or fails naming what was wanted.
+func (p *parser) expect(kind tokenKind, what string) (string, error) {
+	if p.tok.kind != kind {
+		return "", p.errorf("expected %s, found %s", what, p.tok)
+	}
+	text := p.tok.text
+	return text, p.next()
+}
+
+func (p *parser) name(what string) (string, error) {
+	if p.tok.kind == tokenIdent && !IsIdentifier(p.tok.text) {
+		return "", p.errorf("%q is not a valid name; use lowercase letters, digits and underscores", p.tok.text)
+	}
+	return p.expect(tokenIdent, what)
+}
+
+func (p *parser) keyword(word string) error {
+	if p.tok.kind != tokenIdent || p.tok.text != word {
+		return p.errorf("expected %q, found %s", word, p.tok)
+	}
+	return p.next()
+}
+
+func (p *parser) schema() (*Schema, error) {
+	s := &Schema{byName: map[string]*Definition{}}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	for p.tok.kind != tokenEOF {
+		def, err := p.definition()
+		if err != nil {
+			return nil, err
+		}
+		if s.byName[def.Name] != nil {
+			return nil, fmt.Errorf("namespace %q is defined twice", def.Name)
+		}
+		s.Definitions = append(s.Definitions, def)
+		s.byName[def.Name] = def
+	}
+	return s, nil
+}
+
+func (p *parser) definition() (*Definition, error) {
+	if err := p.keyword("definition"); err != nil {
+		return nil, err
+	}
+	name, err := p.name("a namespace name")
+	if err != nil {
+		return nil, err
+	}
+	def := &Definition{Name: name}
+	if _, err := p.expect(tokenLBrace, "{"); err != nil {
+		return nil, err
+	}
+	for p.tok.kind != tokenRBrace {
+		switch {
+		case p.tok.kind == tokenIdent && p.tok.text == "relation":
+			r, err := p.relation()
+			if err != nil {
+				return nil, err
+			}
+			if def.Defines(r.Name) {
+				return nil, fmt.Errorf("%s: %q is defined twice", def.Name, r.Name)
+			}
+			def.Relations = append(def.Relations, r)
+		case p.tok.kind == tokenIdent && p.tok.text == "permission":
+			perm, err := p.permission()
+			if err != nil {
+				return nil, err
+			}
+			if def.Defines(perm.Name) {
+				return nil, fmt.Errorf("%s: %q is defined twice", def.Name, perm.Name)
+			}
+			def.Permissions = append(def.Permissions, perm)
+		default:
+			return nil, p.errorf("expected relation, permission or }, found %s", p.tok)
+		}
+	}
+	return def, p.next()
+}
+
+func (p *parser) relation() (*Relation, error) {
+	if err := p.keyword("relation"); err != nil {
+		return nil, err
+	}
+	name, err := p.name("a relation name")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(tokenColon, ":"); err != nil {
+		return nil, err
+	}
+	r := &Relation{Name: name}
+	for {
+		var t SubjectType
+		if t.Namespace, err = p.name("a namespace"); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == tokenHash {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if t.Relation, err = p.name("a relation or permission"); err != nil {
+				return nil, err
+			}
+		}
+		r.Types = append(r.Types, t)
+		if p.tok.kind != tokenPipe {
+			return r, nil
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) permission() (*Permission, error) {
+	if err := p.keyword("permission"); err != nil {
+		return nil, err
+	}
+	name, err := p.name("a permission name")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(tokenEquals, "="); err != nil {
+		return nil, err
+	}
+	perm := &Permission{Name: name}
+	for {
+		var term Term
+		if term.Relation, err = p.name("a relation or permission"); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == tokenArrow {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if term.Arrow, err = p.name("a relation or permission"); err != nil {
+				return nil, err
+			}
+		}
+		perm.Terms = append(perm.Terms, term)
+		if p.tok.kind != tokenPlus {
+			return perm, nil
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+}
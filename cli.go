@@ -0,0 +1,515 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joshua468/user-authentication/models"
+	"github.com/joshua468/user-authentication/services"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage: user-authentication <command> [arguments]
+
+Commands:
+  serve                                 run the HTTP server (default)
+  config                                print the effective configuration
+  migrate up | down [n] | status        manage database migrations
+  user create | list                    create or list users
+  user disable | enable <userId>        block or restore a user's logins
+  user reset-password <userId>          set a password to change at next sign-in
+  user revoke-sessions <userId>         invalidate every token issued so far
+  user grant-admin | revoke-admin <userId>
+                                        change access to the admin API
+  org create | list                     create or list organisations
+  org add-member <orgId> <userId>       add a user to an organisation
+  org set-role <orgId> <userId> <role>  change a member's role
+  keys rotate                           create a new token signing key
+  client create | list                  manage token introspection clients
+  client grant-scope | revoke-scope <clientId> <scope>
+                                        change a client's access, e.g. authz.write
+  client allow-exchange | deny-exchange <clientId>
+                                        change a client's token exchange policy
+  client policies <clientId>            list a client's token exchange policies
+
+Configuration is read from CONFIG_FILE (.yaml or .toml), ENV_FILE (default
+.env) and the environment, in increasing order of precedence.
+
+Every command except serve and config accepts -json for machine-readable
+output.`)
+}
+
+// command is a subcommand's flag set with the shared -json flag.
+type command struct {
+	*flag.FlagSet
+	json *bool
+}
+
+func newCommand(name, args string) *command {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	cmd := &command{FlagSet: fs, json: fs.Bool("json", false, "print JSON output")}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return cmd
+}
+
+// requireArgs parses args and exits with usage unless exactly n positional
+// arguments remain.
+func (cmd *command) requireArgs(args []string, n int) {
+	cmd.Parse(args)
+	if cmd.NArg() != n {
+		cmd.Usage()
+		os.Exit(2)
+	}
+}
+
+// output prints v as JSON when -json was given, and otherwise calls text.
+func (cmd *command) output(v interface{}, text func(w *tabwriter.Writer)) {
+	if *cmd.json {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			fatal("Failed to encode output", "error", err)
+		}
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	text(w)
+	w.Flush()
+}
+
+func subcommand(args []string, group string) (string, []string) {
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+	return group + " " + args[0], args[1:]
+}
+
+func cliContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), time.Minute)
+}
+
+func generatePassword() string {
+	password, err := services.GeneratePassword()
+	if err != nil {
+		fatal("Failed to generate password", "error", err)
+	}
+	return password
+}
+
+type userOutput struct {
+	UserID                string     `json:"userId"`
+	FirstName             string     `json:"firstName"`
+	LastName              string     `json:"lastName"`
+	Email                 string     `json:"email"`
+	Phone                 string     `json:"phone"`
+	DisabledAt            *time.Time `json:"disabledAt"`
+	IsAdmin               bool       `json:"isAdmin"`
+	PasswordResetRequired bool       `json:"passwordResetRequired"`
+	Password              string     `json:"password,omitempty"`
+}
+
+func newUserOutput(user *models.User) userOutput {
+	return userOutput{
+		UserID:                user.UserID,
+		FirstName:             user.FirstName,
+		LastName:              user.LastName,
+		Email:                 user.Email,
+		Phone:                 user.Phone,
+		DisabledAt:            user.DisabledAt,
+		IsAdmin:               user.IsAdmin,
+		PasswordResetRequired: user.PasswordResetRequired,
+	}
+}
+
+func writeUsers(w *tabwriter.Writer, users []userOutput) {
+	fmt.Fprintln(w, "USER ID\tNAME\tEMAIL\tPHONE\tSTATUS")
+	for _, u := range users {
+		status := "active"
+		if u.DisabledAt != nil {
+			status = "disabled"
+		}
+		if u.IsAdmin {
+			status += ", admin"
+		}
+		if u.PasswordResetRequired {
+			status += ", password reset"
+		}
+		fmt.Fprintf(w, "%s\t%s %s\t%s\t%s\t%s\n", u.UserID, u.FirstName, u.LastName, u.Email, u.Phone, status)
+	}
+}
+
+// Changes made by runUser are recorded in the audit log as services.CLIActor.
+func runUser(args []string) {
+	svc := newServices()
+	users := svc.users
+	ctx, cancel := cliContext()
+	defer cancel()
+
+	name, args := subcommand(args, "user")
+	switch name {
+	case "user create":
+		cmd := newCommand(name, "")
+		firstName := cmd.String("first-name", "", "first name (required)")
+		lastName := cmd.String("last-name", "", "last name (required)")
+		email := cmd.String("email", "", "email address (required)")
+		password := cmd.String("password", "", "password; generated and printed if empty")
+		phone := cmd.String("phone", "", "phone number")
+		cmd.requireArgs(args, 0)
+		if *firstName == "" || *lastName == "" || *email == "" {
+			cmd.Usage()
+			os.Exit(2)
+		}
+
+		generated := ""
+		if *password == "" {
+			generated = generatePassword()
+			*password = generated
+		}
+		user, err := users.Create(ctx, services.CreateUserInput{
+			FirstName: *firstName,
+			LastName:  *lastName,
+			Email:     *email,
+			Password:  *password,
+			Phone:     *phone,
+		})
+		if err != nil {
+			fatal("Failed to create user", "error", err)
+		}
+		out := newUserOutput(user)
+		out.Password = generated
+		cmd.output(out, func(w *tabwriter.Writer) {
+			writeUsers(w, []userOutput{out})
+			if generated != "" {
+				fmt.Fprintf(w, "\ngenerated password: %s\n", generated)
+			}
+		})
+
+	case "user list":
+		cmd := newCommand(name, "")
+		cmd.requireArgs(args, 0)
+		list, err := users.List(ctx)
+		if err != nil {
+			fatal("Failed to list users", "error", err)
+		}
+		out := make([]userOutput, 0, len(list))
+		for i := range list {
+			out = append(out, newUserOutput(&list[i]))
+		}
+		cmd.output(out, func(w *tabwriter.Writer) { writeUsers(w, out) })
+
+	case "user disable", "user enable":
+		cmd := newCommand(name, "<userId>")
+		cmd.requireArgs(args, 1)
+		user, err := svc.admin.SetDisabled(ctx, services.CLIActor, cmd.Arg(0), name == "user disable")
+		if err != nil {
+			fatal("Failed to update user", "error", err)
+		}
+		out := newUserOutput(user)
+		cmd.output(out, func(w *tabwriter.Writer) { writeUsers(w, []userOutput{out}) })
+
+	case "user reset-password":
+		cmd := newCommand(name, "<userId>")
+		password := cmd.String("password", "", "new password; generated and printed if empty")
+		cmd.requireArgs(args, 1)
+
+		generated, err := svc.admin.ResetPassword(ctx, services.CLIActor, cmd.Arg(0), *password)
+		if err != nil {
+			fatal("Failed to reset password", "error", err)
+		}
+		out := map[string]string{"userId": cmd.Arg(0)}
+		if generated != "" {
+			out["password"] = generated
+		}
+		cmd.output(out, func(w *tabwriter.Writer) {
+			fmt.Fprintf(w, "password reset for %s\n", cmd.Arg(0))
+			if generated != "" {
+				fmt.Fprintf(w, "generated password: %s\n", generated)
+			}
+		})
+
+	case "user revoke-sessions":
+		cmd := newCommand(name, "<userId>")
+		cmd.requireArgs(args, 1)
+		if err := svc.admin.RevokeSessions(ctx, services.CLIActor, cmd.Arg(0)); err != nil {
+			fatal("Failed to revoke sessions", "error", err)
+		}
+		out := map[string]string{"userId": cmd.Arg(0)}
+		cmd.output(out, func(w *tabwriter.Writer) {
+			fmt.Fprintf(w, "revoked sessions for %s\n", cmd.Arg(0))
+		})
+
+	case "user grant-admin", "user revoke-admin":
+		cmd := newCommand(name, "<userId>")
+		cmd.requireArgs(args, 1)
+		user, err := svc.admin.SetAdmin(ctx, services.CLIActor, cmd.Arg(0), name == "user grant-admin")
+		if err != nil {
+			fatal("Failed to update user", "error", err)
+		}
+		out := newUserOutput(user)
+		cmd.output(out, func(w *tabwriter.Writer) { writeUsers(w, []userOutput{out}) })
+
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+type orgOutput struct {
+	OrgID       string `json:"orgId"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func newOrgOutputs(orgs []models.Organisation) []orgOutput {
+	out := make([]orgOutput, 0, len(orgs))
+	for _, org := range orgs {
+		out = append(out, orgOutput{OrgID: org.OrgID, Name: org.Name, Description: org.Description})
+	}
+	return out
+}
+
+func writeOrgs(w *tabwriter.Writer, orgs []orgOutput) {
+	fmt.Fprintln(w, "ORG ID\tNAME\tDESCRIPTION")
+	for _, org := range orgs {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", org.OrgID, org.Name, org.Description)
+	}
+}
+
+func runOrg(args []string) {
+	svc := newServices()
+	orgs := svc.orgs
+	ctx, cancel := cliContext()
+	defer cancel()
+
+	name, args := subcommand(args, "org")
+	switch name {
+	case "org create":
+		cmd := newCommand(name, "")
+		orgName := cmd.String("name", "", "organisation name (required)")
+		description := cmd.String("description", "", "organisation description")
+		owner := cmd.String("owner", "", "userId of the first member (required)")
+		cmd.requireArgs(args, 0)
+		if *orgName == "" || *owner == "" {
+			cmd.Usage()
+			os.Exit(2)
+		}
+
+		org, err := orgs.Create(ctx, *owner, services.CreateOrgInput{Name: *orgName, Description: *description})
+		if err != nil {
+			fatal("Failed to create organisation", "error", err)
+		}
+		out := newOrgOutputs([]models.Organisation{*org})
+		cmd.output(out[0], func(w *tabwriter.Writer) { writeOrgs(w, out) })
+
+	case "org add-member":
+		cmd := newCommand(name, "<orgId> <userId>")
+		cmd.requireArgs(args, 2)
+		if err := orgs.AddMember(ctx, cmd.Arg(0), cmd.Arg(1)); err != nil {
+			fatal("Failed to add member", "error", err)
+		}
+		out := map[string]string{"orgId": cmd.Arg(0), "userId": cmd.Arg(1)}
+		cmd.output(out, func(w *tabwriter.Writer) {
+			fmt.Fprintf(w, "added %s to %s\n", cmd.Arg(1), cmd.Arg(0))
+		})
+
+	case "org set-role":
+		cmd := newCommand(name, "<orgId> <userId> <role>")
+		cmd.requireArgs(args, 3)
+		if err := svc.roles.SetRole(ctx, cmd.Arg(0), cmd.Arg(1), cmd.Arg(2)); err != nil {
+			fatal("Failed to set role", "error", err)
+		}
+		out := map[string]string{"orgId": cmd.Arg(0), "userId": cmd.Arg(1), "role": cmd.Arg(2)}
+		cmd.output(out, func(w *tabwriter.Writer) {
+			fmt.Fprintf(w, "%s is now %s of %s\n", cmd.Arg(1), cmd.Arg(2), cmd.Arg(0))
+		})
+
+	case "org list":
+		cmd := newCommand(name, "")
+		member := cmd.String("user", "", "only list organisations this userId belongs to")
+		cmd.requireArgs(args, 0)
+
+		var list []models.Organisation
+		var err error
+		if *member != "" {
+			list, err = orgs.ListForUser(ctx, *member)
+		} else {
+			list, err = orgs.List(ctx)
+		}
+		if err != nil {
+			fatal("Failed to list organisations", "error", err)
+		}
+		out := newOrgOutputs(list)
+		cmd.output(out, func(w *tabwriter.Writer) { writeOrgs(w, out) })
+
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+func runKeys(args []string) {
+	keys := newServices().keys
+	ctx, cancel := cliContext()
+	defer cancel()
+
+	name, args := subcommand(args, "keys")
+	switch name {
+	case "keys rotate":
+		cmd := newCommand(name, "")
+		cmd.requireArgs(args, 0)
+		key, err := keys.Rotate(ctx, loadTokenOptions())
+		if err != nil {
+			fatal("Failed to rotate signing key", "error", err)
+		}
+		out := map[string]interface{}{"keyId": key.KeyID, "notBefore": key.NotBefore}
+		cmd.output(out, func(w *tabwriter.Writer) {
+			fmt.Fprintf(w, "created signing key %s\n", key.KeyID)
+			fmt.Fprintf(w, "signs new tokens from %s\n", key.NotBefore.Format(time.RFC3339))
+		})
+
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+type clientOutput struct {
+	ClientID  string    `json:"clientId"`
+	Name      string    `json:"name"`
+	Scopes    string    `json:"scopes"`
+	Disabled  bool      `json:"disabled"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func newClientOutput(client *models.ServiceClient) clientOutput {
+	return clientOutput{
+		ClientID:  client.ClientID,
+		Name:      client.Name,
+		Scopes:    client.Scopes,
+		Disabled:  client.DisabledAt != nil,
+		CreatedAt: client.CreatedAt,
+	}
+}
+
+func writeClients(w *tabwriter.Writer, clients []clientOutput) {
+	fmt.Fprintln(w, "CLIENT ID\tNAME\tSCOPES\tDISABLED\tCREATED")
+	for _, client := range clients {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%s\n", client.ClientID, client.Name, client.Scopes, client.Disabled, client.CreatedAt.Format(time.RFC3339))
+	}
+}
+
+func writeExchangePolicies(w *tabwriter.Writer, policies []models.ExchangePolicy) {
+	fmt.Fprintln(w, "AUDIENCE\tSCOPES\tMAX TTL")
+	for _, policy := range policies {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", policy.Audience, policy.Scopes, policy.MaxTTL())
+	}
+}
+
+func runClient(args []string) {
+	clients := newServices().clients
+	ctx, cancel := cliContext()
+	defer cancel()
+
+	name, args := subcommand(args, "client")
+	switch name {
+	case "client create":
+		cmd := newCommand(name, "")
+		clientName := cmd.String("name", "", "name of the calling service (required)")
+		cmd.requireArgs(args, 0)
+		if *clientName == "" {
+			cmd.Usage()
+			os.Exit(2)
+		}
+
+		client, secret, err := clients.Create(ctx, *clientName)
+		if err != nil {
+			fatal("Failed to create client", "error", err)
+		}
+		out := map[string]string{"clientId": client.ClientID, "name": client.Name, "clientSecret": secret}
+		cmd.output(out, func(w *tabwriter.Writer) {
+			fmt.Fprintf(w, "created client %s (%s)\n", client.ClientID, client.Name)
+			fmt.Fprintf(w, "secret: %s\n", secret)
+			fmt.Fprintln(w, "the secret is not stored and cannot be shown again")
+		})
+
+	case "client list":
+		cmd := newCommand(name, "")
+		cmd.requireArgs(args, 0)
+		list, err := clients.List(ctx)
+		if err != nil {
+			fatal("Failed to list clients", "error", err)
+		}
+		out := make([]clientOutput, 0, len(list))
+		for i := range list {
+			out = append(out, newClientOutput(&list[i]))
+		}
+		cmd.output(out, func(w *tabwriter.Writer) { writeClients(w, out) })
+
+	case "client grant-scope", "client revoke-scope":
+		cmd := newCommand(name, "<clientId> <scope>")
+		cmd.requireArgs(args, 2)
+		client, err := clients.SetScope(ctx, cmd.Arg(0), cmd.Arg(1), name == "client grant-scope")
+		if err != nil {
+			fatal("Failed to change client scopes", "error", err, "scopes", strings.Join(models.ClientScopes, " "))
+		}
+		out := []clientOutput{newClientOutput(client)}
+		cmd.output(out[0], func(w *tabwriter.Writer) { writeClients(w, out) })
+
+	case "client allow-exchange":
+		cmd := newCommand(name, "<clientId>")
+		audience := cmd.String("audience", "", "audience of the exchanged tokens (required)")
+		scope := cmd.String("scope", "", "space-separated scopes the client may request (required)")
+		ttl := cmd.Duration("ttl", 5*time.Minute, "maximum lifetime of the exchanged tokens")
+		cmd.requireArgs(args, 1)
+		if *audience == "" || strings.TrimSpace(*scope) == "" {
+			cmd.Usage()
+			os.Exit(2)
+		}
+
+		policy, err := clients.SetExchangePolicy(ctx, cmd.Arg(0), *audience, strings.Fields(*scope), *ttl)
+		if err != nil {
+			fatal("Failed to save exchange policy", "error", err)
+		}
+		out := []models.ExchangePolicy{*policy}
+		cmd.output(policy, func(w *tabwriter.Writer) { writeExchangePolicies(w, out) })
+
+	case "client deny-exchange":
+		cmd := newCommand(name, "<clientId>")
+		audience := cmd.String("audience", "", "audience to stop exchanging tokens for (required)")
+		cmd.requireArgs(args, 1)
+		if *audience == "" {
+			cmd.Usage()
+			os.Exit(2)
+		}
+
+		if err := clients.RemoveExchangePolicy(ctx, cmd.Arg(0), *audience); err != nil {
+			fatal("Failed to remove exchange policy", "error", err)
+		}
+		cmd.output(map[string]string{"clientId": cmd.Arg(0), "audience": *audience}, func(w *tabwriter.Writer) {
+			fmt.Fprintf(w, "client %s may no longer exchange tokens for %s\n", cmd.Arg(0), *audience)
+		})
+
+	case "client policies":
+		cmd := newCommand(name, "<clientId>")
+		cmd.requireArgs(args, 1)
+		policies, err := clients.ExchangePolicies(ctx, cmd.Arg(0))
+		if err != nil {
+			fatal("Failed to list exchange policies", "error", err)
+		}
+		cmd.output(policies, func(w *tabwriter.Writer) { writeExchangePolicies(w, policies) })
+
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,517 @@
+// Package client is a typed Go client for the user-authentication HTTP API.
+//
+//	c := client.New("https://auth.example.com", client.Options{
+//		Email:    "svc@example.com",
+//		Password: os.Getenv("SVC_PASSWORD"),
+//	})
+//	orgs, err := c.ListOrganisations(ctx)
+//
+// With credentials set the client logs in on first use and again shortly
+// before the access token expires or whenever the server rejects it.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// refreshMargin is how long before expiry a token is replaced.
+const refreshMargin = 30 * time.Second
+
+var ErrNoCredentials = errors.New("client has no access token or credentials")
+
+type Options struct {
+	// HTTPClient defaults to a client with a 30 second timeout.
+	HTTPClient *http.Client
+	// MaxRetries is how many times a request that failed with a network
+	// error or 5xx is retried, waiting RetryBackoff and then doubling it.
+	// GET requests are retried on network errors and any 5xx; other methods
+	// only on 502, 503 and 504, where the server is unlikely to have acted on
+	// them. A network error may come after the server acted, so retrying a
+	// write could repeat it.
+	MaxRetries   int
+	RetryBackoff time.Duration
+	// Token is an access token to start with.
+	Token string
+	// Email and Password, when set, are used to log in whenever the client
+	// needs a new token.
+	Email    string
+	Password string
+}
+
+func DefaultOptions() Options {
+	return Options{
+		HTTPClient:   &http.Client{Timeout: 30 * time.Second},
+		MaxRetries:   3,
+		RetryBackoff: 200 * time.Millisecond,
+	}
+}
+
+type Client struct {
+	baseURL string
+	opts    Options
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// New returns a client for the API at baseURL. Zero fields in opts take
+// their values from DefaultOptions, except MaxRetries.
+func New(baseURL string, opts Options) *Client {
+	defaults := DefaultOptions()
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = defaults.HTTPClient
+	}
+	if opts.RetryBackoff == 0 {
+		opts.RetryBackoff = defaults.RetryBackoff
+	}
+	c := &Client{baseURL: strings.TrimSuffix(baseURL, "/"), opts: opts}
+	c.setToken(opts.Token)
+	return c
+}
+
+// Token returns the current access token, which may be empty.
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+func (c *Client) Register(ctx context.Context, req RegisterRequest) (*AuthResult, error) {
+	var result AuthResult
+	if err := c.do(ctx, http.MethodPost, "/api/auth/register", req, &result, false); err != nil {
+		return nil, err
+	}
+	c.setToken(result.AccessToken)
+	return &result, nil
+}
+
+// Login authenticates and keeps the returned token. It does not store the
+// credentials; set Options.Email and Options.Password for automatic
+// refresh.
+func (c *Client) Login(ctx context.Context, email, password string) (*AuthResult, error) {
+	var result AuthResult
+	body := map[string]string{"email": email, "password": password}
+	if err := c.do(ctx, http.MethodPost, "/api/auth/login", body, &result, false); err != nil {
+		return nil, err
+	}
+	c.setToken(result.AccessToken)
+	return &result, nil
+}
+
+// ChangePassword replaces the caller's password, completing a forced reset,
+// and keeps the unrestricted token returned.
+func (c *Client) ChangePassword(ctx context.Context, current, password string) (*AuthResult, error) {
+	var result AuthResult
+	body := map[string]string{"currentPassword": current, "newPassword": password}
+	if err := c.do(ctx, http.MethodPost, "/api/auth/change-password", body, &result, true); err != nil {
+		return nil, err
+	}
+	c.setToken(result.AccessToken)
+	return &result, nil
+}
+
+// SwitchOrganisation replaces the client's token with one scoped to orgID,
+// or with an unscoped one when orgID is empty. Automatic refresh logs in
+// again and so returns to an unscoped token.
+func (c *Client) SwitchOrganisation(ctx context.Context, orgID string) (*OrganisationToken, error) {
+	var result OrganisationToken
+	body := map[string]string{"orgId": orgID}
+	if err := c.do(ctx, http.MethodPost, "/api/auth/switch-organisation", body, &result, true); err != nil {
+		return nil, err
+	}
+	c.setToken(result.AccessToken)
+	return &result, nil
+}
+
+func (c *Client) GetUser(ctx context.Context, userID string) (*User, error) {
+	var user User
+	if err := c.do(ctx, http.MethodGet, "/api/users/"+url.PathEscape(userID), nil, &user, true); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ListOrganisations returns every organisation the caller belongs to,
+// fetching as many pages as needed.
+func (c *Client) ListOrganisations(ctx context.Context) ([]Organisation, error) {
+	var orgs []Organisation
+	opts := PageOptions{Limit: 100}
+	for {
+		page, err := c.ListOrganisationsPage(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, page.Items...)
+		if page.NextCursor == "" {
+			return orgs, nil
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
+
+// ListOrganisationsPage returns one page of the caller's organisations.
+func (c *Client) ListOrganisationsPage(ctx context.Context, opts PageOptions) (*OrganisationPage, error) {
+	page := &OrganisationPage{}
+	env := &envelope{Data: &page.Items, Pagination: &page.Pagination}
+	if err := c.doEnvelope(ctx, http.MethodGet, "/api/organisations/"+opts.query(), nil, env, true); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// ListOrganisationMembers returns one page of an organisation's members.
+// The caller must be a member.
+func (c *Client) ListOrganisationMembers(ctx context.Context, orgID string, opts MemberOptions) (*MemberPage, error) {
+	page := &MemberPage{}
+	env := &envelope{Data: &page.Items, Pagination: &page.Pagination}
+	path := "/api/organisations/" + url.PathEscape(orgID) + "/users" + opts.query()
+	if err := c.doEnvelope(ctx, http.MethodGet, path, nil, env, true); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+func (c *Client) GetOrganisation(ctx context.Context, orgID string) (*Organisation, error) {
+	var org Organisation
+	if err := c.do(ctx, http.MethodGet, "/api/organisations/"+url.PathEscape(orgID), nil, &org, true); err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// CreateOrganisation creates an organisation with the caller as its first
+// member.
+func (c *Client) CreateOrganisation(ctx context.Context, req CreateOrganisationRequest) (*Organisation, error) {
+	var org Organisation
+	if err := c.do(ctx, http.MethodPost, "/api/organisations/", req, &org, true); err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (c *Client) AddOrganisationMember(ctx context.Context, orgID, userID string) error {
+	body := map[string]string{"userId": userID}
+	return c.do(ctx, http.MethodPost, "/api/organisations/"+url.PathEscape(orgID)+"/users", body, nil, true)
+}
+
+func rolesPath(orgID string) string {
+	return "/api/organisations/" + url.PathEscape(orgID) + "/roles"
+}
+
+// ListRoles returns the built-in roles followed by orgID's custom roles.
+func (c *Client) ListRoles(ctx context.Context, orgID string) ([]Role, error) {
+	var roles []Role
+	if err := c.do(ctx, http.MethodGet, rolesPath(orgID), nil, &roles, true); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (c *Client) CreateRole(ctx context.Context, orgID string, req RoleRequest) (*Role, error) {
+	var role Role
+	if err := c.do(ctx, http.MethodPost, rolesPath(orgID), req, &role, true); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (c *Client) UpdateRole(ctx context.Context, orgID, roleID string, req RoleRequest) (*Role, error) {
+	var role Role
+	if err := c.do(ctx, http.MethodPatch, rolesPath(orgID)+"/"+url.PathEscape(roleID), req, &role, true); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (c *Client) DeleteRole(ctx context.Context, orgID, roleID string) error {
+	return c.do(ctx, http.MethodDelete, rolesPath(orgID)+"/"+url.PathEscape(roleID), nil, nil, true)
+}
+
+// AssignRole gives a member of orgID a built-in or custom role.
+func (c *Client) AssignRole(ctx context.Context, orgID, userID, role string) error {
+	body := map[string]string{"role": role}
+	path := "/api/organisations/" + url.PathEscape(orgID) + "/users/" + url.PathEscape(userID) + "/role"
+	return c.do(ctx, http.MethodPut, path, body, nil, true)
+}
+
+func teamsPath(orgID string) string {
+	return "/api/organisations/" + url.PathEscape(orgID) + "/teams"
+}
+
+func teamPath(orgID, teamID string) string {
+	return teamsPath(orgID) + "/" + url.PathEscape(teamID)
+}
+
+// ListTeams returns orgID's teams by name.
+func (c *Client) ListTeams(ctx context.Context, orgID string) ([]Team, error) {
+	var teams []Team
+	if err := c.do(ctx, http.MethodGet, teamsPath(orgID), nil, &teams, true); err != nil {
+		return nil, err
+	}
+	return teams, nil
+}
+
+func (c *Client) GetTeam(ctx context.Context, orgID, teamID string) (*Team, error) {
+	var team Team
+	if err := c.do(ctx, http.MethodGet, teamPath(orgID, teamID), nil, &team, true); err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+func (c *Client) CreateTeam(ctx context.Context, orgID string, req TeamRequest) (*Team, error) {
+	var team Team
+	if err := c.do(ctx, http.MethodPost, teamsPath(orgID), req, &team, true); err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+func (c *Client) UpdateTeam(ctx context.Context, orgID, teamID string, req TeamRequest) (*Team, error) {
+	var team Team
+	if err := c.do(ctx, http.MethodPatch, teamPath(orgID, teamID), req, &team, true); err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+func (c *Client) DeleteTeam(ctx context.Context, orgID, teamID string) error {
+	return c.do(ctx, http.MethodDelete, teamPath(orgID, teamID), nil, nil, true)
+}
+
+// ListTeamMembers returns the direct members of a team.
+func (c *Client) ListTeamMembers(ctx context.Context, orgID, teamID string) ([]TeamMember, error) {
+	var members []TeamMember
+	if err := c.do(ctx, http.MethodGet, teamPath(orgID, teamID)+"/members", nil, &members, true); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// SetTeamMember adds a member of orgID to a team, or changes whether they
+// maintain it.
+func (c *Client) SetTeamMember(ctx context.Context, orgID, teamID, userID string, maintainer bool) error {
+	body := map[string]bool{"maintainer": maintainer}
+	return c.do(ctx, http.MethodPut, teamPath(orgID, teamID)+"/members/"+url.PathEscape(userID), body, nil, true)
+}
+
+func (c *Client) RemoveTeamMember(ctx context.Context, orgID, teamID, userID string) error {
+	return c.do(ctx, http.MethodDelete, teamPath(orgID, teamID)+"/members/"+url.PathEscape(userID), nil, nil, true)
+}
+
+// AssignTeamRole grants a team's members, and those of the teams nested in
+// it, a built-in or custom role. An empty role takes the team's role away.
+func (c *Client) AssignTeamRole(ctx context.Context, orgID, teamID, role string) error {
+	if role == "" {
+		return c.do(ctx, http.MethodDelete, teamPath(orgID, teamID)+"/role", nil, nil, true)
+	}
+	body := map[string]string{"role": role}
+	return c.do(ctx, http.MethodPut, teamPath(orgID, teamID)+"/role", body, nil, true)
+}
+
+// do sends a request and decodes the envelope's data into out.
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}, authenticated bool) error {
+	var env *envelope
+	if out != nil {
+		env = &envelope{Data: out}
+	}
+	return c.doEnvelope(ctx, method, path, in, env, authenticated)
+}
+
+// doEnvelope sends a request and decodes the response into env, if not nil.
+// An authenticated request that is rejected with 401 is retried once with a
+// fresh token when the client has credentials.
+func (c *Client) doEnvelope(ctx context.Context, method, path string, in interface{}, env *envelope, authenticated bool) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	var token string
+	if authenticated {
+		var err error
+		if token, err = c.validToken(ctx); err != nil {
+			return err
+		}
+	}
+	res, err := c.send(ctx, method, path, body, token)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode == http.StatusUnauthorized && authenticated && c.canLogin() {
+		res.Body.Close()
+		if token, err = c.refresh(ctx, token); err != nil {
+			return err
+		}
+		if res, err = c.send(ctx, method, path, body, token); err != nil {
+			return err
+		}
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return decodeError(res)
+	}
+	if env == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(env)
+}
+
+// send performs one logical request, retrying network errors and 5xx
+// responses as described on Options.MaxRetries.
+func (c *Client) send(ctx context.Context, method, path string, body []byte, token string) (*http.Response, error) {
+	backoff := c.opts.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		res, err := c.opts.HTTPClient.Do(req)
+		if attempt >= c.opts.MaxRetries || !retryable(method, res, err) {
+			return res, err
+		}
+		if res != nil {
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func retryable(method string, res *http.Response, err error) bool {
+	idempotent := method == http.MethodGet || method == http.MethodHead
+	if err != nil {
+		// A cancelled or expired context is not worth retrying.
+		return idempotent && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch {
+	case res.StatusCode < 500:
+		return false
+	case idempotent:
+		return true
+	default:
+		return res.StatusCode == http.StatusBadGateway ||
+			res.StatusCode == http.StatusServiceUnavailable ||
+			res.StatusCode == http.StatusGatewayTimeout
+	}
+}
+
+func decodeError(res *http.Response) error {
+	apiErr := &APIError{StatusCode: res.StatusCode, RequestID: res.Header.Get("X-Request-ID")}
+	var body errorBody
+	if err := json.NewDecoder(res.Body).Decode(&body); err == nil {
+		apiErr.Message = body.Error
+		if apiErr.Message == "" {
+			apiErr.Message = body.Errors
+		}
+		if body.RequestID != "" {
+			apiErr.RequestID = body.RequestID
+		}
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(res.StatusCode)
+	}
+	return apiErr
+}
+
+func (c *Client) canLogin() bool {
+	return c.opts.Email != "" && c.opts.Password != ""
+}
+
+// validToken returns the current token, logging in first if there is none
+// or it is about to expire and the client has credentials.
+func (c *Client) validToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	token, expiry := c.token, c.expiry
+	c.mu.Unlock()
+
+	fresh := token != "" && (expiry.IsZero() || time.Until(expiry) > refreshMargin)
+	if fresh || (token != "" && !c.canLogin()) {
+		return token, nil
+	}
+	if !c.canLogin() {
+		return "", ErrNoCredentials
+	}
+	return c.refresh(ctx, token)
+}
+
+// refresh logs in again unless another goroutine already replaced stale.
+func (c *Client) refresh(ctx context.Context, stale string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != stale && c.token != "" {
+		return c.token, nil
+	}
+
+	var result AuthResult
+	body, _ := json.Marshal(map[string]string{"email": c.opts.Email, "password": c.opts.Password})
+	res, err := c.send(ctx, http.MethodPost, "/api/auth/login", body, "")
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return "", fmt.Errorf("refreshing access token: %w", decodeError(res))
+	}
+	if err := json.NewDecoder(res.Body).Decode(&envelope{Data: &result}); err != nil {
+		return "", err
+	}
+	c.token, c.expiry = result.AccessToken, tokenExpiry(result.AccessToken)
+	return c.token, nil
+}
+
+func (c *Client) setToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token, c.expiry = token, tokenExpiry(token)
+}
+
+// tokenExpiry reads the exp claim without verifying the token, which is the
+// server's job. It returns the zero time if there is none.
+func tokenExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if json.Unmarshal(payload, &claims) != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
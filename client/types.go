@@ -0,0 +1,213 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type RegisterRequest struct {
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+	Phone     string `json:"phone,omitempty"`
+}
+
+type User struct {
+	UserID    string `json:"userId"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Email     string `json:"email"`
+	Phone     string `json:"phone"`
+}
+
+// AuthResult is returned by Register, Login and ChangePassword. The client
+// keeps the access token and sends it on later calls. PasswordResetRequired
+// is set by Login after an admin reset the password; the token then only
+// allows ChangePassword.
+type AuthResult struct {
+	AccessToken           string `json:"accessToken"`
+	User                  User   `json:"user"`
+	PasswordResetRequired bool   `json:"passwordResetRequired"`
+}
+
+// OrganisationToken is returned by SwitchOrganisation. OrgID and Role are
+// empty for an unscoped token.
+type OrganisationToken struct {
+	AccessToken string `json:"accessToken"`
+	OrgID       string `json:"orgId"`
+	Role        string `json:"role"`
+}
+
+type Organisation struct {
+	OrgID       string    `json:"orgId"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// PageOptions selects a page of a listing. Zero values use the server's
+// defaults; Cursor is the NextCursor of the previous page.
+type PageOptions struct {
+	Limit  int
+	Cursor string
+	Search string
+	// Sort is "createdAt" (the default) or "-createdAt"; organisation
+	// listings also take "name" and "-name". Cursor must come from a page
+	// with the same Sort.
+	Sort string
+}
+
+func (o PageOptions) query() string {
+	return encodeQuery(o.values())
+}
+
+func (o PageOptions) values() url.Values {
+	values := url.Values{}
+	if o.Limit > 0 {
+		values.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Cursor != "" {
+		values.Set("cursor", o.Cursor)
+	}
+	if o.Search != "" {
+		values.Set("q", o.Search)
+	}
+	if o.Sort != "" {
+		values.Set("sort", o.Sort)
+	}
+	return values
+}
+
+func (o MemberOptions) query() string {
+	values := o.values()
+	if o.Role != "" {
+		values.Set("role", o.Role)
+	}
+	return encodeQuery(values)
+}
+
+func encodeQuery(values url.Values) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return "?" + values.Encode()
+}
+
+// Pagination describes where a page sits in a listing. NextCursor is empty
+// on the last page.
+type Pagination struct {
+	Limit      int    `json:"limit"`
+	Total      int64  `json:"total"`
+	NextCursor string `json:"nextCursor"`
+}
+
+type OrganisationPage struct {
+	Items []Organisation
+	Pagination
+}
+
+// Member is a user as listed to the other members of an organisation.
+type Member struct {
+	UserID    string `json:"userId"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Email     string `json:"email"`
+	Phone     string `json:"phone"`
+	Role      string `json:"role"`
+}
+
+// MemberOptions selects a page of members. Search matches first name, last
+// name or email; Role is a built-in or custom role.
+type MemberOptions struct {
+	PageOptions
+	Role string
+}
+
+type MemberPage struct {
+	Items []Member
+	Pagination
+}
+
+type CreateOrganisationRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// Role is a built-in role, which has no RoleID, or a custom one.
+type Role struct {
+	RoleID      string   `json:"roleId"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+	BuiltIn     bool     `json:"builtIn"`
+}
+
+// RoleRequest creates a role, or for UpdateRole changes the fields that are
+// not nil.
+type RoleRequest struct {
+	Name        *string  `json:"name,omitempty"`
+	Description *string  `json:"description,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// Team is a team of an organisation. ParentID is nil for a top-level team
+// and Role for a team without a role.
+type Team struct {
+	TeamID      string    `json:"teamId"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	ParentID    *string   `json:"parentId"`
+	Role        *string   `json:"role"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// TeamRequest creates a team, or for UpdateTeam changes the fields that are
+// not nil. An empty ParentID moves the team to the top level.
+type TeamRequest struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	ParentID    *string `json:"parentId,omitempty"`
+}
+
+// TeamMember is a direct member of a team.
+type TeamMember struct {
+	UserID     string `json:"userId"`
+	FirstName  string `json:"firstName"`
+	LastName   string `json:"lastName"`
+	Email      string `json:"email"`
+	Phone      string `json:"phone"`
+	Maintainer bool   `json:"maintainer"`
+}
+
+// APIError is a non-2xx response. Message is the server's "error" field, or
+// for validation failures its "errors" field.
+type APIError struct {
+	StatusCode int
+	Message    string
+	RequestID  string
+}
+
+func (e *APIError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("%d %s (request %s)", e.StatusCode, e.Message, e.RequestID)
+	}
+	return fmt.Sprintf("%d %s", e.StatusCode, e.Message)
+}
+
+type envelope struct {
+	Status     string      `json:"status"`
+	Message    string      `json:"message"`
+	Data       interface{} `json:"data"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+type errorBody struct {
+	Error     string `json:"error"`
+	Errors    string `json:"errors"`
+	RequestID string `json:"requestId"`
+}
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Config is the complete service configuration. Every field can be set in
+// the config file (yaml/toml keys) and overridden by the environment variable
+// named in its env tag. Fields tagged secret are redacted by Redacted.
+type Config struct {
+	Server      ServerConfig   `yaml:"server" toml:"server"`
+	Database    DatabaseConfig `yaml:"database" toml:"database"`
+	JWT         JWTConfig      `yaml:"jwt" toml:"jwt"`
+	Log         LogConfig      `yaml:"log" toml:"log"`
+	Tracing     TracingConfig  `yaml:"tracing" toml:"tracing"`
+	AutoMigrate bool           `yaml:"autoMigrate" toml:"autoMigrate" env:"AUTO_MIGRATE"`
+}
+
+type ServerConfig struct {
+	Port int `yaml:"port" toml:"port" env:"PORT"`
+	// GRPCPort serves the gRPC API; 0 disables it.
+	GRPCPort          int      `yaml:"grpcPort" toml:"grpcPort" env:"GRPC_PORT"`
+	ReadTimeout       Duration `yaml:"readTimeout" toml:"readTimeout" env:"SERVER_READ_TIMEOUT"`
+	ReadHeaderTimeout Duration `yaml:"readHeaderTimeout" toml:"readHeaderTimeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	WriteTimeout      Duration `yaml:"writeTimeout" toml:"writeTimeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout       Duration `yaml:"idleTimeout" toml:"idleTimeout" env:"SERVER_IDLE_TIMEOUT"`
+	// ShutdownTimeout bounds how long in-flight requests may take to drain
+	// after SIGTERM.
+	ShutdownTimeout Duration `yaml:"shutdownTimeout" toml:"shutdownTimeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+}
+
+// DatabaseConfig selects the database. URL takes precedence and picks the
+// driver from its scheme (postgres, sqlite or mysql); without it the
+// discrete Postgres settings are used.
+type DatabaseConfig struct {
+	URL      string `yaml:"url" toml:"url" env:"DATABASE_URL" secret:"url"`
+	Host     string `yaml:"host" toml:"host" env:"DB_HOST"`
+	Port     int    `yaml:"port" toml:"port" env:"DB_PORT"`
+	User     string `yaml:"user" toml:"user" env:"DB_USER"`
+	Password string `yaml:"password" toml:"password" env:"DB_PASSWORD" secret:"true"`
+	Name     string `yaml:"name" toml:"name" env:"DB_NAME"`
+	SSLMode  string `yaml:"sslMode" toml:"sslMode" env:"DB_SSLMODE"`
+
+	MaxOpenConns    int      `yaml:"maxOpenConns" toml:"maxOpenConns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int      `yaml:"maxIdleConns" toml:"maxIdleConns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime Duration `yaml:"connMaxLifetime" toml:"connMaxLifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime Duration `yaml:"connMaxIdleTime" toml:"connMaxIdleTime" env:"DB_CONN_MAX_IDLE_TIME"`
+
+	// ConnectRetries is how many times a failed initial connection is
+	// retried, waiting ConnectBackoff and then doubling it each time.
+	ConnectRetries int      `yaml:"connectRetries" toml:"connectRetries" env:"DB_CONNECT_RETRIES"`
+	ConnectBackoff Duration `yaml:"connectBackoff" toml:"connectBackoff" env:"DB_CONNECT_BACKOFF"`
+}
+
+type JWTConfig struct {
+	Secret     string   `yaml:"secret" toml:"secret" env:"JWT_SECRET" secret:"true"`
+	Issuer     string   `yaml:"issuer" toml:"issuer" env:"JWT_ISSUER"`
+	Audience   string   `yaml:"audience" toml:"audience" env:"JWT_AUDIENCE"`
+	CookieName string   `yaml:"cookieName" toml:"cookieName" env:"JWT_COOKIE_NAME"`
+	TTL        Duration `yaml:"ttl" toml:"ttl" env:"JWT_TTL"`
+	Leeway     Duration `yaml:"leeway" toml:"leeway" env:"JWT_LEEWAY"`
+	// LegacyUntil ends the window in which pre-migration tokens are accepted.
+	// It is a fixed date, set at deploy time; when zero they are refused.
+	LegacyUntil time.Time `yaml:"legacyUntil" toml:"legacyUntil" env:"JWT_LEGACY_UNTIL"`
+}
+
+// LogConfig sets the minimum level ("debug", "info", "warn" or "error") and
+// the output format ("json" or "text").
+type LogConfig struct {
+	Level  string `yaml:"level" toml:"level" env:"LOG_LEVEL"`
+	Format string `yaml:"format" toml:"format" env:"LOG_FORMAT"`
+}
+
+// TracingConfig selects where spans are exported: "none", "stdout" (or the
+// file named by File) for local use, or "otlp", which is configured by the
+// standard OTEL_EXPORTER_OTLP_* variables.
+type TracingConfig struct {
+	Exporter string `yaml:"exporter" toml:"exporter" env:"TRACING_EXPORTER"`
+	File     string `yaml:"file" toml:"file" env:"TRACING_FILE"`
+}
+
+// Duration is a time.Duration written as a string such as "15m" in config
+// files and environment variables.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+// Default returns the configuration used before any source is applied.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Port:              8080,
+			GRPCPort:          9090,
+			ReadTimeout:       Duration(15 * time.Second),
+			ReadHeaderTimeout: Duration(5 * time.Second),
+			WriteTimeout:      Duration(15 * time.Second),
+			IdleTimeout:       Duration(60 * time.Second),
+			ShutdownTimeout:   Duration(30 * time.Second),
+		},
+		Database: DatabaseConfig{
+			Host:            "localhost",
+			Port:            5432,
+			SSLMode:         "prefer",
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: Duration(30 * time.Minute),
+			ConnMaxIdleTime: Duration(5 * time.Minute),
+			ConnectRetries:  5,
+			ConnectBackoff:  Duration(time.Second),
+		},
+		JWT: JWTConfig{
+			TTL:    Duration(24 * time.Hour),
+			Leeway: Duration(30 * time.Second),
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: TracingConfig{
+			Exporter: "none",
+		},
+		AutoMigrate: true,
+	}
+}
+
+var (
+	sslModes   = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	dbSchemes  = []string{"postgres", "postgresql", "sqlite", "mysql"}
+	logLevels  = []string{"debug", "info", "warn", "error"}
+	logFormats = []string{"json", "text"}
+
+	tracingExporters = []string{"none", "stdout", "otlp"}
+)
+
+// Validate reports every problem with the configuration at once.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.JWT.Secret == "" {
+		errs = append(errs, errors.New("JWT_SECRET is required"))
+	}
+	if c.JWT.TTL <= 0 {
+		errs = append(errs, errors.New("JWT_TTL must be positive"))
+	}
+	if c.JWT.Leeway < 0 {
+		errs = append(errs, errors.New("JWT_LEEWAY must not be negative"))
+	}
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT %d is out of range", c.Server.Port))
+	}
+	if c.Server.GRPCPort < 0 || c.Server.GRPCPort > 65535 {
+		errs = append(errs, fmt.Errorf("GRPC_PORT %d is out of range", c.Server.GRPCPort))
+	} else if c.Server.GRPCPort == c.Server.Port {
+		errs = append(errs, errors.New("GRPC_PORT must differ from PORT"))
+	}
+	if c.Database.Port < 1 || c.Database.Port > 65535 {
+		errs = append(errs, fmt.Errorf("DB_PORT %d is out of range", c.Database.Port))
+	}
+	if !contains(sslModes, c.Database.SSLMode) {
+		errs = append(errs, fmt.Errorf("DB_SSLMODE must be one of %s", strings.Join(sslModes, ", ")))
+	}
+	if c.Database.URL != "" {
+		if u, err := url.Parse(c.Database.URL); err != nil || !contains(dbSchemes, u.Scheme) {
+			errs = append(errs, fmt.Errorf("DATABASE_URL must be a URL with scheme %s", strings.Join(dbSchemes, ", ")))
+		}
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("DB_MAX_OPEN_CONNS and DB_MAX_IDLE_CONNS must not be negative"))
+	}
+	if !contains(logLevels, strings.ToLower(c.Log.Level)) {
+		errs = append(errs, fmt.Errorf("LOG_LEVEL must be one of %s", strings.Join(logLevels, ", ")))
+	}
+	if !contains(logFormats, c.Log.Format) {
+		errs = append(errs, fmt.Errorf("LOG_FORMAT must be one of %s", strings.Join(logFormats, ", ")))
+	}
+	if !contains(tracingExporters, c.Tracing.Exporter) {
+		errs = append(errs, fmt.Errorf("TRACING_EXPORTER must be one of %s", strings.Join(tracingExporters, ", ")))
+	}
+	if c.Database.ConnectRetries < 0 {
+		errs = append(errs, errors.New("DB_CONNECT_RETRIES must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// Driver returns "postgres", "sqlite" or "mysql".
+func (d DatabaseConfig) Driver() string {
+	if d.URL == "" {
+		return "postgres"
+	}
+	u, err := url.Parse(d.URL)
+	if err != nil {
+		return ""
+	}
+	if u.Scheme == "postgresql" {
+		return "postgres"
+	}
+	return u.Scheme
+}
+
+// DSN returns the Postgres connection string built from the discrete
+// settings, for use when URL is empty.
+func (d DatabaseConfig) DSN() string {
+	return fmt.Sprintf("host=%v user=%v password=%v dbname=%v port=%v sslmode=%v",
+		d.Host, d.User, d.Password, d.Name, d.Port, d.SSLMode)
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
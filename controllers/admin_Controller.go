@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/joshua468/user-authentication/repositories"
+	"github.com/joshua468/user-authentication/services"
+	"github.com/joshua468/user-authentication/tracing"
+)
+
+// AdminController serves /api/admin for platform operators. Routes must be
+// guarded by middlewares.RequireAdmin.
+type AdminController struct {
+	admin *services.AdminService
+}
+
+func NewAdminController(admin *services.AdminService) *AdminController {
+	return &AdminController{admin}
+}
+
+func (ac *AdminController) ListUsers(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "AdminController.ListUsers")
+	defer span.End()
+
+	q, err := pageQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+	page, err := ac.admin.ListUsers(ctx, q)
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to retrieve users"))
+		return
+	}
+	c.JSON(http.StatusOK, pageBody("Users retrieved", q, page, newAdminUserResponses(page.Items)))
+}
+
+func (ac *AdminController) ListOrganisations(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "AdminController.ListOrganisations")
+	defer span.End()
+
+	q, err := pageQuery(c, repositories.SortName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+	page, err := ac.admin.ListOrganisations(ctx, q)
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to retrieve organisations"))
+		return
+	}
+	c.JSON(http.StatusOK, pageBody("Organisations retrieved", q, page, newOrganisationResponses(page.Items)))
+}
+
+// ListAuditEvents filters by the actor, target and action query parameters.
+func (ac *AdminController) ListAuditEvents(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "AdminController.ListAuditEvents")
+	defer span.End()
+
+	q, err := pageQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+	filter := repositories.AuditFilter{
+		ActorID:  c.Query("actor"),
+		TargetID: c.Query("target"),
+		Action:   c.Query("action"),
+	}
+	page, err := ac.admin.AuditLog(ctx, filter, q)
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to retrieve audit log"))
+		return
+	}
+	c.JSON(http.StatusOK, pageBody("Audit events retrieved", q, page, page.Items))
+}
+
+func (ac *AdminController) DisableUser(c *gin.Context) {
+	ac.setDisabled(c, true)
+}
+
+func (ac *AdminController) EnableUser(c *gin.Context) {
+	ac.setDisabled(c, false)
+}
+
+func (ac *AdminController) setDisabled(c *gin.Context, disabled bool) {
+	ctx, span := tracing.Start(c.Request.Context(), "AdminController.SetDisabled")
+	defer span.End()
+
+	actorId := c.MustGet("userId").(string)
+	userId := c.Param("id")
+	if disabled && userId == actorId {
+		c.JSON(http.StatusBadRequest, errorBody(c, "You cannot disable your own account"))
+		return
+	}
+	user, err := ac.admin.SetDisabled(ctx, actorId, userId, disabled)
+	if errors.Is(err, services.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, errorBody(c, "User not found"))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to update user"))
+		return
+	}
+
+	message := "User enabled"
+	if disabled {
+		message = "User disabled"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": message,
+		"data":    newAdminUserResponse(user),
+	})
+}
+
+// ResetPassword sets the password given in the body, or a generated one
+// that is returned once, and signs the user out everywhere. The user must
+// change it at their next sign-in.
+func (ac *AdminController) ResetPassword(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "AdminController.ResetPassword")
+	defer span.End()
+
+	var input struct {
+		Password string `json:"password"`
+	}
+	// The body is optional.
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+			return
+		}
+	}
+	userId := c.Param("id")
+	generated, err := ac.admin.ResetPassword(ctx, c.MustGet("userId").(string), userId, input.Password)
+	if errors.Is(err, services.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, errorBody(c, "User not found"))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to reset password"))
+		return
+	}
+
+	data := gin.H{"userId": userId}
+	if generated != "" {
+		data["temporaryPassword"] = generated
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Password reset",
+		"data":    data,
+	})
+}
+
+func (ac *AdminController) RevokeSessions(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "AdminController.RevokeSessions")
+	defer span.End()
+
+	err := ac.admin.RevokeSessions(ctx, c.MustGet("userId").(string), c.Param("id"))
+	if errors.Is(err, services.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, errorBody(c, "User not found"))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to revoke sessions"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Sessions revoked",
+	})
+}
+
+// ImpersonateUser issues a short-lived token that acts as the user. The
+// token carries the admin in its "act" claim and cannot reach the admin API.
+func (ac *AdminController) ImpersonateUser(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "AdminController.ImpersonateUser")
+	defer span.End()
+
+	impersonation, err := ac.admin.Impersonate(ctx, c.MustGet("userId").(string), c.Param("id"))
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, errorBody(c, "User not found"))
+		return
+	case errors.Is(err, services.ErrNotImpersonable):
+		c.JSON(http.StatusForbidden, errorBody(c, "This user cannot be impersonated"))
+		return
+	case err != nil:
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to impersonate user"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Impersonation started",
+		"data": gin.H{
+			"accessToken": impersonation.Token,
+			"expiresAt":   impersonation.ExpiresAt,
+			"user":        newUserResponse(impersonation.User),
+		},
+	})
+}
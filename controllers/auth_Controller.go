@@ -1,29 +1,42 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
-	"gorm.io/gorm"
 
-	"github.com/joshua468/user-authentication/models"
+	"github.com/joshua468/user-authentication/metrics"
+	"github.com/joshua468/user-authentication/services"
+	"github.com/joshua468/user-authentication/tracing"
 	"github.com/joshua468/user-authentication/utils"
 )
 
 type AuthController struct {
-	DB        *gorm.DB
-	JWTSecret string
+	Auth *services.AuthService
+	// Tokens, when set, enables Logout to revoke the caller's token.
+	Tokens *services.TokenService
+	// CookieName, when set, makes Register and Login also deliver the access
+	// token in an HTTP-only cookie that JWTAuthMiddleware accepts.
+	CookieName string
 }
 
-func NewAuthController(db *gorm.DB, jwtSecret string) *AuthController {
-	return &AuthController{
-		DB:        db,
-		JWTSecret: jwtSecret,
+func NewAuthController(auth *services.AuthService) *AuthController {
+	return &AuthController{Auth: auth}
+}
+
+func (ctrl *AuthController) setTokenCookie(c *gin.Context, token string) {
+	if ctrl.CookieName == "" {
+		return
 	}
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(ctrl.CookieName, token, 0, "/", "", true, true)
 }
 
 func (ctrl *AuthController) Register(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "AuthController.Register")
+	defer span.End()
+
 	var input struct {
 		FirstName string `json:"firstName" binding:"required"`
 		LastName  string `json:"lastName" binding:"required"`
@@ -33,94 +46,214 @@ func (ctrl *AuthController) Register(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusUnprocessableEntity, gin.H{"errors": err.Error()})
+		metrics.Registrations.WithLabelValues("invalid").Inc()
+		c.JSON(http.StatusUnprocessableEntity, validationErrorBody(c, err))
 		return
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
-		return
-	}
-
-	user := models.User{
-		UserID:    utils.GenerateUUID(),
+	user, token, err := ctrl.Auth.Register(ctx, services.CreateUserInput{
 		FirstName: input.FirstName,
 		LastName:  input.LastName,
 		Email:     input.Email,
-		Password:  string(hashedPassword),
+		Password:  input.Password,
 		Phone:     input.Phone,
-	}
-
-	if err := ctrl.DB.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register user"})
+	})
+	if errors.Is(err, services.ErrTokenIssuance) {
+		metrics.Registrations.WithLabelValues("error").Inc()
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to generate token"))
 		return
 	}
-
-	// Generate JWT token
-	token, err := utils.GenerateToken(user.UserID, ctrl.JWTSecret)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		metrics.Registrations.WithLabelValues("error").Inc()
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to register user"))
 		return
 	}
+	metrics.Registrations.WithLabelValues("success").Inc()
+	ctrl.setTokenCookie(c, token)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"status":  "success",
 		"message": "Registration successful",
 		"data": gin.H{
 			"accessToken": token,
-			"user": gin.H{
-				"userId":    user.UserID,
-				"firstName": user.FirstName,
-				"lastName":  user.LastName,
-				"email":     user.Email,
-				"phone":     user.Phone,
-			},
+			"user":        newUserResponse(user),
 		},
 	})
 }
 
 func (ctrl *AuthController) Login(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "AuthController.Login")
+	defer span.End()
+
 	var input struct {
 		Email    string `json:"email" binding:"required,email"`
 		Password string `json:"password" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusUnprocessableEntity, gin.H{"errors": err.Error()})
+		metrics.Logins.WithLabelValues("invalid").Inc()
+		c.JSON(http.StatusUnprocessableEntity, validationErrorBody(c, err))
 		return
 	}
 
-	var user models.User
-	if err := ctrl.DB.Where("email = ?", input.Email).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+	user, token, err := ctrl.Auth.Login(ctx, input.Email, input.Password)
+	switch {
+	case errors.Is(err, services.ErrUserDisabled):
+		metrics.Logins.WithLabelValues("disabled").Inc()
+		c.JSON(http.StatusForbidden, errorBody(c, "Account is disabled"))
 		return
+	case errors.Is(err, services.ErrTokenIssuance):
+		metrics.Logins.WithLabelValues("error").Inc()
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to generate token"))
+		return
+	case err != nil:
+		metrics.Logins.WithLabelValues("invalid_credentials").Inc()
+		c.JSON(http.StatusUnauthorized, errorBody(c, "Invalid email or password"))
+		return
+	}
+	metrics.Logins.WithLabelValues("success").Inc()
+	ctrl.setTokenCookie(c, token)
+
+	message := "Login successful"
+	if user.PasswordResetRequired {
+		message = "Password must be changed"
 	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": message,
+		"data": gin.H{
+			"accessToken":           token,
+			"user":                  newUserResponse(user),
+			"passwordResetRequired": user.PasswordResetRequired,
+		},
+	})
+}
+
+// ChangePassword replaces the caller's password after checking the current
+// one. It is the only route the token Login issues after a forced reset
+// reaches, and it returns an unrestricted token.
+func (ctrl *AuthController) ChangePassword(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "AuthController.ChangePassword")
+	defer span.End()
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+	var input struct {
+		CurrentPassword string `json:"currentPassword" binding:"required"`
+		NewPassword     string `json:"newPassword" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, validationErrorBody(c, err))
 		return
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(user.UserID, ctrl.JWTSecret)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+	user, token, err := ctrl.Auth.ChangePassword(ctx, c.MustGet("userId").(string), input.CurrentPassword, input.NewPassword)
+	switch {
+	case errors.Is(err, services.ErrInvalidCredentials):
+		c.JSON(http.StatusBadRequest, errorBody(c, "Current password is incorrect"))
+		return
+	case errors.Is(err, services.ErrPasswordUnchanged):
+		c.JSON(http.StatusBadRequest, errorBody(c, "The new password must differ from the current one"))
+		return
+	case errors.Is(err, services.ErrTokenIssuance):
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to generate token"))
+		return
+	case err != nil:
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to change password"))
 		return
 	}
+	ctrl.setTokenCookie(c, token)
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
-		"message": "Login successful",
+		"message": "Password changed",
 		"data": gin.H{
 			"accessToken": token,
-			"user": gin.H{
-				"userId":    user.UserID,
-				"firstName": user.FirstName,
-				"lastName":  user.LastName,
-				"email":     user.Email,
-				"phone":     user.Phone,
-			},
+			"user":        newUserResponse(user),
 		},
 	})
 }
+
+// SwitchOrganisation issues a token scoped to the organisation in the body,
+// or an unscoped token when orgId is empty. The caller's current token stays
+// valid until it expires.
+func (ctrl *AuthController) SwitchOrganisation(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "AuthController.SwitchOrganisation")
+	defer span.End()
+
+	var input struct {
+		OrgID string `json:"orgId"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+	claims, ok := c.MustGet("claims").(*utils.Claims)
+	if !ok || ctrl.Tokens == nil {
+		c.JSON(http.StatusNotImplemented, errorBody(c, "Organisation tokens are not enabled"))
+		return
+	}
+
+	token, switched, err := ctrl.Tokens.SwitchOrganisation(ctx, claims, input.OrgID)
+	switch {
+	case errors.Is(err, services.ErrOrganisationNotFound):
+		c.JSON(http.StatusNotFound, errorBody(c, "Organisation not found"))
+		return
+	case errors.Is(err, services.ErrNotMember):
+		c.JSON(http.StatusForbidden, errorBody(c, "Only members can switch to an organisation"))
+		return
+	case errors.Is(err, services.ErrInvalidSubjectToken):
+		c.JSON(http.StatusUnauthorized, errorBody(c, "Token has expired"))
+		return
+	case err != nil:
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to generate token"))
+		return
+	}
+	ctrl.setTokenCookie(c, token)
+
+	data := gin.H{"accessToken": token, "orgId": nil, "role": nil}
+	if switched.OrgID != "" {
+		data["orgId"], data["role"] = switched.OrgID, switched.OrgRole
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Organisation switched",
+		"data":    data,
+	})
+}
+
+// Logout revokes the access token the request was authenticated with and
+// clears the token cookie.
+func (ctrl *AuthController) Logout(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "AuthController.Logout")
+	defer span.End()
+
+	claims, ok := c.MustGet("claims").(*utils.Claims)
+	if !ok || ctrl.Tokens == nil {
+		c.JSON(http.StatusNotImplemented, errorBody(c, "Token revocation is not enabled"))
+		return
+	}
+	err := ctrl.Tokens.Revoke(ctx, claims)
+	if errors.Is(err, services.ErrTokenNotRevocable) {
+		c.JSON(http.StatusBadRequest, errorBody(c, "Token cannot be revoked"))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to revoke token"))
+		return
+	}
+	if ctrl.CookieName != "" {
+		c.SetSameSite(http.SameSiteStrictMode)
+		c.SetCookie(ctrl.CookieName, "", -1, "/", "", true, true)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Logout successful",
+	})
+}
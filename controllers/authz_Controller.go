@@ -0,0 +1,259 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/joshua468/user-authentication/metrics"
+	"github.com/joshua468/user-authentication/models"
+	"github.com/joshua468/user-authentication/repositories"
+	"github.com/joshua468/user-authentication/services"
+	"github.com/joshua468/user-authentication/tracing"
+)
+
+// maxRelationships caps a relationship listing.
+const maxRelationships = 1000
+
+// AuthzController serves /api/authz, which lets other services use the
+// relationship-based authorization engine. Routes must be guarded by
+// middlewares.RequireClient.
+type AuthzController struct {
+	authz *services.AuthzService
+}
+
+func NewAuthzController(authz *services.AuthzService) *AuthzController {
+	return &AuthzController{authz}
+}
+
+// relationshipBody is a relationship as read and written by the API.
+type relationshipBody struct {
+	Namespace        string `json:"namespace" binding:"required"`
+	ObjectID         string `json:"objectId" binding:"required"`
+	Relation         string `json:"relation" binding:"required"`
+	SubjectNamespace string `json:"subjectNamespace" binding:"required"`
+	SubjectID        string `json:"subjectId" binding:"required"`
+	SubjectRelation  string `json:"subjectRelation"`
+}
+
+func (b relationshipBody) model() models.Relationship {
+	return models.Relationship{
+		Namespace:        b.Namespace,
+		ObjectID:         b.ObjectID,
+		Relation:         b.Relation,
+		SubjectNamespace: b.SubjectNamespace,
+		SubjectID:        b.SubjectID,
+		SubjectRelation:  b.SubjectRelation,
+	}
+}
+
+func newRelationshipBodies(rels []models.Relationship) []relationshipBody {
+	out := make([]relationshipBody, 0, len(rels))
+	for _, rel := range rels {
+		out = append(out, relationshipBody{rel.Namespace, rel.ObjectID, rel.Relation, rel.SubjectNamespace, rel.SubjectID, rel.SubjectRelation})
+	}
+	return out
+}
+
+// subjectBody names the subject of a check or listing.
+type subjectBody struct {
+	SubjectNamespace string `json:"subjectNamespace" binding:"required"`
+	SubjectID        string `json:"subjectId" binding:"required"`
+	SubjectRelation  string `json:"subjectRelation"`
+}
+
+func (b subjectBody) ref() services.ObjectRef {
+	return services.ObjectRef{Namespace: b.SubjectNamespace, ID: b.SubjectID, Relation: b.SubjectRelation}
+}
+
+// authzError responds to the engine's errors, with failure as the message
+// for unexpected ones.
+func authzError(c *gin.Context, err error, failure string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidSchema),
+		errors.Is(err, services.ErrInvalidRelationship),
+		errors.Is(err, services.ErrDerivedRelationship),
+		errors.Is(err, services.ErrUnknownPermission):
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+	default:
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, errorBody(c, failure))
+	}
+}
+
+func (ac *AuthzController) Check(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "AuthzController.Check")
+	defer span.End()
+
+	var input struct {
+		Namespace  string `json:"namespace" binding:"required"`
+		ObjectID   string `json:"objectId" binding:"required"`
+		Permission string `json:"permission" binding:"required"`
+		subjectBody
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+
+	object := services.ObjectRef{Namespace: input.Namespace, ID: input.ObjectID}
+	allowed, err := ac.authz.Check(ctx, object, input.Permission, input.ref())
+	if err != nil {
+		metrics.AuthzChecks.WithLabelValues("error").Inc()
+		authzError(c, err, "Failed to check permission")
+		return
+	}
+	if allowed {
+		metrics.AuthzChecks.WithLabelValues("allowed").Inc()
+	} else {
+		metrics.AuthzChecks.WithLabelValues("denied").Inc()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Permission checked",
+		"data":    gin.H{"allowed": allowed},
+	})
+}
+
+func (ac *AuthzController) ListObjects(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "AuthzController.ListObjects")
+	defer span.End()
+
+	var input struct {
+		Namespace  string `json:"namespace" binding:"required"`
+		Permission string `json:"permission" binding:"required"`
+		subjectBody
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+
+	ids, err := ac.authz.ListObjects(ctx, input.Namespace, input.Permission, input.ref())
+	if err != nil {
+		authzError(c, err, "Failed to list objects")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Objects retrieved",
+		"data":    gin.H{"objectIds": ids},
+	})
+}
+
+// ReadRelationships filters by the namespace, which is required, and the
+// objectId, relation, subjectNamespace, subjectId and subjectRelation query
+// parameters. At most maxRelationships are returned; truncated tells the
+// caller that more matched and the filter should be narrowed.
+func (ac *AuthzController) ReadRelationships(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "AuthzController.ReadRelationships")
+	defer span.End()
+
+	filter := repositories.RelationshipFilter{
+		Namespace:        c.Query("namespace"),
+		ObjectID:         c.Query("objectId"),
+		Relation:         c.Query("relation"),
+		SubjectNamespace: c.Query("subjectNamespace"),
+		SubjectID:        c.Query("subjectId"),
+		// One extra relationship tells us whether any were left out.
+		Limit: maxRelationships + 1,
+	}
+	if filter.Namespace == "" {
+		c.JSON(http.StatusBadRequest, errorBody(c, "namespace is required"))
+		return
+	}
+	if subjectRelation, ok := c.GetQuery("subjectRelation"); ok {
+		filter.SubjectRelation = &subjectRelation
+	}
+
+	rels, err := ac.authz.ReadRelationships(ctx, filter)
+	if err != nil {
+		authzError(c, err, "Failed to read relationships")
+		return
+	}
+	truncated := len(rels) > maxRelationships
+	if truncated {
+		rels = rels[:maxRelationships]
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":    "success",
+		"message":   "Relationships retrieved",
+		"data":      newRelationshipBodies(rels),
+		"truncated": truncated,
+	})
+}
+
+// WriteRelationships applies a batch of touch and delete updates
+// atomically.
+func (ac *AuthzController) WriteRelationships(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "AuthzController.WriteRelationships")
+	defer span.End()
+
+	var input struct {
+		Updates []struct {
+			Operation    string           `json:"operation" binding:"required,oneof=touch delete"`
+			Relationship relationshipBody `json:"relationship" binding:"required"`
+		} `json:"updates" binding:"required,min=1,max=1000,dive"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+
+	var touch, remove []models.Relationship
+	for _, update := range input.Updates {
+		if update.Operation == "touch" {
+			touch = append(touch, update.Relationship.model())
+		} else {
+			remove = append(remove, update.Relationship.model())
+		}
+	}
+	if err := ac.authz.WriteRelationships(ctx, touch, remove); err != nil {
+		authzError(c, err, "Failed to write relationships")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Relationships written",
+	})
+}
+
+func (ac *AuthzController) GetSchema(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "AuthzController.GetSchema")
+	defer span.End()
+
+	source, err := ac.authz.Schema(ctx)
+	if err != nil {
+		authzError(c, err, "Failed to read schema")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Schema retrieved",
+		"data":    gin.H{"schema": source},
+	})
+}
+
+func (ac *AuthzController) WriteSchema(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "AuthzController.WriteSchema")
+	defer span.End()
+
+	var input struct {
+		Schema string `json:"schema" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+
+	if err := ac.authz.WriteSchema(ctx, input.Schema); err != nil {
+		authzError(c, err, "Failed to write schema")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Schema written",
+	})
+}
@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/joshua468/user-authentication/models"
+)
+
+// Responses are built from these types rather than from the models, so
+// that a field added to a model is never exposed until it is added here.
+
+type userResponse struct {
+	UserID    string `json:"userId"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Email     string `json:"email"`
+	Phone     string `json:"phone"`
+}
+
+func newUserResponse(user *models.User) userResponse {
+	return userResponse{
+		UserID:    user.UserID,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Email:     user.Email,
+		Phone:     user.Phone,
+	}
+}
+
+// memberResponse is a user as listed to the other members of an
+// organisation.
+type memberResponse struct {
+	userResponse
+	Role string `json:"role"`
+}
+
+func newMemberResponses(members []models.Member) []memberResponse {
+	out := make([]memberResponse, 0, len(members))
+	for i := range members {
+		out = append(out, memberResponse{newUserResponse(&members[i].User), members[i].Role})
+	}
+	return out
+}
+
+type organisationResponse struct {
+	OrgID       string    `json:"orgId"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+func newOrganisationResponse(org *models.Organisation) organisationResponse {
+	return organisationResponse{
+		OrgID:       org.OrgID,
+		Name:        org.Name,
+		Description: org.Description,
+		CreatedAt:   org.CreatedAt,
+		UpdatedAt:   org.UpdatedAt,
+	}
+}
+
+func newOrganisationResponses(orgs []models.Organisation) []organisationResponse {
+	out := make([]organisationResponse, 0, len(orgs))
+	for i := range orgs {
+		out = append(out, newOrganisationResponse(&orgs[i]))
+	}
+	return out
+}
+
+// adminUserResponse adds account state to userResponse for the admin API.
+type adminUserResponse struct {
+	userResponse
+	IsAdmin               bool       `json:"isAdmin"`
+	DisabledAt            *time.Time `json:"disabledAt"`
+	SessionsRevokedAt     *time.Time `json:"sessionsRevokedAt"`
+	PasswordResetRequired bool       `json:"passwordResetRequired"`
+	CreatedAt             time.Time  `json:"createdAt"`
+}
+
+func newAdminUserResponse(user *models.User) adminUserResponse {
+	return adminUserResponse{
+		userResponse:          newUserResponse(user),
+		IsAdmin:               user.IsAdmin,
+		DisabledAt:            user.DisabledAt,
+		SessionsRevokedAt:     user.SessionsRevokedAt,
+		PasswordResetRequired: user.PasswordResetRequired,
+		CreatedAt:             user.CreatedAt,
+	}
+}
+
+func newAdminUserResponses(users []models.User) []adminUserResponse {
+	out := make([]adminUserResponse, 0, len(users))
+	for i := range users {
+		out = append(out, newAdminUserResponse(&users[i]))
+	}
+	return out
+}
+
+type permissionResponse struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func newPermissionResponses(perms []models.Permission) []permissionResponse {
+	out := make([]permissionResponse, 0, len(perms))
+	for _, perm := range perms {
+		out = append(out, permissionResponse{perm.Name, perm.Description})
+	}
+	return out
+}
+
+// roleResponse describes a built-in role, which has no roleId, or a custom
+// one.
+type roleResponse struct {
+	RoleID      string   `json:"roleId,omitempty"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+	BuiltIn     bool     `json:"builtIn"`
+}
+
+var builtinRoleDescriptions = map[string]string{
+	models.RoleOwner:  "Full control of the organisation, including its owners",
+	models.RoleAdmin:  "Manages members, roles and teams, except owners",
+	models.RoleMember: "Views the organisation, its members and its teams",
+}
+
+func newRoleResponse(role *models.Role) roleResponse {
+	return roleResponse{
+		RoleID:      role.RoleID,
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: role.PermissionList(),
+	}
+}
+
+func newRoleResponses(roles []models.Role) []roleResponse {
+	out := make([]roleResponse, 0, len(models.Roles)+len(roles))
+	for _, name := range models.Roles {
+		out = append(out, roleResponse{
+			Name:        name,
+			Description: builtinRoleDescriptions[name],
+			Permissions: models.BuiltinPermissions[name],
+			BuiltIn:     true,
+		})
+	}
+	for i := range roles {
+		out = append(out, newRoleResponse(&roles[i]))
+	}
+	return out
+}
+
+type teamResponse struct {
+	TeamID      string    `json:"teamId"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	ParentID    *string   `json:"parentId"`
+	Role        *string   `json:"role"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+func newTeamResponse(team *models.Team) teamResponse {
+	out := teamResponse{
+		TeamID:      team.TeamID,
+		Name:        team.Name,
+		Description: team.Description,
+		CreatedAt:   team.CreatedAt,
+		UpdatedAt:   team.UpdatedAt,
+	}
+	if team.Parent != nil {
+		out.ParentID = &team.Parent.TeamID
+	}
+	if team.Role != "" {
+		out.Role = &team.Role
+	}
+	return out
+}
+
+func newTeamResponses(teams []models.Team) []teamResponse {
+	out := make([]teamResponse, 0, len(teams))
+	for i := range teams {
+		out = append(out, newTeamResponse(&teams[i]))
+	}
+	return out
+}
+
+// teamMemberResponse is a user as listed to the members of the team's
+// organisation.
+type teamMemberResponse struct {
+	userResponse
+	Maintainer bool `json:"maintainer"`
+}
+
+func newTeamMemberResponses(members []models.TeamMember) []teamMemberResponse {
+	out := make([]teamMemberResponse, 0, len(members))
+	for i := range members {
+		out = append(out, teamMemberResponse{newUserResponse(&members[i].User), members[i].Maintainer})
+	}
+	return out
+}
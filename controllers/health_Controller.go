@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadinessCheck is one dependency that must be healthy before the instance
+// should receive traffic.
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type HealthController struct {
+	checks       []ReadinessCheck
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+func NewHealthController(checks ...ReadinessCheck) *HealthController {
+	return &HealthController{checks: checks, timeout: 2 * time.Second}
+}
+
+// ShuttingDown makes Ready fail so load balancers stop routing to the
+// instance while in-flight requests drain.
+func (hc *HealthController) ShuttingDown() {
+	hc.shuttingDown.Store(true)
+}
+
+// Live reports that the process is up. It deliberately checks nothing else,
+// so a slow database never gets the pod restarted.
+func (hc *HealthController) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+func (hc *HealthController) Ready(c *gin.Context) {
+	if hc.shuttingDown.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "error": "shutting down"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), hc.timeout)
+	defer cancel()
+
+	status, code := "ok", http.StatusOK
+	results := gin.H{}
+	for _, check := range hc.checks {
+		if err := check.Check(ctx); err != nil {
+			status, code = "unavailable", http.StatusServiceUnavailable
+			results[check.Name] = err.Error()
+			continue
+		}
+		results[check.Name] = "ok"
+	}
+
+	c.JSON(code, gin.H{"status": status, "checks": results})
+}
@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/joshua468/user-authentication/metrics"
+	"github.com/joshua468/user-authentication/models"
+	"github.com/joshua468/user-authentication/services"
+	"github.com/joshua468/user-authentication/tracing"
+)
+
+// Token exchange parameter values from RFC 8693.
+const (
+	grantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	tokenTypeAccessToken   = "urn:ietf:params:oauth:token-type:access_token"
+)
+
+// OAuthController serves the RFC 7662 introspection endpoint, which lets
+// resource servers check tokens without sharing the signing secret, and the
+// RFC 8693 token exchange grant, which lets them call each other on a
+// user's behalf.
+type OAuthController struct {
+	clients *services.ClientService
+	tokens  *services.TokenService
+}
+
+func NewOAuthController(clients *services.ClientService, tokens *services.TokenService) *OAuthController {
+	return &OAuthController{clients, tokens}
+}
+
+func (oc *OAuthController) Introspect(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "OAuthController.Introspect")
+	defer span.End()
+
+	if oc.authenticateClient(c, "introspect") == nil {
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	token := c.PostForm("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": "token is required"})
+		return
+	}
+
+	result, err := oc.tokens.Introspect(ctx, token)
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to introspect token"))
+		return
+	}
+	if result == nil {
+		c.JSON(http.StatusOK, gin.H{"active": false})
+		return
+	}
+
+	claims := result.Claims
+	body := gin.H{
+		"active":     true,
+		"token_type": "Bearer",
+		"sub":        claims.Subject,
+		"org_ids":    result.OrgIDs,
+	}
+	if claims.Scope != "" {
+		body["scope"] = claims.Scope
+	}
+	if claims.Actor != nil {
+		body["act"] = claims.Actor
+	}
+	if claims.ClientID != "" {
+		body["client_id"] = claims.ClientID
+	}
+	if claims.OrgID != "" {
+		body["org_id"] = claims.OrgID
+		body["org_role"] = claims.OrgRole
+	}
+	if claims.ExpiresAt != nil {
+		body["exp"] = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		body["iat"] = claims.IssuedAt.Unix()
+	}
+	if claims.NotBefore != nil {
+		body["nbf"] = claims.NotBefore.Unix()
+	}
+	if claims.Issuer != "" {
+		body["iss"] = claims.Issuer
+	}
+	if len(claims.Audience) > 0 {
+		body["aud"] = claims.Audience
+	}
+	if claims.ID != "" {
+		body["jti"] = claims.ID
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// Token implements the token exchange grant. The calling client must have an
+// exchange policy for the requested audience.
+func (oc *OAuthController) Token(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "OAuthController.Token")
+	defer span.End()
+
+	client := oc.authenticateClient(c, "token")
+	if client == nil {
+		metrics.TokenExchanges.WithLabelValues("invalid_client").Inc()
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	if grantType := c.PostForm("grant_type"); grantType != grantTypeTokenExchange {
+		exchangeError(c, "unsupported_grant_type", fmt.Sprintf("grant_type must be %s", grantTypeTokenExchange))
+		return
+	}
+	subjectToken := c.PostForm("subject_token")
+	if subjectToken == "" || c.PostForm("subject_token_type") != tokenTypeAccessToken {
+		exchangeError(c, "invalid_request", fmt.Sprintf("subject_token and a subject_token_type of %s are required", tokenTypeAccessToken))
+		return
+	}
+	if requested := c.PostForm("requested_token_type"); requested != "" && requested != tokenTypeAccessToken {
+		exchangeError(c, "invalid_request", "only access tokens can be issued")
+		return
+	}
+	audience := c.PostForm("audience")
+	if audience == "" {
+		exchangeError(c, "invalid_request", "audience is required")
+		return
+	}
+
+	policy, err := oc.clients.ExchangePolicy(ctx, client.ClientID, audience)
+	if errors.Is(err, services.ErrNoExchangePolicy) {
+		exchangeError(c, "invalid_target", err.Error())
+		return
+	}
+	if err != nil {
+		oc.exchangeFailed(c, err)
+		return
+	}
+	exchanged, err := oc.tokens.Exchange(ctx, subjectToken, policy, c.PostForm("scope"))
+	switch {
+	case errors.Is(err, services.ErrInvalidSubjectToken), errors.Is(err, services.ErrImpersonatedSubject):
+		exchangeError(c, "invalid_grant", err.Error())
+		return
+	case errors.Is(err, services.ErrInvalidScope):
+		exchangeError(c, "invalid_scope", err.Error())
+		return
+	case err != nil:
+		oc.exchangeFailed(c, err)
+		return
+	}
+	metrics.TokenExchanges.WithLabelValues("success").Inc()
+
+	claims := exchanged.Claims
+	body := gin.H{
+		"access_token":      exchanged.Token,
+		"issued_token_type": tokenTypeAccessToken,
+		"token_type":        "Bearer",
+		"expires_in":        int64(claims.ExpiresAt.Sub(claims.IssuedAt.Time).Seconds()),
+	}
+	if claims.Scope != "" {
+		body["scope"] = claims.Scope
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// authenticateClient returns the calling service client, or nil after
+// responding with 401. RFC 6749 section 2.3.1 prefers HTTP Basic, but
+// credentials in the form body are accepted too.
+func (oc *OAuthController) authenticateClient(c *gin.Context, realm string) *models.ServiceClient {
+	clientID, secret, ok := c.Request.BasicAuth()
+	if !ok {
+		clientID, secret = c.PostForm("client_id"), c.PostForm("client_secret")
+	}
+	client, err := oc.clients.Authenticate(c.Request.Context(), clientID, secret)
+	if err != nil {
+		c.Header("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", realm))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_client"})
+		return nil
+	}
+	return client
+}
+
+func (oc *OAuthController) exchangeFailed(c *gin.Context, err error) {
+	metrics.TokenExchanges.WithLabelValues("error").Inc()
+	c.Error(err)
+	c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to exchange token"))
+}
+
+// exchangeError responds with an RFC 6749 section 5.2 error.
+func exchangeError(c *gin.Context, code, description string) {
+	metrics.TokenExchanges.WithLabelValues(code).Inc()
+	c.JSON(http.StatusBadRequest, gin.H{"error": code, "error_description": description})
+}
@@ -1,123 +1,348 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"slices"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
-	"gorm.io/gorm"
 
+	"github.com/joshua468/user-authentication/metrics"
 	"github.com/joshua468/user-authentication/models"
+	"github.com/joshua468/user-authentication/repositories"
+	"github.com/joshua468/user-authentication/services"
+	"github.com/joshua468/user-authentication/tracing"
 )
 
 type OrganisationController struct {
-	db *gorm.DB
+	orgs  *services.OrgService
+	roles *services.RoleService
 }
 
-func NewOrganisationController(db *gorm.DB) *OrganisationController {
-	return &OrganisationController{db}
+func NewOrganisationController(orgs *services.OrgService, roles *services.RoleService) *OrganisationController {
+	return &OrganisationController{orgs, roles}
+}
+
+// RequirePermission rejects requests from users who do not hold permission
+// in the organisation named by the orgId path parameter, through their role
+// or their teams. A token scoped to that organisation is refused with 401
+// once the caller's role no longer matches its claim. It must run after the
+// JWT middleware and middlewares.RequireTokenOrg.
+func (oc *OrganisationController) RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		orgId := c.Param("orgId")
+
+		var perms []string
+		var err error
+		if role := c.GetString("orgRole"); role != "" && c.GetString("orgId") == orgId {
+			perms, err = oc.roles.TokenPermissions(ctx, orgId, c.GetString("userId"), role)
+		} else {
+			perms, err = oc.roles.MemberPermissions(ctx, orgId, c.GetString("userId"))
+		}
+		switch {
+		case errors.Is(err, services.ErrOrganisationNotFound):
+			c.AbortWithStatusJSON(http.StatusNotFound, errorBody(c, "Organisation not found"))
+			return
+		case errors.Is(err, services.ErrNotMember):
+			c.AbortWithStatusJSON(http.StatusForbidden, errorBody(c, "Only members can access this organisation"))
+			return
+		case errors.Is(err, services.ErrStaleOrgRole):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, errorBody(c, "Your role in this organisation has changed; switch organisation again"))
+			return
+		case err != nil:
+			c.Error(err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, errorBody(c, "Failed to check permissions"))
+			return
+		case !slices.Contains(perms, permission):
+			c.AbortWithStatusJSON(http.StatusForbidden, errorBody(c, "Missing permission "+permission))
+			return
+		}
+		c.Next()
+	}
 }
 
 func (oc *OrganisationController) GetOrganisations(c *gin.Context) {
-	var orgs []models.Organisation
+	ctx, span := tracing.Start(c.Request.Context(), "OrganisationController.GetOrganisations")
+	defer span.End()
+
 	userId := c.MustGet("userId").(string)
+	q, err := pageQuery(c, repositories.SortName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
 
-	if err := oc.db.Joins("JOIN organisation_users on organisation_users.organisation_id = organisations.id").
-		Joins("JOIN users on users.id = organisation_users.user_id").
-		Where("users.user_id = ?", userId).
-		Find(&orgs).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve organisations"})
+	page, err := oc.orgs.PageForUser(ctx, userId, q)
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to retrieve organisations"))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "success",
-		"message": "Organisations retrieved",
-		"data":    orgs,
-	})
+	c.JSON(http.StatusOK, pageBody("Organisations retrieved", q, page, newOrganisationResponses(page.Items)))
 }
 
 func (oc *OrganisationController) GetOrganisation(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "OrganisationController.GetOrganisation")
+	defer span.End()
+
 	orgId := c.Param("orgId")
-	var org models.Organisation
 
-	if err := oc.db.Where("org_id = ?", orgId).First(&org).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Organisation not found"})
+	org, err := oc.orgs.Get(ctx, orgId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, errorBody(c, "Organisation not found"))
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
 		"message": "Organisation found",
-		"data":    org,
+		"data":    newOrganisationResponse(org),
 	})
 }
 
 func (oc *OrganisationController) CreateOrganisation(c *gin.Context) {
-	var org models.Organisation
+	ctx, span := tracing.Start(c.Request.Context(), "OrganisationController.CreateOrganisation")
+	defer span.End()
 
-	if err := c.ShouldBindJSON(&org); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+	var input struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
 	}
 
-	org.OrgID = uuid.New().String()
-
-	if err := oc.db.Create(&org).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organisation"})
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
 		return
 	}
 
 	userId := c.MustGet("userId").(string)
-	var user models.User
-	if err := oc.db.Where("user_id = ?", userId).First(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find user"})
+	org, err := oc.orgs.Create(ctx, userId, services.CreateOrgInput{
+		Name:        input.Name,
+		Description: input.Description,
+	})
+	if errors.Is(err, services.ErrUserNotFound) {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to find user"))
 		return
 	}
-
-	if err := oc.db.Model(&org).Association("Users").Append(&user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to associate user with organisation"})
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to create organisation"))
 		return
 	}
+	// The creator joins as the first member.
+	metrics.OrgMembershipChanges.WithLabelValues("added").Inc()
 
 	c.JSON(http.StatusCreated, gin.H{
 		"status":  "success",
 		"message": "Organisation created successfully",
-		"data":    org,
+		"data":    newOrganisationResponse(org),
 	})
 }
 
 func (oc *OrganisationController) AddUserToOrganisation(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "OrganisationController.AddUserToOrganisation")
+	defer span.End()
+
 	var input struct {
 		UserID string `json:"userId" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
 		return
 	}
 
 	orgId := c.Param("orgId")
-	var org models.Organisation
+	err := oc.orgs.AddMember(ctx, orgId, input.UserID)
+	switch {
+	case errors.Is(err, services.ErrOrganisationNotFound):
+		c.JSON(http.StatusNotFound, errorBody(c, "Organisation not found"))
+		return
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, errorBody(c, "User not found"))
+		return
+	case err != nil:
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to add user to organisation"))
+		return
+	}
+	metrics.OrgMembershipChanges.WithLabelValues("added").Inc()
 
-	if err := oc.db.Where("org_id = ?", orgId).First(&org).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Organisation not found"})
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "User added to organisation successfully",
+	})
+}
+
+func (oc *OrganisationController) GetOrganisationMembers(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "OrganisationController.GetOrganisationMembers")
+	defer span.End()
+
+	orgId := c.Param("orgId")
+	q, err := pageQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
 		return
 	}
 
-	var user models.User
-	if err := oc.db.Where("user_id = ?", input.UserID).First(&user).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	page, err := oc.orgs.PageMembers(ctx, orgId, c.Query("role"), q)
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to retrieve members"))
 		return
 	}
+	c.JSON(http.StatusOK, pageBody("Members retrieved", q, page, newMemberResponses(page.Items)))
+}
+
+func (oc *OrganisationController) ListPermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Permissions retrieved",
+		"data":    newPermissionResponses(models.Permissions),
+	})
+}
 
-	if err := oc.db.Model(&org).Association("Users").Append(&user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add user to organisation"})
+// roleError responds to the errors of the role endpoints, with failure as
+// the message for unexpected ones.
+func roleError(c *gin.Context, err error, failure string) {
+	switch {
+	case errors.Is(err, services.ErrOrganisationNotFound):
+		c.JSON(http.StatusNotFound, errorBody(c, "Organisation not found"))
+	case errors.Is(err, services.ErrRoleNotFound):
+		c.JSON(http.StatusNotFound, errorBody(c, "Role not found"))
+	case errors.Is(err, services.ErrNotMember):
+		c.JSON(http.StatusNotFound, errorBody(c, "User is not a member of the organisation"))
+	case errors.Is(err, services.ErrInvalidRole):
+		c.JSON(http.StatusBadRequest, errorBody(c, "A role needs a name of 2 to 32 lowercase letters, digits or dashes that is not a built-in role, and permissions from the catalogue"))
+	case errors.Is(err, services.ErrPermissionEscalation):
+		c.JSON(http.StatusForbidden, errorBody(c, "You cannot grant or change permissions you do not hold"))
+	case errors.Is(err, services.ErrRoleExists):
+		c.JSON(http.StatusConflict, errorBody(c, "The organisation already has a role with this name"))
+	case errors.Is(err, services.ErrRoleInUse):
+		c.JSON(http.StatusConflict, errorBody(c, "Role is assigned to members or teams"))
+	case errors.Is(err, services.ErrLastOwner):
+		c.JSON(http.StatusConflict, errorBody(c, "An organisation must keep at least one owner"))
+	default:
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, errorBody(c, failure))
+	}
+}
+
+// ListRoles lists the built-in roles followed by the organisation's own.
+func (oc *OrganisationController) ListRoles(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "OrganisationController.ListRoles")
+	defer span.End()
+
+	roles, err := oc.roles.List(ctx, c.Param("orgId"))
+	if err != nil {
+		roleError(c, err, "Failed to retrieve roles")
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Roles retrieved",
+		"data":    newRoleResponses(roles),
+	})
+}
+
+func (oc *OrganisationController) CreateRole(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "OrganisationController.CreateRole")
+	defer span.End()
 
+	var input struct {
+		Name        string   `json:"name" binding:"required"`
+		Description string   `json:"description"`
+		Permissions []string `json:"permissions" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+
+	role, err := oc.roles.Create(ctx, c.Param("orgId"), c.GetString("userId"), services.RoleInput{
+		Name:        input.Name,
+		Description: input.Description,
+		Permissions: input.Permissions,
+	})
+	if err != nil {
+		roleError(c, err, "Failed to create role")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "Role created successfully",
+		"data":    newRoleResponse(role),
+	})
+}
+
+// UpdateRole changes the fields present in the body.
+func (oc *OrganisationController) UpdateRole(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "OrganisationController.UpdateRole")
+	defer span.End()
+
+	var input struct {
+		Name        *string  `json:"name"`
+		Description *string  `json:"description"`
+		Permissions []string `json:"permissions"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+
+	role, err := oc.roles.Update(ctx, c.Param("orgId"), c.GetString("userId"), c.Param("roleId"), services.RoleUpdate{
+		Name:        input.Name,
+		Description: input.Description,
+		Permissions: input.Permissions,
+	})
+	if err != nil {
+		roleError(c, err, "Failed to update role")
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
-		"message": "User added to organisation successfully",
+		"message": "Role updated successfully",
+		"data":    newRoleResponse(role),
+	})
+}
+
+func (oc *OrganisationController) DeleteRole(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "OrganisationController.DeleteRole")
+	defer span.End()
+
+	if err := oc.roles.Delete(ctx, c.Param("orgId"), c.GetString("userId"), c.Param("roleId")); err != nil {
+		roleError(c, err, "Failed to delete role")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Role deleted successfully",
+	})
+}
+
+func (oc *OrganisationController) AssignRole(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "OrganisationController.AssignRole")
+	defer span.End()
+
+	var input struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+
+	err := oc.roles.AssignRole(ctx, c.Param("orgId"), c.GetString("userId"), c.Param("memberId"), input.Role)
+	if err != nil {
+		roleError(c, err, "Failed to assign role")
+		return
+	}
+	metrics.OrgMembershipChanges.WithLabelValues("role_changed").Inc()
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Role assigned successfully",
 	})
 }
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/joshua468/user-authentication/repositories"
+	"github.com/joshua468/user-authentication/services"
+)
+
+var (
+	errInvalidLimit  = errors.New("limit must be a positive integer")
+	errInvalidCursor = errors.New("cursor is invalid")
+	errCursorSort    = errors.New("cursor was issued for a different sort")
+)
+
+// pageQuery reads the query parameters shared by every paged listing:
+// limit, cursor (from a previous response's nextCursor), q and sort. Limits
+// above services.MaxPageSize are lowered to it. Every listing sorts by
+// createdAt; sorts are the other keys the listing accepts, each of which may
+// be prefixed with "-" for descending order.
+func pageQuery(c *gin.Context, sorts ...string) (repositories.PageQuery, error) {
+	q := repositories.PageQuery{Limit: services.DefaultPageSize}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return q, errInvalidLimit
+		}
+		q.Limit = min(n, services.MaxPageSize)
+	}
+	if cursor := c.Query("cursor"); cursor != "" {
+		after, err := decodeCursor(cursor)
+		if err != nil {
+			return q, errInvalidCursor
+		}
+		q.After = after
+	}
+	sorts = append([]string{repositories.SortCreatedAt}, sorts...)
+	sort := c.DefaultQuery("sort", repositories.SortCreatedAt)
+	q.Sort, q.Descending = strings.TrimPrefix(sort, "-"), strings.HasPrefix(sort, "-")
+	if !slices.Contains(sorts, q.Sort) {
+		return q, fmt.Errorf("sort must be one of %s, optionally prefixed with -", strings.Join(sorts, ", "))
+	}
+	if q.After != nil && q.After.Sort != q.Order() {
+		return q, errCursorSort
+	}
+	q.Search = c.Query("q")
+	return q, nil
+}
+
+// pageBody is the success envelope for a paged listing; data is page.Items
+// as the listing presents them. The pagination object is the same for every
+// listing so clients can page generically.
+func pageBody[T any](message string, q repositories.PageQuery, page repositories.Page[T], data any) gin.H {
+	pagination := gin.H{"limit": q.Limit, "total": page.Total, "nextCursor": nil}
+	if page.Next != nil {
+		pagination["nextCursor"] = encodeCursor(page.Next)
+	}
+	return gin.H{
+		"status":     "success",
+		"message":    message,
+		"data":       data,
+		"pagination": pagination,
+	}
+}
+
+// Cursors are opaque to clients; the encoding may change between releases.
+func encodeCursor(cursor *repositories.Cursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(s string) (*repositories.Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var cursor repositories.Cursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/joshua468/user-authentication/logging"
+)
+
+// errorBody is the JSON body for an error response. The request ID lets a
+// caller's report be matched to the server's log lines.
+func errorBody(c *gin.Context, message string) gin.H {
+	return gin.H{"error": message, "requestId": logging.RequestID(c.Request.Context())}
+}
+
+func validationErrorBody(c *gin.Context, err error) gin.H {
+	return gin.H{"errors": err.Error(), "requestId": logging.RequestID(c.Request.Context())}
+}
+
+func init() {
+	// Name fields in validation errors as clients send them ("firstName"
+	// rather than "FirstName"), matching the documented request bodies.
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "" || name == "-" {
+				return field.Name
+			}
+			return name
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/joshua468/user-authentication/metrics"
+	"github.com/joshua468/user-authentication/services"
+	"github.com/joshua468/user-authentication/tracing"
+)
+
+// TeamController serves /api/organisations/:orgId/teams. Routes must be
+// guarded by OrganisationController.RequirePermission; the service checks
+// maintainers on top.
+type TeamController struct {
+	teams *services.TeamService
+}
+
+func NewTeamController(teams *services.TeamService) *TeamController {
+	return &TeamController{teams}
+}
+
+// teamError responds to the errors of the team endpoints, with failure as
+// the message for unexpected ones.
+func teamError(c *gin.Context, err error, failure string) {
+	switch {
+	case errors.Is(err, services.ErrOrganisationNotFound):
+		c.JSON(http.StatusNotFound, errorBody(c, "Organisation not found"))
+	case errors.Is(err, services.ErrTeamNotFound):
+		c.JSON(http.StatusNotFound, errorBody(c, "Team not found"))
+	case errors.Is(err, services.ErrRoleNotFound):
+		c.JSON(http.StatusNotFound, errorBody(c, "Role not found"))
+	case errors.Is(err, services.ErrNotMember), errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, errorBody(c, "User is not a member of the organisation"))
+	case errors.Is(err, services.ErrNotTeamMember):
+		c.JSON(http.StatusNotFound, errorBody(c, "User is not a member of the team"))
+	case errors.Is(err, services.ErrInvalidTeam):
+		c.JSON(http.StatusBadRequest, errorBody(c, "A team needs a name of 1 to 64 characters without surrounding spaces"))
+	case errors.Is(err, services.ErrTeamDescription):
+		c.JSON(http.StatusBadRequest, errorBody(c, "A team's description can be at most 255 characters"))
+	case errors.Is(err, services.ErrOwnerTeam):
+		c.JSON(http.StatusBadRequest, errorBody(c, "Teams cannot be granted the owner role"))
+	case errors.Is(err, services.ErrNotTeamManager):
+		c.JSON(http.StatusForbidden, errorBody(c, "Only the team's maintainers and members with org.teams.manage can change this team"))
+	case errors.Is(err, services.ErrPermissionEscalation):
+		c.JSON(http.StatusForbidden, errorBody(c, "You cannot grant or change permissions you do not hold"))
+	case errors.Is(err, services.ErrTeamExists):
+		c.JSON(http.StatusConflict, errorBody(c, "The organisation already has a team with this name"))
+	case errors.Is(err, services.ErrTeamCycle):
+		c.JSON(http.StatusConflict, errorBody(c, "A team cannot be nested in itself or in a team nested in it"))
+	case errors.Is(err, services.ErrTeamHasChildren):
+		c.JSON(http.StatusConflict, errorBody(c, "Team has nested teams"))
+	default:
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, errorBody(c, failure))
+	}
+}
+
+func (tc *TeamController) ListTeams(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "TeamController.ListTeams")
+	defer span.End()
+
+	teams, err := tc.teams.List(ctx, c.Param("orgId"))
+	if err != nil {
+		teamError(c, err, "Failed to retrieve teams")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Teams retrieved",
+		"data":    newTeamResponses(teams),
+	})
+}
+
+func (tc *TeamController) GetTeam(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "TeamController.GetTeam")
+	defer span.End()
+
+	team, err := tc.teams.Get(ctx, c.Param("orgId"), c.Param("teamId"))
+	if err != nil {
+		teamError(c, err, "Failed to retrieve team")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Team found",
+		"data":    newTeamResponse(team),
+	})
+}
+
+func (tc *TeamController) CreateTeam(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "TeamController.CreateTeam")
+	defer span.End()
+
+	var input struct {
+		Name        string `json:"name" binding:"required"`
+		Description string `json:"description"`
+		ParentID    string `json:"parentId"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+
+	team, err := tc.teams.Create(ctx, c.Param("orgId"), services.TeamInput{
+		Name:        input.Name,
+		Description: input.Description,
+		ParentID:    input.ParentID,
+	})
+	if err != nil {
+		teamError(c, err, "Failed to create team")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "Team created successfully",
+		"data":    newTeamResponse(team),
+	})
+}
+
+// UpdateTeam changes the fields present in the body. A null or empty
+// parentId moves the team to the top level.
+func (tc *TeamController) UpdateTeam(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "TeamController.UpdateTeam")
+	defer span.End()
+
+	var input map[string]*string
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+	update := services.TeamUpdate{Name: input["name"], Description: input["description"]}
+	if parentID, ok := input["parentId"]; ok {
+		if parentID == nil {
+			parentID = new(string)
+		}
+		update.ParentID = parentID
+	}
+
+	team, err := tc.teams.Update(ctx, c.Param("orgId"), c.GetString("userId"), c.Param("teamId"), update)
+	if err != nil {
+		teamError(c, err, "Failed to update team")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Team updated successfully",
+		"data":    newTeamResponse(team),
+	})
+}
+
+func (tc *TeamController) DeleteTeam(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "TeamController.DeleteTeam")
+	defer span.End()
+
+	if err := tc.teams.Delete(ctx, c.Param("orgId"), c.Param("teamId")); err != nil {
+		teamError(c, err, "Failed to delete team")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Team deleted successfully",
+	})
+}
+
+func (tc *TeamController) ListMembers(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "TeamController.ListMembers")
+	defer span.End()
+
+	members, err := tc.teams.Members(ctx, c.Param("orgId"), c.Param("teamId"))
+	if err != nil {
+		teamError(c, err, "Failed to retrieve team members")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Team members retrieved",
+		"data":    newTeamMemberResponses(members),
+	})
+}
+
+// SetMember adds a member of the organisation to the team, or changes
+// whether they maintain it.
+func (tc *TeamController) SetMember(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "TeamController.SetMember")
+	defer span.End()
+
+	var input struct {
+		Maintainer bool `json:"maintainer"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+
+	err := tc.teams.SetMember(ctx, c.Param("orgId"), c.GetString("userId"), c.Param("teamId"), c.Param("memberId"), input.Maintainer)
+	if err != nil {
+		teamError(c, err, "Failed to add user to team")
+		return
+	}
+	metrics.TeamMembershipChanges.WithLabelValues("added").Inc()
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Team member saved successfully",
+	})
+}
+
+func (tc *TeamController) RemoveMember(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "TeamController.RemoveMember")
+	defer span.End()
+
+	err := tc.teams.RemoveMember(ctx, c.Param("orgId"), c.GetString("userId"), c.Param("teamId"), c.Param("memberId"))
+	if err != nil {
+		teamError(c, err, "Failed to remove user from team")
+		return
+	}
+	metrics.TeamMembershipChanges.WithLabelValues("removed").Inc()
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "User removed from team successfully",
+	})
+}
+
+// AssignRole grants the team's members a built-in or custom role.
+func (tc *TeamController) AssignRole(c *gin.Context) {
+	var input struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+	tc.assignRole(c, "TeamController.AssignRole", input.Role)
+}
+
+// RemoveRole takes the team's role away.
+func (tc *TeamController) RemoveRole(c *gin.Context) {
+	tc.assignRole(c, "TeamController.RemoveRole", "")
+}
+
+func (tc *TeamController) assignRole(c *gin.Context, spanName, role string) {
+	ctx, span := tracing.Start(c.Request.Context(), spanName)
+	defer span.End()
+
+	err := tc.teams.AssignRole(ctx, c.Param("orgId"), c.GetString("userId"), c.Param("teamId"), role)
+	if err != nil {
+		teamError(c, err, "Failed to assign role to team")
+		return
+	}
+	metrics.TeamMembershipChanges.WithLabelValues("role_changed").Inc()
+
+	message := "Role assigned to team successfully"
+	if role == "" {
+		message = "Role removed from team successfully"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": message,
+	})
+}
@@ -4,35 +4,32 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 
-	"github.com/joshua468/user-authentication/models"
+	"github.com/joshua468/user-authentication/services"
+	"github.com/joshua468/user-authentication/tracing"
 )
 
 type UserController struct {
-	db *gorm.DB
+	users *services.UserService
 }
 
-func NewUserController(db *gorm.DB) *UserController {
-	return &UserController{db}
+func NewUserController(users *services.UserService) *UserController {
+	return &UserController{users}
 }
 
 func (uc *UserController) GetUser(c *gin.Context) {
+	ctx, span := tracing.Start(c.Request.Context(), "UserController.GetUser")
+	defer span.End()
+
 	userId := c.Param("id")
-	var user models.User
-	if err := uc.db.Where("user_id = ?", userId).First(&user).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	user, err := uc.users.Get(ctx, userId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, errorBody(c, "User not found"))
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
 		"message": "User found",
-		"data": gin.H{
-			"userId":    user.UserID,
-			"firstName": user.FirstName,
-			"lastName":  user.LastName,
-			"email":     user.Email,
-			"phone":     user.Phone,
-		},
+		"data":    newUserResponse(user),
 	})
 }
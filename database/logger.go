@@ -0,0 +1,27 @@
+package database
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// Logger returns a gorm logger that reports slow queries and errors through
+// slog. Queries are logged with placeholders rather than bound values, which
+// include emails and password hashes.
+func Logger() gormlogger.Interface {
+	return gormlogger.New(slogWriter{}, gormlogger.Config{
+		SlowThreshold:             200 * time.Millisecond,
+		LogLevel:                  gormlogger.Warn,
+		IgnoreRecordNotFoundError: true,
+		ParameterizedQueries:      true,
+	})
+}
+
+type slogWriter struct{}
+
+func (slogWriter) Printf(format string, args ...interface{}) {
+	slog.Warn(fmt.Sprintf(format, args...), "component", "gorm")
+}
@@ -0,0 +1,11 @@
+// Package docs embeds the OpenAPI description of the HTTP API and a
+// self-contained page that renders it, served at /openapi.json and /docs.
+package docs
+
+import _ "embed"
+
+//go:embed openapi.json
+var Spec []byte
+
+//go:embed index.html
+var Page []byte
@@ -0,0 +1,103 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/joshua468/user-authentication/metrics"
+	"github.com/joshua468/user-authentication/middlewares"
+	"github.com/joshua468/user-authentication/models"
+	userauthv1 "github.com/joshua468/user-authentication/proto/userauth/v1"
+	"github.com/joshua468/user-authentication/services"
+	"github.com/joshua468/user-authentication/utils"
+)
+
+type authServer struct {
+	userauthv1.UnimplementedAuthServiceServer
+	auth     *services.AuthService
+	verifier utils.TokenVerifier
+	checker  middlewares.TokenChecker
+}
+
+func (s *authServer) Login(ctx context.Context, req *userauthv1.LoginRequest) (*userauthv1.LoginResponse, error) {
+	if req.GetEmail() == "" || req.GetPassword() == "" {
+		metrics.Logins.WithLabelValues("invalid").Inc()
+		return nil, status.Error(codes.InvalidArgument, "email and password are required")
+	}
+
+	user, token, err := s.auth.Login(ctx, req.GetEmail(), req.GetPassword())
+	switch {
+	case errors.Is(err, services.ErrUserDisabled):
+		metrics.Logins.WithLabelValues("disabled").Inc()
+	case errors.Is(err, services.ErrTokenIssuance):
+		metrics.Logins.WithLabelValues("error").Inc()
+	case err != nil:
+		metrics.Logins.WithLabelValues("invalid_credentials").Inc()
+	default:
+		metrics.Logins.WithLabelValues("success").Inc()
+	}
+	if err != nil {
+		return nil, statusError(ctx, err, "Failed to log in")
+	}
+	if user.PasswordResetRequired {
+		return nil, status.Error(codes.FailedPrecondition, "Password must be changed first, through POST /api/auth/change-password")
+	}
+	return &userauthv1.LoginResponse{AccessToken: token, User: userMessage(user)}, nil
+}
+
+// VerifyToken reports an invalid token in the response rather than as an
+// error, since the call itself succeeded. Tokens that only allow a password
+// change are invalid for other services.
+func (s *authServer) VerifyToken(ctx context.Context, req *userauthv1.VerifyTokenRequest) (*userauthv1.VerifyTokenResponse, error) {
+	claims, err := s.verifier.VerifyToken(req.GetToken())
+	if err == nil && claims.ClientID != "" {
+		err = utils.ErrTokenExchanged
+	}
+	if err != nil {
+		return &userauthv1.VerifyTokenResponse{Valid: false, Error: err.Error()}, nil
+	}
+	if s.checker != nil {
+		err := s.checker.CheckToken(ctx, claims)
+		if utils.TokenRejected(err) {
+			return &userauthv1.VerifyTokenResponse{Valid: false, Error: err.Error()}, nil
+		}
+		if err != nil {
+			return nil, statusError(ctx, err, "Failed to check token")
+		}
+	}
+	if claims.PasswordChangeOnly() {
+		return &userauthv1.VerifyTokenResponse{Valid: false, Error: "password must be changed first"}, nil
+	}
+	resp := &userauthv1.VerifyTokenResponse{Valid: true, UserId: claims.UserID}
+	if claims.ExpiresAt != nil {
+		resp.ExpiresAt = timestamppb.New(claims.ExpiresAt.Time)
+	}
+	return resp, nil
+}
+
+// RefreshToken refuses impersonation tokens, which would otherwise be
+// exchanged for an ordinary token outliving the impersonation.
+func (s *authServer) RefreshToken(ctx context.Context, req *userauthv1.RefreshTokenRequest) (*userauthv1.RefreshTokenResponse, error) {
+	if ActorID(ctx) != "" {
+		return nil, status.Error(codes.PermissionDenied, "Not allowed while impersonating a user")
+	}
+	token, err := s.auth.Refresh(ctx, UserID(ctx))
+	if err != nil {
+		return nil, statusError(ctx, err, "Failed to refresh token")
+	}
+	return &userauthv1.RefreshTokenResponse{AccessToken: token}, nil
+}
+
+func userMessage(user *models.User) *userauthv1.User {
+	return &userauthv1.User{
+		UserId:    user.UserID,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Email:     user.Email,
+		Phone:     user.Phone,
+	}
+}
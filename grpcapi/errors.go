@@ -0,0 +1,38 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/joshua468/user-authentication/services"
+)
+
+// statusError maps a service error to a gRPC status with the same message
+// the REST API would send. Unexpected errors are logged and reported as
+// Internal with message.
+func statusError(ctx context.Context, err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		return status.Error(codes.NotFound, "User not found")
+	case errors.Is(err, services.ErrOrganisationNotFound):
+		return status.Error(codes.NotFound, "Organisation not found")
+	case errors.Is(err, services.ErrNotMember):
+		return status.Error(codes.PermissionDenied, "Only members can access this organisation")
+	case errors.Is(err, services.ErrStaleOrgRole):
+		return status.Error(codes.Unauthenticated, "Your role in this organisation has changed; switch organisation again")
+	case errors.Is(err, services.ErrInvalidCredentials):
+		return status.Error(codes.Unauthenticated, "Invalid email or password")
+	case errors.Is(err, services.ErrUserDisabled):
+		return status.Error(codes.PermissionDenied, "Account is disabled")
+	case errors.Is(err, services.ErrTokenIssuance):
+		slog.ErrorContext(ctx, "Failed to generate token", "error", err)
+		return status.Error(codes.Internal, "Failed to generate token")
+	default:
+		slog.ErrorContext(ctx, message, "error", err)
+		return status.Error(codes.Internal, message)
+	}
+}
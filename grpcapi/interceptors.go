@@ -0,0 +1,143 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/joshua468/user-authentication/logging"
+	"github.com/joshua468/user-authentication/metrics"
+	"github.com/joshua468/user-authentication/middlewares"
+	"github.com/joshua468/user-authentication/utils"
+)
+
+const requestIDMetadata = "x-request-id"
+
+type claimsKey struct{}
+
+// UserID returns the authenticated caller's userId, or "" for public
+// methods.
+func UserID(ctx context.Context) string {
+	claims, _ := ctx.Value(claimsKey{}).(*utils.Claims)
+	if claims == nil {
+		return ""
+	}
+	return claims.UserID
+}
+
+// ActorID returns the impersonating admin's userId when the caller's token
+// was issued by impersonation, or "".
+func ActorID(ctx context.Context) string {
+	claims, _ := ctx.Value(claimsKey{}).(*utils.Claims)
+	if claims == nil {
+		return ""
+	}
+	return claims.ActorID()
+}
+
+// AuthInterceptor checks the bearer token in the "authorization" metadata
+// the way JWTAuthMiddleware checks the Authorization header. Missing
+// credentials and invalid tokens are Unauthenticated; a malformed header is
+// InvalidArgument, matching the middleware's 400. Tokens that only allow a
+// password change are PermissionDenied, as no method changes it. checker
+// may be nil.
+func AuthInterceptor(verifier utils.TokenVerifier, checker middlewares.TokenChecker, public map[string]bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if public[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		var header string
+		if values := metadata.ValueFromIncomingContext(ctx, "authorization"); len(values) > 0 {
+			header = values[0]
+		}
+		token, err := middlewares.BearerToken(header)
+		if errors.Is(err, middlewares.ErrNoCredentials) {
+			metrics.TokenValidations.WithLabelValues("missing").Inc()
+			return nil, status.Error(codes.Unauthenticated, "Authorization header required")
+		}
+		if err != nil {
+			metrics.TokenValidations.WithLabelValues("malformed_header").Inc()
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		claims, err := verifier.VerifyToken(token)
+		if err == nil && claims.ClientID != "" {
+			err = utils.ErrTokenExchanged
+		}
+		if err != nil {
+			metrics.TokenValidations.WithLabelValues(middlewares.TokenFailureReason(err)).Inc()
+			return nil, status.Error(codes.Unauthenticated, "Invalid token: "+err.Error())
+		}
+		if checker != nil {
+			err := checker.CheckToken(ctx, claims)
+			if utils.TokenRejected(err) {
+				metrics.TokenValidations.WithLabelValues(middlewares.TokenFailureReason(err)).Inc()
+				return nil, status.Error(codes.Unauthenticated, "Invalid token: "+err.Error())
+			}
+			if err != nil {
+				return nil, statusError(ctx, err, "Failed to check token")
+			}
+		}
+		if claims.PasswordChangeOnly() {
+			metrics.TokenValidations.WithLabelValues("password_change").Inc()
+			return nil, status.Error(codes.PermissionDenied, "Password must be changed first")
+		}
+		metrics.TokenValidations.WithLabelValues("valid").Inc()
+		ctx = context.WithValue(ctx, claimsKey{}, claims)
+		if actorID := claims.ActorID(); actorID != "" {
+			ctx = logging.WithImpersonator(ctx, actorID)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// RequestIDInterceptor accepts the caller's x-request-id metadata or
+// generates one, and returns it in the response header.
+func RequestIDInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var id string
+		if values := metadata.ValueFromIncomingContext(ctx, requestIDMetadata); len(values) > 0 {
+			id = values[0]
+		}
+		if !middlewares.ValidRequestID(id) {
+			id = utils.GenerateUUID()
+		}
+		grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadata, id))
+		return handler(logging.WithRequestID(ctx, id), req)
+	}
+}
+
+// LoggingInterceptor writes one structured line per call.
+func LoggingInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		code := status.Code(err)
+		level := slog.LevelInfo
+		switch code {
+		case codes.OK:
+		case codes.Internal, codes.Unknown, codes.Unavailable, codes.DataLoss:
+			level = slog.LevelError
+		default:
+			level = slog.LevelWarn
+		}
+		attrs := []slog.Attr{
+			slog.String("method", info.FullMethod),
+			slog.String("code", code.String()),
+			slog.Duration("latency", time.Since(start)),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+		slog.LogAttrs(ctx, level, "grpc request", attrs...)
+		return resp, err
+	}
+}
@@ -0,0 +1,123 @@
+package grpcapi
+
+import (
+	"context"
+	"slices"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/joshua468/user-authentication/middlewares"
+	"github.com/joshua468/user-authentication/models"
+	userauthv1 "github.com/joshua468/user-authentication/proto/userauth/v1"
+	"github.com/joshua468/user-authentication/services"
+	"github.com/joshua468/user-authentication/utils"
+)
+
+// organisationServer applies the permissions the REST API requires for the
+// same data: org.read to see an organisation and org.members.read to see
+// who else belongs to it.
+type organisationServer struct {
+	userauthv1.UnimplementedOrganisationServiceServer
+	orgs   *services.OrgService
+	roles  *services.RoleService
+	admins middlewares.AdminChecker
+}
+
+// requireTokenOrg is the gRPC counterpart of middlewares.RequireTokenOrg:
+// a token scoped to one organisation is refused for any other.
+func requireTokenOrg(ctx context.Context, orgID string) error {
+	claims, _ := ctx.Value(claimsKey{}).(*utils.Claims)
+	if claims != nil && claims.OrgID != "" && claims.OrgID != orgID {
+		return status.Error(codes.PermissionDenied, "Token is scoped to another organisation")
+	}
+	return nil
+}
+
+// requirePermission is the gRPC counterpart of
+// OrganisationController.RequirePermission, after requireTokenOrg.
+func (s *organisationServer) requirePermission(ctx context.Context, orgID, permission string) error {
+	if err := requireTokenOrg(ctx, orgID); err != nil {
+		return err
+	}
+	var perms []string
+	var err error
+	claims, _ := ctx.Value(claimsKey{}).(*utils.Claims)
+	if claims != nil && claims.OrgRole != "" {
+		perms, err = s.roles.TokenPermissions(ctx, orgID, claims.UserID, claims.OrgRole)
+	} else {
+		perms, err = s.roles.MemberPermissions(ctx, orgID, UserID(ctx))
+	}
+	if err != nil {
+		return statusError(ctx, err, "Failed to check permissions")
+	}
+	if !slices.Contains(perms, permission) {
+		return status.Error(codes.PermissionDenied, "Missing permission "+permission)
+	}
+	return nil
+}
+
+// ListOrganisations lists the caller's organisations. Only admins, and not
+// while impersonating, may name another user.
+func (s *organisationServer) ListOrganisations(ctx context.Context, req *userauthv1.ListOrganisationsRequest) (*userauthv1.ListOrganisationsResponse, error) {
+	userID := req.GetUserId()
+	if userID == "" {
+		userID = UserID(ctx)
+	}
+	if userID != UserID(ctx) {
+		admin := false
+		if s.admins != nil && ActorID(ctx) == "" {
+			var err error
+			if admin, err = s.admins.IsAdmin(ctx, UserID(ctx)); err != nil {
+				return nil, statusError(ctx, err, "Failed to check admin rights")
+			}
+		}
+		if !admin {
+			return nil, status.Error(codes.PermissionDenied, "Only admins can list the organisations of other users")
+		}
+	}
+	orgs, err := s.orgs.ListForUser(ctx, userID)
+	if err != nil {
+		return nil, statusError(ctx, err, "Failed to retrieve organisations")
+	}
+
+	resp := &userauthv1.ListOrganisationsResponse{Organisations: make([]*userauthv1.Organisation, 0, len(orgs))}
+	for i := range orgs {
+		resp.Organisations = append(resp.Organisations, organisationMessage(&orgs[i]))
+	}
+	return resp, nil
+}
+
+func (s *organisationServer) GetOrganisation(ctx context.Context, req *userauthv1.GetOrganisationRequest) (*userauthv1.GetOrganisationResponse, error) {
+	if err := s.requirePermission(ctx, req.GetOrgId(), models.PermOrgRead); err != nil {
+		return nil, err
+	}
+	org, err := s.orgs.Get(ctx, req.GetOrgId())
+	if err != nil {
+		return nil, statusError(ctx, err, "Failed to find organisation")
+	}
+	return &userauthv1.GetOrganisationResponse{Organisation: organisationMessage(org)}, nil
+}
+
+// CheckMembership tells whether a user belongs to the organisation. Callers
+// may always ask about themselves, unless their token is scoped to another
+// organisation; asking about others needs org.members.read.
+func (s *organisationServer) CheckMembership(ctx context.Context, req *userauthv1.CheckMembershipRequest) (*userauthv1.CheckMembershipResponse, error) {
+	if err := requireTokenOrg(ctx, req.GetOrgId()); err != nil {
+		return nil, err
+	}
+	if req.GetUserId() != UserID(ctx) {
+		if err := s.requirePermission(ctx, req.GetOrgId(), models.PermMembersRead); err != nil {
+			return nil, err
+		}
+	}
+	member, err := s.orgs.IsMember(ctx, req.GetOrgId(), req.GetUserId())
+	if err != nil {
+		return nil, statusError(ctx, err, "Failed to check membership")
+	}
+	return &userauthv1.CheckMembershipResponse{Member: member}, nil
+}
+
+func organisationMessage(org *models.Organisation) *userauthv1.Organisation {
+	return &userauthv1.Organisation{OrgId: org.OrgID, Name: org.Name, Description: org.Description}
+}
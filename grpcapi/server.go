@@ -0,0 +1,50 @@
+// Package grpcapi serves the gRPC API defined in proto/userauth/v1. Like the
+// gin controllers it is a thin adapter over the services package, so both
+// APIs share their business rules.
+package grpcapi
+
+import (
+	"google.golang.org/grpc"
+
+	"github.com/joshua468/user-authentication/middlewares"
+	userauthv1 "github.com/joshua468/user-authentication/proto/userauth/v1"
+	"github.com/joshua468/user-authentication/services"
+	"github.com/joshua468/user-authentication/utils"
+)
+
+// Services are the dependencies of the gRPC API. Checker is optional; without
+// it revoked tokens and tokens of disabled users are accepted until they
+// expire. Admins may list the organisations of other users; without it
+// nobody may.
+type Services struct {
+	Auth     *services.AuthService
+	Users    *services.UserService
+	Orgs     *services.OrgService
+	Roles    *services.RoleService
+	Admins   middlewares.AdminChecker
+	Verifier utils.TokenVerifier
+	Checker  middlewares.TokenChecker
+}
+
+// publicMethods can be called without an access token.
+var publicMethods = map[string]bool{
+	userauthv1.AuthService_Login_FullMethodName:       true,
+	userauthv1.AuthService_VerifyToken_FullMethodName: true,
+}
+
+// NewServer returns a gRPC server with every service registered behind the
+// request ID, logging and authentication interceptors.
+func NewServer(svc Services, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			RequestIDInterceptor(),
+			LoggingInterceptor(),
+			AuthInterceptor(svc.Verifier, svc.Checker, publicMethods),
+		),
+	}, opts...)
+	server := grpc.NewServer(opts...)
+	userauthv1.RegisterAuthServiceServer(server, &authServer{auth: svc.Auth, verifier: svc.Verifier, checker: svc.Checker})
+	userauthv1.RegisterUserServiceServer(server, &userServer{users: svc.Users})
+	userauthv1.RegisterOrganisationServiceServer(server, &organisationServer{orgs: svc.Orgs, roles: svc.Roles, admins: svc.Admins})
+	return server
+}
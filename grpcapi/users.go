@@ -0,0 +1,21 @@
+package grpcapi
+
+import (
+	"context"
+
+	userauthv1 "github.com/joshua468/user-authentication/proto/userauth/v1"
+	"github.com/joshua468/user-authentication/services"
+)
+
+type userServer struct {
+	userauthv1.UnimplementedUserServiceServer
+	users *services.UserService
+}
+
+func (s *userServer) GetUser(ctx context.Context, req *userauthv1.GetUserRequest) (*userauthv1.GetUserResponse, error) {
+	user, err := s.users.Get(ctx, req.GetUserId())
+	if err != nil {
+		return nil, statusError(ctx, err, "Failed to find user")
+	}
+	return &userauthv1.GetUserResponse{User: userMessage(user)}, nil
+}
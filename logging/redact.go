@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute names whose values are always dropped,
+// matched case-insensitively as substrings so that "newPassword" and
+// "accessToken" are caught too.
+var sensitiveKeys = []string{
+	"password", "passwd", "secret", "token", "authorization", "cookie",
+	"email", "phone", "dsn",
+}
+
+// Values are also scrubbed, since emails and tokens turn up inside error
+// messages (for example a unique constraint violation quoting the email).
+var (
+	emailPattern  = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	jwtPattern    = regexp.MustCompile(`eyJ[A-Za-z0-9_\-]*\.[A-Za-z0-9_\-]*\.[A-Za-z0-9_\-]*`)
+	bearerPattern = regexp.MustCompile(`(?i)bearer\s+\S+`)
+	bcryptPattern = regexp.MustCompile(`\$2[abxy]?\$\d{2}\$[./A-Za-z0-9]{53}`)
+	phonePattern  = regexp.MustCompile(`\+\d[\d\s\-()]{6,}\d`)
+)
+
+// RedactString replaces emails, JWTs, bearer credentials, bcrypt hashes and
+// international phone numbers in s.
+func RedactString(s string) string {
+	for _, pattern := range []*regexp.Regexp{bearerPattern, jwtPattern, bcryptPattern, emailPattern, phonePattern} {
+		s = pattern.ReplaceAllString(s, redacted)
+	}
+	return s
+}
+
+func sensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if sensitiveKey(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, RedactString(a.Value.String()))
+	case slog.KindAny:
+		// Structs such as models.User would otherwise be marshalled field by
+		// field, so they are flattened to a string and scrubbed like one.
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, RedactString(err.Error()))
+		}
+		return slog.String(a.Key, RedactString(fmt.Sprintf("%+v", a.Value.Any())))
+	}
+	return a
+}
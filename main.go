@@ -1,86 +1,367 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/driver/postgres"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"google.golang.org/grpc"
 	"gorm.io/gorm"
 
+	"github.com/joshua468/user-authentication/config"
 	"github.com/joshua468/user-authentication/controllers"
+	"github.com/joshua468/user-authentication/database"
+	"github.com/joshua468/user-authentication/grpcapi"
+	"github.com/joshua468/user-authentication/logging"
+	"github.com/joshua468/user-authentication/metrics"
 	"github.com/joshua468/user-authentication/middlewares"
-	"github.com/joshua468/user-authentication/models"
+	"github.com/joshua468/user-authentication/migrations"
+	"github.com/joshua468/user-authentication/repositories"
+	"github.com/joshua468/user-authentication/routes"
+	"github.com/joshua468/user-authentication/services"
+	"github.com/joshua468/user-authentication/tracing"
+	"github.com/joshua468/user-authentication/utils"
 )
 
-var db *gorm.DB
+var (
+	cfg *config.Config
+	db  *gorm.DB
+)
 
-func connect() {
+// loadConfig reads the file named by CONFIG_FILE, if any, then the .env file
+// (ENV_FILE, default ".env") and the environment, and exits on invalid
+// configuration.
+func loadConfig() {
+	envFile := os.Getenv("ENV_FILE")
+	if envFile == "" {
+		envFile = ".env"
+	}
+	var err error
+	cfg, err = config.Load(os.Getenv("CONFIG_FILE"), envFile)
+	if err != nil {
+		fatal("Failed to load configuration", "error", err)
+	}
 
-	dbhost := os.Getenv("DB_HOST")
-	dbuser := os.Getenv("DB_USER")
-	dbpassword := os.Getenv("DB_PASSWORD")
-	dbname := os.Getenv("DB_NAME")
-	port := os.Getenv("DB_PORT")
+	logger, err := logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		fatal("Invalid log level", "level", cfg.Log.Level, "error", err)
+	}
+	// SetDefault also routes the standard library's log package through
+	// the JSON handler.
+	slog.SetDefault(logger)
+}
+
+// fatal logs msg at error level and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
 
+func connect() {
 	// Database connection
-	dsn := fmt.Sprintf("host=%v user=%v password=%v dbname=%v port=%v sslmode=disable", dbhost, dbuser, dbpassword, dbname, port)
-	fmt.Println(dsn)
 	var err error
-	db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err = database.Open(cfg.Database, &gorm.Config{Logger: database.Logger()})
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal("Failed to connect to database", "error", err)
 	}
+}
 
-	// Migrate the schema
-	db.AutoMigrate(&models.User{}, &models.Organisation{})
+// appServices are the services shared by the HTTP server and the admin
+// subcommands, backed by the GORM repositories.
+type appServices struct {
+	jwt          *utils.JWTService
+	users        *services.UserService
+	orgs         *services.OrgService
+	roles        *services.RoleService
+	teams        *services.TeamService
+	keys         *services.KeyService
+	clients      *services.ClientService
+	tokenService *services.TokenService
+	admin        *services.AdminService
+	authz        *services.AuthzService
 }
 
-// func loadenv() {
-// 	if err := godotenv.Load(".env"); err != nil {
-// 		log.Fatalf("Error loading .env file: %v", err)
-// 	}
-// }
+func newServices() *appServices {
+	userRepo := repositories.NewGormUserRepository(db)
+	orgRepo := repositories.NewGormOrganisationRepository(db)
+	jwt := utils.NewJWTService(cfg.JWT.Secret, loadTokenOptions())
+	users := services.NewUserService(userRepo)
+	tokens := services.NewTokenService(jwt, repositories.NewGormRevokedTokenRepository(db), userRepo, orgRepo)
+	teamRepo := repositories.NewGormTeamRepository(db)
+	roles := services.NewRoleService(repositories.NewGormRoleRepository(db), orgRepo, teamRepo)
+	return &appServices{
+		jwt:          jwt,
+		users:        users,
+		orgs:         services.NewOrgService(orgRepo, userRepo),
+		roles:        roles,
+		teams:        services.NewTeamService(teamRepo, orgRepo, userRepo, roles),
+		keys:         services.NewKeyService(repositories.NewGormSigningKeyRepository(db)),
+		clients:      services.NewClientService(repositories.NewGormServiceClientRepository(db), repositories.NewGormExchangePolicyRepository(db)),
+		tokenService: tokens,
+		admin:        services.NewAdminService(users, userRepo, orgRepo, tokens, repositories.NewGormAuditEventRepository(db)),
+		authz:        services.NewAuthzService(repositories.NewGormRelationshipRepository(db), repositories.NewGormAuthzSchemaRepository(db)),
+	}
+}
 
-func loadserver() {
-	router := gin.Default()
+func loadTokenOptions() utils.TokenOptions {
+	tokenOptions := utils.DefaultTokenOptions()
+	tokenOptions.Issuer = cfg.JWT.Issuer
+	tokenOptions.Audience = cfg.JWT.Audience
+	tokenOptions.TTL = cfg.JWT.TTL.Duration()
+	tokenOptions.Leeway = cfg.JWT.Leeway.Duration()
+	// Tokens minted before the jwt library migration are accepted until the
+	// fixed jwt.legacyUntil date, and refused when it is not set.
+	tokenOptions.LegacyUntil = cfg.JWT.LegacyUntil
+	return tokenOptions
+}
 
-	// Initialize controllers
-	authController := controllers.NewAuthController(db, os.Getenv("JWT_SECRET"))
-	orgController := controllers.NewOrganisationController(db)
-	userController := controllers.NewUserController(db)
-
-	// Routes
-	api := router.Group("/api")
-	{
-		authRoutes := api.Group("/auth")
-		{
-			authRoutes.POST("/register", authController.Register)
-			authRoutes.POST("/login", authController.Login)
+// refreshSigningKeys reloads rotated signing keys so that keys created by
+// "keys rotate" are picked up without a restart.
+func refreshSigningKeys(ctx context.Context, keys *services.KeyService, tokens *utils.JWTService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
 		}
-		userRoutes := api.Group("/users").Use(middlewares.JWTAuthMiddleware(os.Getenv("JWT_SECRET")))
-		{
-			userRoutes.GET("/:id", userController.GetUser)
+		signingKeys, err := keys.SigningKeys(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to refresh signing keys", "error", err)
+			continue
 		}
-		orgRoutes := api.Group("/organisations").Use(middlewares.JWTAuthMiddleware(os.Getenv("JWT_SECRET")))
-		{
-			orgRoutes.GET("/", orgController.GetOrganisations)
-			orgRoutes.GET("/:orgId", orgController.GetOrganisation)
-			orgRoutes.POST("/", orgController.CreateOrganisation)
-			orgRoutes.POST("/:orgId/users", orgController.AddUserToOrganisation)
+		tokens.SetKeys(signingKeys)
+	}
+}
+
+// readinessChecks are the dependencies /readyz reports on. migrator is
+// built once at startup, so probes only read the database's version.
+func readinessChecks(tokens *utils.JWTService, migrator *migrations.Migrator) []controllers.ReadinessCheck {
+	return []controllers.ReadinessCheck{
+		{Name: "database", Check: func(ctx context.Context) error {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.PingContext(ctx)
+		}},
+		{Name: "migrations", Check: func(ctx context.Context) error {
+			version, err := migrator.Version(ctx)
+			if err != nil {
+				return err
+			}
+			if version != migrator.Latest() {
+				return fmt.Errorf("database is at version %d, want %d", version, migrator.Latest())
+			}
+			return nil
+		}},
+		{Name: "signingKeys", Check: func(ctx context.Context) error {
+			if !tokens.HasSigningKey() {
+				return errors.New("no signing key loaded")
+			}
+			return nil
+		}},
+	}
+}
+
+// tracedRequest leaves probes and scrapes out of the traces.
+func tracedRequest(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/healthz", "/readyz", "/metrics":
+		return false
+	}
+	return true
+}
+
+func loadserver() {
+	slog.Info("Effective configuration", "config", cfg.String())
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		fatal("Failed to set up tracing", "error", err)
+	}
+	router := gin.New()
+	router.Use(
+		middlewares.RequestID(),
+		// Before Logger, so request log lines carry the trace ID.
+		otelgin.Middleware(tracing.ServiceName, otelgin.WithFilter(tracedRequest)),
+		middlewares.Logger(slog.Default()),
+		middlewares.Recovery(slog.Default()),
+		middlewares.Metrics(),
+	)
+	if sqlDB, err := db.DB(); err == nil {
+		if err := metrics.RegisterDBStats(sqlDB, cfg.Database.Driver()); err != nil {
+			slog.Warn("Failed to register database metrics", "error", err)
 		}
 	}
 
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	svc := newServices()
+	tokens := svc.jwt
+	signingKeys, err := svc.keys.SigningKeys(ctx)
+	if err != nil {
+		fatal("Failed to load signing keys", "error", err)
+	}
+	tokens.SetKeys(signingKeys)
+	go refreshSigningKeys(ctx, svc.keys, tokens, time.Minute)
+
+	jwtConfig := middlewares.JWTConfig{
+		Verifier:   tokens,
+		Checker:    svc.tokenService,
+		Auditor:    svc.admin,
+		CookieName: cfg.JWT.CookieName,
+	}
+
+	// Initialize controllers
+	authService := services.NewAuthService(svc.users, tokens)
+	authController := controllers.NewAuthController(authService)
+	authController.CookieName = jwtConfig.CookieName
+	authController.Tokens = svc.tokenService
+	orgController := controllers.NewOrganisationController(svc.orgs, svc.roles)
+	userController := controllers.NewUserController(svc.users)
+	migrator, err := migrations.New(db)
+	if err != nil {
+		fatal("Failed to load migrations", "error", err)
+	}
+	healthController := controllers.NewHealthController(readinessChecks(tokens, migrator)...)
+
+	routes.Register(router, routes.Handlers{
+		Auth:               authController,
+		Users:              userController,
+		Organisations:      orgController,
+		Teams:              controllers.NewTeamController(svc.teams),
+		Health:             healthController,
+		OAuth:              controllers.NewOAuthController(svc.clients, svc.tokenService),
+		Admin:              controllers.NewAdminController(svc.admin),
+		Authz:              controllers.NewAuthzController(svc.authz),
+		Authenticate:       middlewares.JWTAuthMiddlewareWithConfig(jwtConfig),
+		RequireAdmin:       middlewares.RequireAdmin(svc.admin),
+		AuthenticateClient: middlewares.RequireClient(svc.clients, "authz"),
+	})
+
 	// Start server
-	if err := router.Run(":8080"); err != nil {
-		log.Fatalf("Failed to run server: %v", err)
+	srv := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler:           router,
+		ReadTimeout:       cfg.Server.ReadTimeout.Duration(),
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout.Duration(),
+		WriteTimeout:      cfg.Server.WriteTimeout.Duration(),
+		IdleTimeout:       cfg.Server.IdleTimeout.Duration(),
+	}
+	serverErr := make(chan error, 2)
+	go func() {
+		slog.Info("Listening", "addr", srv.Addr)
+		serverErr <- srv.ListenAndServe()
+	}()
+
+	var grpcServer *grpc.Server
+	if cfg.Server.GRPCPort != 0 {
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.Server.GRPCPort))
+		if err != nil {
+			fatal("Failed to listen for gRPC", "error", err)
+		}
+		grpcServer = grpcapi.NewServer(grpcapi.Services{
+			Auth:     authService,
+			Users:    svc.users,
+			Orgs:     svc.orgs,
+			Roles:    svc.roles,
+			Admins:   svc.admin,
+			Verifier: tokens,
+			Checker:  svc.tokenService,
+		})
+		go func() {
+			slog.Info("Listening for gRPC", "addr", listener.Addr().String())
+			serverErr <- grpcServer.Serve(listener)
+		}()
+	}
+
+	select {
+	case err := <-serverErr:
+		fatal("Failed to run server", "error", err)
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutting down, draining requests", "timeout", cfg.Server.ShutdownTimeout.Duration())
+	healthController.ShuttingDown()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Duration())
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Failed to shut down cleanly", "error", err)
+	}
+	if grpcServer != nil {
+		stopGRPC(shutdownCtx, grpcServer)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
+
+// stopGRPC waits for in-flight calls to finish, cancelling them when ctx
+// expires.
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		slog.Error("Timed out draining gRPC calls")
+		server.Stop()
 	}
 }
 
 func main() {
-	// loadenv()
-	connect()
-	loadserver()
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
 
+	if command == "help" || command == "-h" || command == "-help" || command == "--help" {
+		usage()
+		return
+	}
+	loadConfig()
+
+	switch command {
+	case "config":
+		fmt.Print(cfg)
+	case "serve":
+		connect()
+		migrateUp()
+		loadserver()
+	case "migrate":
+		connect()
+		runMigrate(args)
+	case "user":
+		connect()
+		runUser(args)
+	case "org":
+		connect()
+		runOrg(args)
+	case "keys":
+		connect()
+		runKeys(args)
+	case "client":
+		connect()
+		runClient(args)
+	default:
+		usage()
+		os.Exit(2)
+	}
 }
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "user_authentication"
+
+var (
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route template, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	Registrations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "registrations_total",
+		Help:      "User registrations by outcome.",
+	}, []string{"outcome"})
+
+	Logins = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Login attempts by outcome.",
+	}, []string{"outcome"})
+
+	TokenValidations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "token_validations_total",
+		Help:      "Access token validations by result; failures carry the reason.",
+	}, []string{"result"})
+
+	TokenExchanges = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "token_exchanges_total",
+		Help:      "Token exchange requests by outcome; rejections carry the OAuth error code.",
+	}, []string{"outcome"})
+
+	OrgMembershipChanges = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "org_membership_changes_total",
+		Help:      "Organisation membership changes by action.",
+	}, []string{"action"})
+
+	TeamMembershipChanges = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "team_membership_changes_total",
+		Help:      "Team membership changes by action (added, removed or role_changed).",
+	}, []string{"action"})
+
+	AuthzChecks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "authz_checks_total",
+		Help:      "Relationship authorization checks by result (allowed, denied or error).",
+	}, []string{"result"})
+
+	PasswordHashDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "password_hash_duration_seconds",
+		Help:      "Time spent in bcrypt by operation (hash or compare).",
+		Buckets:   []float64{.01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation"})
+)
+
+// ObservePasswordHash records how long a bcrypt operation started at start
+// took.
+func ObservePasswordHash(operation string, start time.Time) {
+	PasswordHashDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// RegisterDBStats exports connection pool gauges for db.
+func RegisterDBStats(db *sql.DB, name string) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, name))
+}
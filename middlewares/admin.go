@@ -0,0 +1,27 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminChecker reports whether a user is a platform operator, usually
+// services.AdminService.
+type AdminChecker interface {
+	IsAdmin(ctx context.Context, userID string) (bool, error)
+}
+
+// RequireAdmin rejects requests from users who are not admins with 403. It
+// must run after the JWT middleware, which sets the userId it checks.
+func RequireAdmin(checker AdminChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		admin, err := checker.IsAdmin(c.Request.Context(), c.GetString("userId"))
+		if err != nil || !admin {
+			c.AbortWithStatusJSON(http.StatusForbidden, errorBody(c, "Admin access required"))
+			return
+		}
+		c.Next()
+	}
+}
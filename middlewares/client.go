@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/joshua468/user-authentication/models"
+)
+
+// ClientAuthenticator checks service client credentials, usually
+// services.ClientService.
+type ClientAuthenticator interface {
+	Authenticate(ctx context.Context, clientID, secret string) (*models.ServiceClient, error)
+}
+
+// RequireClient lets through requests from service clients authenticated
+// with HTTP Basic, and sets clientId. Others get 401 with a challenge for
+// realm.
+func RequireClient(clients ClientAuthenticator, realm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientID, secret, _ := c.Request.BasicAuth()
+		client, err := clients.Authenticate(c.Request.Context(), clientID, secret)
+		if err != nil {
+			c.Header("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", realm))
+			c.AbortWithStatusJSON(http.StatusUnauthorized, errorBody(c, "Service client credentials required"))
+			return
+		}
+		c.Set("clientId", client.ClientID)
+		c.Set("clientScopes", client.Scopes)
+		c.Next()
+	}
+}
+
+// RequireClientScope rejects requests from service clients without scope,
+// one of models.ClientScopes, with 403. It must run after RequireClient.
+func RequireClientScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !slices.Contains(strings.Fields(c.GetString("clientScopes")), scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, errorBody(c, "Client lacks the "+scope+" scope"))
+			return
+		}
+		c.Next()
+	}
+}
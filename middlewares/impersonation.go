@@ -0,0 +1,20 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RejectImpersonation guards sensitive routes, such as credential changes
+// and the admin API, from sessions started by impersonation. It must run
+// after the JWT middleware, which sets actorId for those sessions.
+func RejectImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("actorId") != "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, errorBody(c, "Not allowed while impersonating a user"))
+			return
+		}
+		c.Next()
+	}
+}
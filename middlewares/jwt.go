@@ -1,31 +1,223 @@
 package middlewares
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/joshua468/user-authentication/logging"
+	"github.com/joshua468/user-authentication/metrics"
+	"github.com/joshua468/user-authentication/tracing"
 	"github.com/joshua468/user-authentication/utils"
 )
 
+// RFC 6750 section 3.1 error codes.
+const (
+	bearerInvalidRequest    = "invalid_request"
+	bearerInvalidToken      = "invalid_token"
+	bearerInsufficientScope = "insufficient_scope"
+)
+
+var (
+	ErrNoCredentials          = errors.New("no bearer credentials")
+	ErrMalformedAuthorization = errors.New("malformed Authorization header")
+)
+
+// TokenChecker makes the checks on a verified token that need the
+// database, usually services.TokenService. CheckToken returns an error
+// matched by TokenRejected for a token that must be refused.
+type TokenChecker interface {
+	CheckToken(ctx context.Context, claims *utils.Claims) error
+}
+
+// ImpersonationAuditor records the changes made with impersonation tokens,
+// usually services.AdminService.
+type ImpersonationAuditor interface {
+	RecordImpersonatedRequest(ctx context.Context, userID, impersonatorID, request string) error
+}
+
+// JWTConfig configures JWTAuthMiddlewareWithConfig. CookieName is optional;
+// when set, a token carried in that cookie is accepted if the request has no
+// Authorization header. Checker is optional too; without it revoked tokens
+// and tokens of disabled users are accepted until they expire. Auditor, if
+// set, records every successful POST, PUT, PATCH or DELETE made with an
+// impersonation token.
+type JWTConfig struct {
+	Verifier   utils.TokenVerifier
+	Checker    TokenChecker
+	Auditor    ImpersonationAuditor
+	Realm      string
+	CookieName string
+}
+
 func JWTAuthMiddleware(secret string) gin.HandlerFunc {
+	return JWTAuthMiddlewareWithConfig(JWTConfig{
+		Verifier: utils.NewJWTService(secret, utils.DefaultTokenOptions()),
+	})
+}
+
+func JWTAuthMiddlewareWithConfig(cfg JWTConfig) gin.HandlerFunc {
+	if cfg.Realm == "" {
+		cfg.Realm = "api"
+	}
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
+		tokenString, err := extractToken(c, cfg.CookieName)
+		if errors.Is(err, ErrNoCredentials) {
+			metrics.TokenValidations.WithLabelValues("missing").Inc()
+			abortWithBearerError(c, cfg.Realm, http.StatusUnauthorized, "", "Authorization header required")
+			return
+		}
+		if err != nil {
+			metrics.TokenValidations.WithLabelValues("malformed_header").Inc()
+			abortWithBearerError(c, cfg.Realm, http.StatusBadRequest, bearerInvalidRequest, err.Error())
 			return
 		}
 
-		tokenString := strings.Split(authHeader, "Bearer ")[1]
-		claims, err := utils.ParseToken(tokenString, secret)
+		_, span := tracing.Start(c.Request.Context(), "jwt.Verify")
+		claims, err := cfg.Verifier.VerifyToken(tokenString)
+		span.End()
+		if err == nil && claims.ClientID != "" {
+			// Exchanged tokens are for the services they were issued to, even
+			// when an exchange policy names this API's audience.
+			err = utils.ErrTokenExchanged
+		}
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
+			metrics.TokenValidations.WithLabelValues(TokenFailureReason(err)).Inc()
+			abortWithBearerError(c, cfg.Realm, http.StatusUnauthorized, bearerInvalidToken, "Invalid token: "+err.Error())
+			return
+		}
+
+		if cfg.Checker != nil {
+			err := cfg.Checker.CheckToken(c.Request.Context(), claims)
+			if utils.TokenRejected(err) {
+				metrics.TokenValidations.WithLabelValues(TokenFailureReason(err)).Inc()
+				abortWithBearerError(c, cfg.Realm, http.StatusUnauthorized, bearerInvalidToken, "Invalid token: "+err.Error())
+				return
+			}
+			if err != nil {
+				c.Error(err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, errorBody(c, "Failed to check token"))
+				return
+			}
+		}
+
+		if claims.PasswordChangeOnly() && !c.GetBool(passwordChangeKey) {
+			metrics.TokenValidations.WithLabelValues("password_change").Inc()
+			abortWithBearerError(c, cfg.Realm, http.StatusForbidden, bearerInsufficientScope, "Password must be changed first")
 			return
 		}
 
+		metrics.TokenValidations.WithLabelValues("valid").Inc()
 		c.Set("userId", claims.UserID)
+		c.Set("claims", claims)
+		if claims.OrgID != "" {
+			c.Set("orgId", claims.OrgID)
+			c.Set("orgRole", claims.OrgRole)
+		}
+		if actorID := claims.ActorID(); actorID != "" {
+			// userId stays the impersonated user so handlers behave as they
+			// would for them; the admin is flagged in logs and audit events.
+			c.Set("actorId", actorID)
+			c.Request = c.Request.WithContext(logging.WithImpersonator(c.Request.Context(), actorID))
+			if cfg.Auditor != nil && c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+				c.Next()
+				auditImpersonatedRequest(c, cfg.Auditor, claims.UserID, actorID)
+				return
+			}
+		}
 		c.Next()
 	}
 }
+
+// auditImpersonatedRequest records the request once it has been handled,
+// unless it failed and so changed nothing.
+func auditImpersonatedRequest(c *gin.Context, auditor ImpersonationAuditor, userID, actorID string) {
+	if c.Writer.Status() >= http.StatusBadRequest {
+		return
+	}
+	request := c.Request.Method + " " + c.Request.URL.Path
+	if err := auditor.RecordImpersonatedRequest(c.Request.Context(), userID, actorID, request); err != nil {
+		c.Error(fmt.Errorf("audit %s: %w", request, err))
+	}
+}
+
+// TokenFailureReason maps a verification error to a low-cardinality metric
+// label.
+func TokenFailureReason(err error) string {
+	switch {
+	case errors.Is(err, utils.ErrTokenExpired):
+		return "expired"
+	case errors.Is(err, utils.ErrTokenNotValidYet):
+		return "not_valid_yet"
+	case errors.Is(err, utils.ErrTokenSignatureInvalid):
+		return "signature_invalid"
+	case errors.Is(err, utils.ErrTokenAlgorithm):
+		return "algorithm"
+	case errors.Is(err, utils.ErrTokenIssuer):
+		return "issuer"
+	case errors.Is(err, utils.ErrTokenAudience):
+		return "audience"
+	case errors.Is(err, utils.ErrTokenLegacy):
+		return "legacy"
+	case errors.Is(err, utils.ErrTokenExchanged):
+		return "exchanged"
+	case errors.Is(err, utils.ErrTokenUnknownKey):
+		return "unknown_key"
+	case errors.Is(err, utils.ErrTokenMalformed):
+		return "malformed"
+	case errors.Is(err, utils.ErrTokenRevoked):
+		return "revoked"
+	case errors.Is(err, utils.ErrTokenSubjectDisabled):
+		return "disabled"
+	case errors.Is(err, utils.ErrTokenSubjectUnknown):
+		return "unknown_subject"
+	case errors.Is(err, utils.ErrTokenActorRevoked):
+		return "actor_revoked"
+	default:
+		return "other"
+	}
+}
+
+// extractToken reads a bearer token from the Authorization header and falls
+// back to cookieName if configured.
+func extractToken(c *gin.Context, cookieName string) (string, error) {
+	if authHeader := strings.TrimSpace(c.GetHeader("Authorization")); authHeader != "" {
+		return BearerToken(authHeader)
+	}
+	if cookieName != "" {
+		if cookie, err := c.Cookie(cookieName); err == nil && cookie != "" {
+			return cookie, nil
+		}
+	}
+	return "", ErrNoCredentials
+}
+
+// BearerToken parses an Authorization header value, matching the scheme
+// case-insensitively. The gRPC interceptor uses it for the "authorization"
+// metadata so both APIs accept exactly the same credentials.
+func BearerToken(authHeader string) (string, error) {
+	scheme, credentials, _ := strings.Cut(strings.TrimSpace(authHeader), " ")
+	if scheme == "" || !strings.EqualFold(scheme, "Bearer") {
+		return "", ErrNoCredentials
+	}
+	credentials = strings.TrimSpace(credentials)
+	if credentials == "" || strings.ContainsAny(credentials, " \t") {
+		return "", ErrMalformedAuthorization
+	}
+	return credentials, nil
+}
+
+// abortWithBearerError sets an RFC 6750 WWW-Authenticate challenge. The error
+// attribute is omitted when the request carried no credentials at all.
+func abortWithBearerError(c *gin.Context, realm string, status int, code, description string) {
+	challenge := fmt.Sprintf("Bearer realm=%q", realm)
+	if code != "" {
+		challenge += fmt.Sprintf(", error=%q, error_description=%q", code, description)
+	}
+	c.Header("WWW-Authenticate", challenge)
+	c.AbortWithStatusJSON(status, errorBody(c, description))
+}
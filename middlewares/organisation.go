@@ -0,0 +1,21 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireTokenOrg rejects requests made with a token scoped to one
+// organisation for routes about another, named by the param path parameter.
+// Unscoped tokens are let through for the handler to check membership. It
+// must run after the JWT middleware, which sets the token's orgId.
+func RequireTokenOrg(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if orgID := c.GetString("orgId"); orgID != "" && orgID != c.Param(param) {
+			c.AbortWithStatusJSON(http.StatusForbidden, errorBody(c, "Token is scoped to another organisation"))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,19 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// passwordChangeKey marks a route that accepts tokens limited to
+// utils.ScopePasswordChange.
+const passwordChangeKey = "allowPasswordChange"
+
+// AllowPasswordChange lets the tokens Login issues to users who must change
+// their password reach the route, which JWTAuthMiddleware otherwise refuses.
+// It must run before the JWT middleware.
+func AllowPasswordChange() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(passwordChangeKey, true)
+		c.Next()
+	}
+}
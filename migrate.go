@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joshua468/user-authentication/migrations"
+)
+
+// migrateUp applies pending migrations before the server starts. Set
+// AUTO_MIGRATE=false to leave that to an explicit "migrate up".
+func migrateUp() {
+	if !cfg.AutoMigrate {
+		return
+	}
+	migrator, err := migrations.New(db)
+	if err != nil {
+		fatal("Failed to load migrations", "error", err)
+	}
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		fatal("Failed to migrate database", "error", err)
+	}
+	for _, m := range applied {
+		slog.Info("Applied migration", "version", m.Version, "name", m.Name)
+	}
+}
+
+type migrationOutput struct {
+	Version int64  `json:"version"`
+	Name    string `json:"name"`
+}
+
+func newMigrationOutputs(ms []migrations.Migration) []migrationOutput {
+	out := make([]migrationOutput, 0, len(ms))
+	for _, m := range ms {
+		out = append(out, migrationOutput{Version: m.Version, Name: m.Name})
+	}
+	return out
+}
+
+func writeMigrations(w *tabwriter.Writer, verb string, ms []migrationOutput) {
+	for _, m := range ms {
+		fmt.Fprintf(w, "%s %d_%s\n", verb, m.Version, m.Name)
+	}
+	if len(ms) == 0 {
+		fmt.Fprintln(w, "nothing to do")
+	}
+}
+
+func runMigrate(args []string) {
+	cmd := newCommand("migrate", "up | down [n] | status | version")
+	timeout := cmd.Duration("timeout", 5*time.Minute, "how long to wait for the migration lock")
+	cmd.Parse(args)
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		fatal("Failed to load migrations", "error", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	switch cmd.Arg(0) {
+	case "up", "":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			fatal("Failed to migrate database", "error", err)
+		}
+		out := newMigrationOutputs(applied)
+		cmd.output(out, func(w *tabwriter.Writer) { writeMigrations(w, "applied", out) })
+	case "down":
+		steps := 1
+		if cmd.NArg() > 1 {
+			if steps, err = strconv.Atoi(cmd.Arg(1)); err != nil || steps < 1 {
+				fatal("Invalid number of steps", "steps", cmd.Arg(1))
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			fatal("Failed to roll back database", "error", err)
+		}
+		out := newMigrationOutputs(reverted)
+		cmd.output(out, func(w *tabwriter.Writer) { writeMigrations(w, "reverted", out) })
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			fatal("Failed to read migration status", "error", err)
+		}
+		cmd.output(statuses, func(w *tabwriter.Writer) {
+			fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+			for _, s := range statuses {
+				applied := "pending"
+				if s.AppliedAt != nil {
+					applied = s.AppliedAt.Format(time.RFC3339)
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+			}
+		})
+	case "version":
+		version, err := migrator.Version(ctx)
+		if err != nil {
+			fatal("Failed to read migration version", "error", err)
+		}
+		cmd.output(map[string]int64{"version": version}, func(w *tabwriter.Writer) {
+			fmt.Fprintln(w, version)
+		})
+	default:
+		cmd.Usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,298 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	historyTable = "schema_migrations"
+	lockTable    = "schema_migrations_lock"
+
+	// advisoryLockKey identifies the migration lock in pg_advisory_lock, and
+	// advisoryLockName in MySQL's GET_LOCK.
+	advisoryLockKey  = 7263016028
+	advisoryLockName = "schema_migrations"
+
+	// staleLockAge is how long a row in the lock table may go unrefreshed
+	// before it is assumed to belong to a crashed process and is removed.
+	// The holder refreshes it every lockRefresh, so a long migration keeps
+	// its lock.
+	staleLockAge = 10 * time.Minute
+	lockRefresh  = staleLockAge / 4
+)
+
+var ErrUnknownVersion = errors.New("database has migrations this binary does not know about")
+
+// Status describes one migration and whether it has been applied.
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"appliedAt"`
+}
+
+type historyRow struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time
+}
+
+// Migrator applies versioned SQL migrations and records them in the
+// schema_migrations table. Concurrent runs are serialised with an advisory
+// lock on Postgres and MySQL, or a lock row on SQLite.
+//
+// MySQL commits DDL implicitly, so a failing MySQL migration can leave its
+// earlier statements applied without a history row.
+type Migrator struct {
+	db         *gorm.DB
+	dialect    string
+	migrations []Migration
+	lockWait   time.Duration
+}
+
+func New(db *gorm.DB) (*Migrator, error) {
+	dialect := db.Dialector.Name()
+	migrations, err := Load(dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations, lockWait: 200 * time.Millisecond}, nil
+}
+
+// Migrations returns every known migration in version order.
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Latest returns the highest known migration version.
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies all pending migrations and returns the ones it applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func(conn *gorm.DB) error {
+		history, err := m.history(conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := history[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(conn, migration, true); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the most recently applied migrations, at most steps of them.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withLock(ctx, func(conn *gorm.DB) error {
+		history, err := m.history(conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := history[migration.Version]; !ok {
+				continue
+			}
+			if err := m.apply(conn, migration, false); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every known migration with the time it was applied, if any.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn := m.db.WithContext(ctx)
+	if err := m.ensureTables(conn); err != nil {
+		return nil, err
+	}
+	history, err := m.history(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if row, ok := history[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Version returns the highest applied migration version, or 0 if none are.
+// It returns ErrUnknownVersion if the database is ahead of this binary.
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	var version int64
+	err := m.db.WithContext(ctx).Table(historyTable).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	if err != nil {
+		return 0, err
+	}
+	if version > m.Latest() {
+		return version, ErrUnknownVersion
+	}
+	return version, nil
+}
+
+func (m *Migrator) apply(conn *gorm.DB, migration Migration, up bool) error {
+	sql := migration.Down
+	if up {
+		sql = migration.Up
+	}
+	return conn.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range statements(sql) {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+		}
+		if up {
+			return tx.Table(historyTable).Create(map[string]interface{}{
+				"version":    migration.Version,
+				"name":       migration.Name,
+				"applied_at": time.Now().UTC(),
+			}).Error
+		}
+		return tx.Exec("DELETE FROM "+historyTable+" WHERE version = ?", migration.Version).Error
+	})
+}
+
+func (m *Migrator) history(conn *gorm.DB) (map[int64]historyRow, error) {
+	var rows []historyRow
+	if err := conn.Table(historyTable).Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	history := make(map[int64]historyRow, len(rows))
+	for _, row := range rows {
+		history[row.Version] = row
+	}
+	return history, nil
+}
+
+func (m *Migrator) ensureTables(conn *gorm.DB) error {
+	if err := conn.Exec(`CREATE TABLE IF NOT EXISTS ` + historyTable + ` (
+		version BIGINT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`).Error; err != nil {
+		return err
+	}
+	if m.dialect != "sqlite" {
+		return nil
+	}
+	return conn.Exec(`CREATE TABLE IF NOT EXISTS ` + lockTable + ` (
+		id INTEGER PRIMARY KEY,
+		locked_at TIMESTAMP NOT NULL
+	)`).Error
+}
+
+// withLock runs fn on a single pinned connection while holding the
+// migration lock.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if err := m.ensureTables(conn); err != nil {
+			return err
+		}
+		if err := m.lock(ctx, conn); err != nil {
+			return err
+		}
+		defer m.unlock(conn)
+		if m.dialect == "sqlite" {
+			defer m.refreshLock(ctx)()
+		}
+		return fn(conn)
+	})
+}
+
+func (m *Migrator) lock(ctx context.Context, conn *gorm.DB) error {
+	switch m.dialect {
+	case "postgres":
+		return conn.Exec("SELECT pg_advisory_lock(?)", advisoryLockKey).Error
+	case "mysql":
+		var acquired int
+		deadline, ok := ctx.Deadline()
+		timeout := 3600
+		if ok {
+			timeout = max(int(time.Until(deadline).Seconds()), 1)
+		}
+		if err := conn.Raw("SELECT GET_LOCK(?, ?)", advisoryLockName, timeout).Scan(&acquired).Error; err != nil {
+			return err
+		}
+		if acquired != 1 {
+			return errors.New("timed out waiting for migration lock")
+		}
+		return nil
+	}
+
+	for {
+		conn.Exec("DELETE FROM "+lockTable+" WHERE locked_at < ?", time.Now().UTC().Add(-staleLockAge))
+		err := conn.Exec("INSERT INTO "+lockTable+" (id, locked_at) VALUES (1, ?)", time.Now().UTC()).Error
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for migration lock: %w", ctx.Err())
+		case <-time.After(m.lockWait):
+		}
+	}
+}
+
+// refreshLock keeps the SQLite lock row fresh until the returned function is
+// called, which waits for the refresher to stop. It writes through the pool
+// rather than the pinned connection, which fn is using.
+func (m *Migrator) refreshLock(ctx context.Context) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(lockRefresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.db.WithContext(ctx).Exec("UPDATE "+lockTable+" SET locked_at = ? WHERE id = 1", time.Now().UTC())
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func (m *Migrator) unlock(conn *gorm.DB) {
+	switch m.dialect {
+	case "postgres":
+		conn.Exec("SELECT pg_advisory_unlock(?)", advisoryLockKey)
+	case "mysql":
+		conn.Exec("SELECT RELEASE_LOCK(?)", advisoryLockName)
+	default:
+		conn.Exec("DELETE FROM " + lockTable + " WHERE id = 1")
+	}
+}
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS users (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    created_at DATETIME(3) NULL,
+    updated_at DATETIME(3) NULL,
+    deleted_at DATETIME(3) NULL,
+    user_id VARCHAR(191) NOT NULL,
+    first_name VARCHAR(255) NOT NULL,
+    last_name VARCHAR(255) NOT NULL,
+    email VARCHAR(191) NOT NULL,
+    password VARCHAR(255) NOT NULL,
+    phone VARCHAR(64),
+    CONSTRAINT uni_users_user_id UNIQUE (user_id),
+    CONSTRAINT uni_users_email UNIQUE (email),
+    INDEX idx_users_deleted_at (deleted_at)
+);
+
+CREATE TABLE IF NOT EXISTS organisations (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    created_at DATETIME(3) NULL,
+    updated_at DATETIME(3) NULL,
+    deleted_at DATETIME(3) NULL,
+    org_id VARCHAR(191) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    CONSTRAINT uni_organisations_org_id UNIQUE (org_id),
+    INDEX idx_organisations_deleted_at (deleted_at)
+);
+
+CREATE TABLE IF NOT EXISTS organisation_users (
+    organisation_id BIGINT UNSIGNED NOT NULL,
+    user_id BIGINT UNSIGNED NOT NULL,
+    PRIMARY KEY (organisation_id, user_id),
+    CONSTRAINT fk_organisation_users_organisation FOREIGN KEY (organisation_id) REFERENCES organisations (id),
+    CONSTRAINT fk_organisation_users_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
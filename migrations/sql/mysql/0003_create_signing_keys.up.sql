@@ -0,0 +1,12 @@
+CREATE TABLE signing_keys (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    created_at DATETIME(3) NULL,
+    updated_at DATETIME(3) NULL,
+    deleted_at DATETIME(3) NULL,
+    key_id VARCHAR(191) NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    not_before DATETIME(3) NOT NULL,
+    retires_at DATETIME(3) NULL,
+    CONSTRAINT uni_signing_keys_key_id UNIQUE (key_id),
+    INDEX idx_signing_keys_deleted_at (deleted_at)
+);
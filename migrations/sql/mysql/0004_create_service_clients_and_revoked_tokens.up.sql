@@ -0,0 +1,25 @@
+CREATE TABLE service_clients (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    created_at DATETIME(3) NULL,
+    updated_at DATETIME(3) NULL,
+    deleted_at DATETIME(3) NULL,
+    client_id VARCHAR(191) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    secret_hash VARCHAR(255) NOT NULL,
+    disabled_at DATETIME(3) NULL,
+    CONSTRAINT uni_service_clients_client_id UNIQUE (client_id),
+    INDEX idx_service_clients_deleted_at (deleted_at)
+);
+
+CREATE TABLE revoked_tokens (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    created_at DATETIME(3) NULL,
+    updated_at DATETIME(3) NULL,
+    deleted_at DATETIME(3) NULL,
+    token_id VARCHAR(191) NOT NULL,
+    user_id VARCHAR(191) NOT NULL,
+    expires_at DATETIME(3) NOT NULL,
+    CONSTRAINT uni_revoked_tokens_token_id UNIQUE (token_id),
+    INDEX idx_revoked_tokens_deleted_at (deleted_at),
+    INDEX idx_revoked_tokens_expires_at (expires_at)
+);
@@ -0,0 +1,3 @@
+-- Existing memberships become plain members; who created an organisation
+-- was never recorded.
+ALTER TABLE organisation_users ADD COLUMN role VARCHAR(32) NOT NULL DEFAULT 'member';
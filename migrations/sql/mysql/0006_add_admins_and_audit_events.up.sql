@@ -0,0 +1,14 @@
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN sessions_revoked_at DATETIME(3) NULL;
+
+CREATE TABLE audit_events (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    created_at DATETIME(3) NOT NULL,
+    actor_id VARCHAR(191) NOT NULL,
+    action VARCHAR(64) NOT NULL,
+    target_type VARCHAR(32) NOT NULL,
+    target_id VARCHAR(191) NOT NULL,
+    request_id VARCHAR(128) NULL,
+    INDEX idx_audit_events_created_at (created_at),
+    INDEX idx_audit_events_target (target_type, target_id)
+);
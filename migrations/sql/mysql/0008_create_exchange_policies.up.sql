@@ -0,0 +1,10 @@
+CREATE TABLE exchange_policies (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    created_at DATETIME(3) NULL,
+    updated_at DATETIME(3) NULL,
+    client_id VARCHAR(191) NOT NULL,
+    audience VARCHAR(191) NOT NULL,
+    scopes TEXT NOT NULL,
+    max_ttl_seconds INT NOT NULL,
+    CONSTRAINT uni_exchange_policies_client_audience UNIQUE (client_id, audience)
+);
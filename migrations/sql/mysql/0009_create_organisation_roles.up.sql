@@ -0,0 +1,13 @@
+CREATE TABLE organisation_roles (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    created_at DATETIME(3) NULL,
+    updated_at DATETIME(3) NULL,
+    role_id VARCHAR(191) NOT NULL,
+    organisation_id BIGINT UNSIGNED NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    permissions TEXT NOT NULL,
+    CONSTRAINT uni_organisation_roles_role_id UNIQUE (role_id),
+    CONSTRAINT uni_organisation_roles_name UNIQUE (organisation_id, name),
+    CONSTRAINT fk_organisation_roles_organisation FOREIGN KEY (organisation_id) REFERENCES organisations (id)
+);
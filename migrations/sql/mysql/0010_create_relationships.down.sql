@@ -0,0 +1,2 @@
+DROP TABLE authz_schemas;
+DROP TABLE relationships;
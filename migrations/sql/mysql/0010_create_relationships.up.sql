@@ -0,0 +1,18 @@
+CREATE TABLE relationships (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    created_at DATETIME(3) NULL,
+    namespace VARCHAR(64) NOT NULL,
+    object_id VARCHAR(128) NOT NULL,
+    relation VARCHAR(64) NOT NULL,
+    subject_namespace VARCHAR(64) NOT NULL,
+    subject_id VARCHAR(128) NOT NULL,
+    subject_relation VARCHAR(64) NOT NULL DEFAULT '',
+    CONSTRAINT uni_relationships_tuple UNIQUE (namespace, object_id, relation, subject_namespace, subject_id, subject_relation)
+);
+CREATE INDEX idx_relationships_subject ON relationships (subject_namespace, subject_id, subject_relation);
+
+CREATE TABLE authz_schemas (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    created_at DATETIME(3) NULL,
+    source TEXT NOT NULL
+);
@@ -0,0 +1,25 @@
+CREATE TABLE teams (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    created_at DATETIME(3) NULL,
+    updated_at DATETIME(3) NULL,
+    team_id VARCHAR(191) NOT NULL,
+    organisation_id BIGINT UNSIGNED NOT NULL,
+    parent_id BIGINT UNSIGNED NULL,
+    name VARCHAR(64) NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    role VARCHAR(64) NOT NULL DEFAULT '',
+    CONSTRAINT uni_teams_team_id UNIQUE (team_id),
+    CONSTRAINT uni_teams_name UNIQUE (organisation_id, name),
+    CONSTRAINT fk_teams_organisation FOREIGN KEY (organisation_id) REFERENCES organisations (id),
+    CONSTRAINT fk_teams_parent FOREIGN KEY (parent_id) REFERENCES teams (id)
+);
+
+CREATE TABLE team_users (
+    team_id BIGINT UNSIGNED NOT NULL,
+    user_id BIGINT UNSIGNED NOT NULL,
+    maintainer BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (team_id, user_id),
+    CONSTRAINT fk_team_users_team FOREIGN KEY (team_id) REFERENCES teams (id),
+    CONSTRAINT fk_team_users_user FOREIGN KEY (user_id) REFERENCES users (id),
+    INDEX idx_team_users_user (user_id)
+);
@@ -0,0 +1,12 @@
+-- 0005 made every existing member a plain member, leaving organisations
+-- created before it without an owner. The member who registered first, the
+-- closest record of who created the organisation, becomes its owner. MySQL
+-- cannot read the table an UPDATE writes in a subquery, hence the join.
+UPDATE organisation_users
+JOIN (
+    SELECT organisation_id, MIN(user_id) AS user_id FROM organisation_users
+    GROUP BY organisation_id
+    HAVING SUM(role = 'owner') = 0
+) first_members ON first_members.organisation_id = organisation_users.organisation_id
+    AND first_members.user_id = organisation_users.user_id
+SET organisation_users.role = 'owner';
@@ -0,0 +1,3 @@
+-- Existing clients get no scopes, so they keep only the read access every
+-- client has. TEXT columns cannot have a default in MySQL.
+ALTER TABLE service_clients ADD COLUMN scopes VARCHAR(255) NOT NULL DEFAULT '';
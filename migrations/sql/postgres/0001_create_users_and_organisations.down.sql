@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS organisation_users;
+DROP TABLE IF EXISTS organisations;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,39 @@
+-- Matches the schema previously produced by gorm AutoMigrate, so existing
+-- databases can adopt versioned migrations without changes.
+CREATE TABLE IF NOT EXISTS users (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    user_id TEXT NOT NULL,
+    first_name TEXT NOT NULL,
+    last_name TEXT NOT NULL,
+    email TEXT NOT NULL,
+    password TEXT NOT NULL,
+    phone TEXT,
+    CONSTRAINT uni_users_user_id UNIQUE (user_id),
+    CONSTRAINT uni_users_email UNIQUE (email)
+);
+
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS organisations (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    org_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT,
+    CONSTRAINT uni_organisations_org_id UNIQUE (org_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_organisations_deleted_at ON organisations (deleted_at);
+
+CREATE TABLE IF NOT EXISTS organisation_users (
+    organisation_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    PRIMARY KEY (organisation_id, user_id),
+    CONSTRAINT fk_organisation_users_organisation FOREIGN KEY (organisation_id) REFERENCES organisations (id),
+    CONSTRAINT fk_organisation_users_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
@@ -0,0 +1,13 @@
+CREATE TABLE signing_keys (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    key_id TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    not_before TIMESTAMPTZ NOT NULL,
+    retires_at TIMESTAMPTZ,
+    CONSTRAINT uni_signing_keys_key_id UNIQUE (key_id)
+);
+
+CREATE INDEX idx_signing_keys_deleted_at ON signing_keys (deleted_at);
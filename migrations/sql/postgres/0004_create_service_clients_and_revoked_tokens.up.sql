@@ -0,0 +1,27 @@
+CREATE TABLE service_clients (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    client_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    secret_hash TEXT NOT NULL,
+    disabled_at TIMESTAMPTZ,
+    CONSTRAINT uni_service_clients_client_id UNIQUE (client_id)
+);
+
+CREATE INDEX idx_service_clients_deleted_at ON service_clients (deleted_at);
+
+CREATE TABLE revoked_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    token_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT uni_revoked_tokens_token_id UNIQUE (token_id)
+);
+
+CREATE INDEX idx_revoked_tokens_deleted_at ON revoked_tokens (deleted_at);
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
@@ -0,0 +1,10 @@
+CREATE TABLE exchange_policies (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    client_id TEXT NOT NULL,
+    audience TEXT NOT NULL,
+    scopes TEXT NOT NULL,
+    max_ttl_seconds INTEGER NOT NULL,
+    CONSTRAINT uni_exchange_policies_client_audience UNIQUE (client_id, audience)
+);
@@ -0,0 +1,13 @@
+CREATE TABLE organisation_roles (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    role_id TEXT NOT NULL,
+    organisation_id BIGINT NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    permissions TEXT NOT NULL,
+    CONSTRAINT uni_organisation_roles_role_id UNIQUE (role_id),
+    CONSTRAINT uni_organisation_roles_name UNIQUE (organisation_id, name),
+    CONSTRAINT fk_organisation_roles_organisation FOREIGN KEY (organisation_id) REFERENCES organisations (id)
+);
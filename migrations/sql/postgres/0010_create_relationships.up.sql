@@ -0,0 +1,18 @@
+CREATE TABLE relationships (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    namespace TEXT NOT NULL,
+    object_id TEXT NOT NULL,
+    relation TEXT NOT NULL,
+    subject_namespace TEXT NOT NULL,
+    subject_id TEXT NOT NULL,
+    subject_relation TEXT NOT NULL DEFAULT '',
+    CONSTRAINT uni_relationships_tuple UNIQUE (namespace, object_id, relation, subject_namespace, subject_id, subject_relation)
+);
+CREATE INDEX idx_relationships_subject ON relationships (subject_namespace, subject_id, subject_relation);
+
+CREATE TABLE authz_schemas (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    source TEXT NOT NULL
+);
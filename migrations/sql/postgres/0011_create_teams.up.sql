@@ -0,0 +1,25 @@
+CREATE TABLE teams (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    team_id TEXT NOT NULL,
+    organisation_id BIGINT NOT NULL,
+    parent_id BIGINT,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    role TEXT NOT NULL DEFAULT '',
+    CONSTRAINT uni_teams_team_id UNIQUE (team_id),
+    CONSTRAINT uni_teams_name UNIQUE (organisation_id, name),
+    CONSTRAINT fk_teams_organisation FOREIGN KEY (organisation_id) REFERENCES organisations (id),
+    CONSTRAINT fk_teams_parent FOREIGN KEY (parent_id) REFERENCES teams (id)
+);
+
+CREATE TABLE team_users (
+    team_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    maintainer BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (team_id, user_id),
+    CONSTRAINT fk_team_users_team FOREIGN KEY (team_id) REFERENCES teams (id),
+    CONSTRAINT fk_team_users_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE INDEX idx_team_users_user ON team_users (user_id);
@@ -0,0 +1,11 @@
+-- 0005 made every existing member a plain member, leaving organisations
+-- created before it without an owner. The member who registered first, the
+-- closest record of who created the organisation, becomes its owner.
+UPDATE organisation_users SET role = 'owner'
+WHERE user_id = (
+    SELECT MIN(members.user_id) FROM organisation_users members
+    WHERE members.organisation_id = organisation_users.organisation_id
+)
+AND organisation_id NOT IN (
+    SELECT organisation_id FROM organisation_users WHERE role = 'owner'
+);
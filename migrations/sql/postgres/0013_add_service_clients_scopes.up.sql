@@ -0,0 +1,3 @@
+-- Existing clients get no scopes, so they keep only the read access every
+-- client has.
+ALTER TABLE service_clients ADD COLUMN scopes TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,12 @@
+CREATE TABLE signing_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at DATETIME,
+    updated_at DATETIME,
+    deleted_at DATETIME,
+    key_id TEXT NOT NULL UNIQUE,
+    secret TEXT NOT NULL,
+    not_before DATETIME NOT NULL,
+    retires_at DATETIME
+);
+
+CREATE INDEX idx_signing_keys_deleted_at ON signing_keys (deleted_at);
@@ -0,0 +1,25 @@
+CREATE TABLE service_clients (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at DATETIME,
+    updated_at DATETIME,
+    deleted_at DATETIME,
+    client_id TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    secret_hash TEXT NOT NULL,
+    disabled_at DATETIME
+);
+
+CREATE INDEX idx_service_clients_deleted_at ON service_clients (deleted_at);
+
+CREATE TABLE revoked_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at DATETIME,
+    updated_at DATETIME,
+    deleted_at DATETIME,
+    token_id TEXT NOT NULL UNIQUE,
+    user_id TEXT NOT NULL,
+    expires_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_revoked_tokens_deleted_at ON revoked_tokens (deleted_at);
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
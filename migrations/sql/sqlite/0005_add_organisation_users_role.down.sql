@@ -0,0 +1 @@
+ALTER TABLE organisation_users DROP COLUMN role;
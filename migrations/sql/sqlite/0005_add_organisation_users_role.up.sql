@@ -0,0 +1,3 @@
+-- Existing memberships become plain members; who created an organisation
+-- was never recorded.
+ALTER TABLE organisation_users ADD COLUMN role TEXT NOT NULL DEFAULT 'member';
@@ -0,0 +1,3 @@
+DROP TABLE audit_events;
+ALTER TABLE users DROP COLUMN sessions_revoked_at;
+ALTER TABLE users DROP COLUMN is_admin;
@@ -0,0 +1 @@
+DROP TABLE organisation_roles;
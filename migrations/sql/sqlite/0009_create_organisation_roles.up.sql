@@ -0,0 +1,11 @@
+CREATE TABLE organisation_roles (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at DATETIME,
+    updated_at DATETIME,
+    role_id TEXT NOT NULL UNIQUE,
+    organisation_id INTEGER NOT NULL REFERENCES organisations (id),
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    permissions TEXT NOT NULL,
+    CONSTRAINT uni_organisation_roles_name UNIQUE (organisation_id, name)
+);
@@ -0,0 +1,2 @@
+DROP TABLE team_users;
+DROP TABLE teams;
@@ -0,0 +1,20 @@
+CREATE TABLE teams (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at DATETIME,
+    updated_at DATETIME,
+    team_id TEXT NOT NULL UNIQUE,
+    organisation_id INTEGER NOT NULL REFERENCES organisations (id),
+    parent_id INTEGER REFERENCES teams (id),
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    role TEXT NOT NULL DEFAULT '',
+    CONSTRAINT uni_teams_name UNIQUE (organisation_id, name)
+);
+
+CREATE TABLE team_users (
+    team_id INTEGER NOT NULL REFERENCES teams (id),
+    user_id INTEGER NOT NULL REFERENCES users (id),
+    maintainer BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (team_id, user_id)
+);
+CREATE INDEX idx_team_users_user ON team_users (user_id);
@@ -0,0 +1,2 @@
+-- Backfilled owners cannot be told apart from owners appointed since, so
+-- they keep their role.
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Audit actions.
+const (
+	AuditUserDisabled        = "user.disabled"
+	AuditUserEnabled         = "user.enabled"
+	AuditUserPasswordReset   = "user.password_reset"
+	AuditUserSessionsRevoked = "user.sessions_revoked"
+	AuditUserAdminGranted    = "user.admin_granted"
+	AuditUserAdminRevoked    = "user.admin_revoked"
+	AuditUserImpersonated    = "user.impersonated"
+	// AuditImpersonatedRequest records a change made with an impersonation
+	// token; the target is the request, such as "POST /api/organisations".
+	AuditImpersonatedRequest = "request.impersonated"
+)
+
+// AuditEvent records an administrative action, or a change made while an
+// admin impersonated a user. Events are only ever
+// inserted, so there is no UpdatedAt or soft delete.
+type AuditEvent struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"not null" json:"createdAt"`
+	// ActorID is the userId of the admin who acted, of the impersonated user
+	// for AuditImpersonatedRequest, or "cli" for commands run on the server.
+	ActorID    string `gorm:"not null" json:"actorId"`
+	Action     string `gorm:"not null" json:"action"`
+	TargetType string `gorm:"not null" json:"targetType"`
+	TargetID   string `gorm:"not null" json:"targetId"`
+	RequestID  string `json:"requestId"`
+	// ImpersonatorID is set when ActorID acted in a session started by this
+	// admin through impersonation.
+	ImpersonatorID string `json:"impersonatorId,omitempty"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// ExchangePolicy allows a service client to exchange users' access tokens
+// for tokens addressed to Audience (RFC 8693). The new tokens carry at most
+// Scopes, a space-separated list, and live at most MaxTTLSeconds. There is
+// one policy per client and audience, and removing it deletes the row.
+type ExchangePolicy struct {
+	ID            uint      `gorm:"primarykey" json:"-"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+	ClientID      string    `gorm:"not null" json:"clientId"`
+	Audience      string    `gorm:"not null" json:"audience"`
+	Scopes        string    `gorm:"not null" json:"scopes"`
+	MaxTTLSeconds int       `gorm:"column:max_ttl_seconds;not null" json:"maxTtlSeconds"`
+}
+
+// MaxTTL returns MaxTTLSeconds as a duration.
+func (p *ExchangePolicy) MaxTTL() time.Duration {
+	return time.Duration(p.MaxTTLSeconds) * time.Second
+}
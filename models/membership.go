@@ -0,0 +1,30 @@
+package models
+
+// Organisation roles, from most to least privileged.
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+// Roles lists the built-in organisation roles; organisations may define
+// more, see Role.
+var Roles = []string{RoleOwner, RoleAdmin, RoleMember}
+
+// Membership is a row of the organisation_users join table behind
+// Organisation.Users, with the member's role.
+type Membership struct {
+	OrganisationID uint   `gorm:"primaryKey"`
+	UserID         uint   `gorm:"primaryKey"`
+	Role           string `gorm:"not null;default:member"`
+}
+
+func (Membership) TableName() string {
+	return "organisation_users"
+}
+
+// Member is a user as seen through one organisation.
+type Member struct {
+	User
+	Role string
+}
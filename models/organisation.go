@@ -7,5 +7,5 @@ type Organisation struct {
 	OrgID       string `gorm:"unique;not null" json:"orgId"`
 	Name        string `gorm:"not null" json:"name"`
 	Description string `json:"description"`
-	Users       []User `gorm:"many2many:organisation_users;" json:"users"`
+	Users       []User `gorm:"many2many:organisation_users;" json:"-"`
 }
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// Namespaces whose relationships are derived from memberships and teams
+// rather than stored.
+const (
+	NamespaceUser         = "user"
+	NamespaceOrganisation = "organisation"
+	NamespaceTeam         = "team"
+)
+
+// MembershipRelations are the relations of the organisation namespace that
+// mirror organisation_users: member holds every member, owner and admin the
+// members with those roles.
+var MembershipRelations = []string{RoleOwner, RoleAdmin, RoleMember}
+
+// Relations of the team namespace.
+const (
+	TeamRelationOrganisation = "organisation"
+	TeamRelationParent       = "parent"
+	TeamRelationMaintainer   = "maintainer"
+	TeamRelationMember       = "member"
+)
+
+// TeamRelations mirror teams and team_users: organisation holds a team's
+// organisation, parent the team it is nested in, maintainer its
+// maintainers, and member its members and the members of the teams nested
+// in it.
+var TeamRelations = []string{TeamRelationOrganisation, TeamRelationParent, TeamRelationMaintainer, TeamRelationMember}
+
+// IsDerived reports whether the relationships of namespace's relation are
+// derived from memberships or teams, and so cannot be written.
+func IsDerived(namespace, relation string) bool {
+	switch namespace {
+	case NamespaceOrganisation:
+		return slices.Contains(MembershipRelations, relation)
+	case NamespaceTeam:
+		return slices.Contains(TeamRelations, relation)
+	}
+	return false
+}
+
+// Relationship is a relation tuple: the subject SubjectNamespace:SubjectID
+// (or, if SubjectRelation is not empty, every subject of that object's
+// relation) has Relation to the object Namespace:ObjectID.
+type Relationship struct {
+	ID               uint `gorm:"primarykey"`
+	CreatedAt        time.Time
+	Namespace        string `gorm:"not null"`
+	ObjectID         string `gorm:"not null"`
+	Relation         string `gorm:"not null"`
+	SubjectNamespace string `gorm:"not null"`
+	SubjectID        string `gorm:"not null"`
+	SubjectRelation  string `gorm:"not null"`
+}
+
+// String formats r as namespace:object#relation@subject.
+func (r Relationship) String() string {
+	s := r.Namespace + ":" + r.ObjectID + "#" + r.Relation + "@" + r.SubjectNamespace + ":" + r.SubjectID
+	if r.SubjectRelation != "" {
+		s += "#" + r.SubjectRelation
+	}
+	return s
+}
+
+// AuthzSchema is one version of the authorization schema; the latest is in
+// force. Older versions are kept as a history.
+type AuthzSchema struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	Source    string `gorm:"not null"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RevokedToken records an access token, by its jti, that must no longer be
+// accepted. Rows can be deleted once ExpiresAt has passed.
+type RevokedToken struct {
+	gorm.Model
+	TokenID   string    `gorm:"unique;not null" json:"tokenId"`
+	UserID    string    `gorm:"not null" json:"userId"`
+	ExpiresAt time.Time `gorm:"not null" json:"expiresAt"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Organisation permissions. Routes about an organisation each require one.
+const (
+	PermOrgRead       = "org.read"
+	PermMembersRead   = "org.members.read"
+	PermMembersInvite = "org.members.invite"
+	PermMembersUpdate = "org.members.update"
+	PermRolesManage   = "org.roles.manage"
+	PermTeamsManage   = "org.teams.manage"
+)
+
+// Permission is an entry of the catalogue custom roles are built from.
+type Permission struct {
+	Name        string
+	Description string
+}
+
+// Permissions is the catalogue of organisation permissions.
+var Permissions = []Permission{
+	{PermOrgRead, "View the organisation and its roles"},
+	{PermMembersRead, "List the organisation's members"},
+	{PermMembersInvite, "Add users to the organisation"},
+	{PermMembersUpdate, "Change members' roles"},
+	{PermRolesManage, "Create, update and delete custom roles"},
+	{PermTeamsManage, "Create, move and delete teams, and manage any team's members"},
+}
+
+// IsPermission reports whether name is in the catalogue.
+func IsPermission(name string) bool {
+	return slices.ContainsFunc(Permissions, func(p Permission) bool { return p.Name == name })
+}
+
+func allPermissions() []string {
+	names := make([]string, 0, len(Permissions))
+	for _, p := range Permissions {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// BuiltinPermissions holds the permissions of the built-in roles, which
+// every organisation has and which cannot be changed.
+var BuiltinPermissions = map[string][]string{
+	RoleOwner:  allPermissions(),
+	RoleAdmin:  allPermissions(),
+	RoleMember: {PermOrgRead, PermMembersRead},
+}
+
+// Role is a custom role defined by one organisation. Members are assigned a
+// role by name, so Name is unique within the organisation and never that of
+// a built-in role. Permissions is a space-separated list from the catalogue.
+type Role struct {
+	ID             uint `gorm:"primarykey"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	RoleID         string `gorm:"unique;not null"`
+	OrganisationID uint   `gorm:"not null"`
+	Name           string `gorm:"not null"`
+	Description    string `gorm:"not null"`
+	Permissions    string `gorm:"not null"`
+}
+
+func (Role) TableName() string {
+	return "organisation_roles"
+}
+
+// PermissionList returns Permissions split into names.
+func (r *Role) PermissionList() []string {
+	return strings.Fields(r.Permissions)
+}
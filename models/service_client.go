@@ -0,0 +1,32 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ClientScopeAuthzWrite lets a service client change the authorization
+// schema and relationships; any client may read and check them.
+const ClientScopeAuthzWrite = "authz.write"
+
+// ClientScopes are the scopes a service client can be granted.
+var ClientScopes = []string{ClientScopeAuthzWrite}
+
+// ServiceClient is a downstream service allowed to call /oauth/introspect.
+// Only a SHA-256 hash of its secret is stored. Scopes, a space-separated
+// list of ClientScopes, allow it more of this API.
+type ServiceClient struct {
+	gorm.Model
+	ClientID   string     `gorm:"unique;not null" json:"clientId"`
+	Name       string     `gorm:"not null" json:"name"`
+	SecretHash string     `gorm:"not null" json:"-"`
+	Scopes     string     `gorm:"not null" json:"scopes"`
+	DisabledAt *time.Time `json:"disabledAt"`
+}
+
+func (c *ServiceClient) HasScope(scope string) bool {
+	return slices.Contains(strings.Fields(c.Scopes), scope)
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SigningKey is an HMAC secret used to sign access tokens. The newest key
+// whose NotBefore has passed signs new tokens; every key that has not reached
+// RetiresAt is still accepted for verification.
+type SigningKey struct {
+	gorm.Model
+	KeyID     string     `gorm:"unique;not null" json:"keyId"`
+	Secret    string     `gorm:"not null" json:"-"`
+	NotBefore time.Time  `gorm:"not null" json:"notBefore"`
+	RetiresAt *time.Time `json:"retiresAt"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Team groups members of one organisation. A team nested in a parent is
+// part of it: the parent's members include the child's, and the child's
+// members hold the role granted to the parent. Role, if not empty, is a
+// built-in or custom role other than owner that every member holds on top
+// of their own.
+type Team struct {
+	ID             uint `gorm:"primarykey"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	TeamID         string `gorm:"unique;not null"`
+	OrganisationID uint   `gorm:"not null"`
+	ParentID       *uint
+	Parent         *Team  `gorm:"foreignKey:ParentID"`
+	Name           string `gorm:"not null"`
+	Description    string `gorm:"not null"`
+	Role           string `gorm:"not null"`
+}
+
+// TeamMembership is a row of the team_users join table. Maintainers manage
+// the team's name, description and members.
+type TeamMembership struct {
+	TeamID     uint `gorm:"primaryKey"`
+	UserID     uint `gorm:"primaryKey"`
+	Maintainer bool `gorm:"not null"`
+}
+
+func (TeamMembership) TableName() string {
+	return "team_users"
+}
+
+// TeamMember is a user as seen through one team.
+type TeamMember struct {
+	User
+	Maintainer bool
+}
@@ -1,13 +1,29 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type User struct {
 	gorm.Model
-	UserID    string `gorm:"unique;not null" json:"userId"`
-	FirstName string `gorm:"not null" json:"firstName"`
-	LastName  string `gorm:"not null" json:"lastName"`
-	Email     string `gorm:"unique;not null" json:"email"`
-	Password  string `gorm:"not null" json:"password"`
-	Phone     string `json:"phone"`
+	UserID     string     `gorm:"unique;not null" json:"userId"`
+	FirstName  string     `gorm:"not null" json:"firstName"`
+	LastName   string     `gorm:"not null" json:"lastName"`
+	Email      string     `gorm:"unique;not null" json:"email"`
+	Password   string     `gorm:"not null" json:"-"`
+	Phone      string     `json:"phone"`
+	DisabledAt *time.Time `json:"disabledAt"`
+	// IsAdmin marks a platform operator allowed to use the admin API.
+	IsAdmin bool `gorm:"not null;default:false" json:"isAdmin"`
+	// SessionsRevokedAt invalidates every token issued before it.
+	SessionsRevokedAt *time.Time `json:"sessionsRevokedAt"`
+	// PasswordResetRequired is set when an admin resets the password. Until
+	// the user changes it, Login only issues tokens for changing it.
+	PasswordResetRequired bool `gorm:"not null;default:false" json:"passwordResetRequired"`
+}
+
+func (u *User) Disabled() bool {
+	return u.DisabledAt != nil
 }
@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/joshua468/user-authentication/models"
+)
+
+type gormAuditEventRepository struct {
+	db *gorm.DB
+}
+
+func NewGormAuditEventRepository(db *gorm.DB) AuditEventRepository {
+	return &gormAuditEventRepository{db}
+}
+
+func (r *gormAuditEventRepository) Create(ctx context.Context, event *models.AuditEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+func (r *gormAuditEventRepository) Page(ctx context.Context, filter AuditFilter, q PageQuery) (Page[models.AuditEvent], error) {
+	tx := r.db.WithContext(ctx).Model(&models.AuditEvent{})
+	if filter.ActorID != "" {
+		tx = tx.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.TargetID != "" {
+		tx = tx.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.Action != "" {
+		tx = tx.Where("action = ?", filter.Action)
+	}
+	return paginate(tx, "audit_events", q, func(event models.AuditEvent) Cursor {
+		return Cursor{CreatedAt: event.CreatedAt, ID: event.ID}
+	})
+}
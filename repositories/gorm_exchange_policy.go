@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/joshua468/user-authentication/models"
+)
+
+type gormExchangePolicyRepository struct {
+	db *gorm.DB
+}
+
+func NewGormExchangePolicyRepository(db *gorm.DB) ExchangePolicyRepository {
+	return &gormExchangePolicyRepository{db}
+}
+
+func (r *gormExchangePolicyRepository) Save(ctx context.Context, policy *models.ExchangePolicy) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "client_id"}, {Name: "audience"}},
+		DoUpdates: clause.AssignmentColumns([]string{"scopes", "max_ttl_seconds", "updated_at"}),
+	}).Create(policy).Error
+}
+
+func (r *gormExchangePolicyRepository) Find(ctx context.Context, clientID, audience string) (*models.ExchangePolicy, error) {
+	var policy models.ExchangePolicy
+	if err := r.db.WithContext(ctx).Where("client_id = ? AND audience = ?", clientID, audience).First(&policy).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &policy, nil
+}
+
+func (r *gormExchangePolicyRepository) Delete(ctx context.Context, clientID, audience string) error {
+	result := r.db.WithContext(ctx).Where("client_id = ? AND audience = ?", clientID, audience).Delete(&models.ExchangePolicy{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormExchangePolicyRepository) ListForClient(ctx context.Context, clientID string) ([]models.ExchangePolicy, error) {
+	var policies []models.ExchangePolicy
+	if err := r.db.WithContext(ctx).Where("client_id = ?", clientID).Order("audience").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
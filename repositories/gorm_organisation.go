@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/joshua468/user-authentication/models"
+)
+
+type gormOrganisationRepository struct {
+	db *gorm.DB
+}
+
+func NewGormOrganisationRepository(db *gorm.DB) OrganisationRepository {
+	return &gormOrganisationRepository{db}
+}
+
+func (r *gormOrganisationRepository) Create(ctx context.Context, org *models.Organisation, owner *models.User) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.Membership{OrganisationID: org.ID, UserID: owner.ID, Role: models.RoleOwner}).Error
+	})
+}
+
+func (r *gormOrganisationRepository) FindByOrgID(ctx context.Context, orgID string) (*models.Organisation, error) {
+	var org models.Organisation
+	if err := r.db.WithContext(ctx).Where("org_id = ?", orgID).First(&org).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &org, nil
+}
+
+func (r *gormOrganisationRepository) List(ctx context.Context) ([]models.Organisation, error) {
+	var orgs []models.Organisation
+	if err := r.db.WithContext(ctx).Order("id").Find(&orgs).Error; err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
+
+func (r *gormOrganisationRepository) ListForUser(ctx context.Context, userID string) ([]models.Organisation, error) {
+	var orgs []models.Organisation
+	if err := r.forUser(ctx, userID).Find(&orgs).Error; err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
+
+func (r *gormOrganisationRepository) PageForUser(ctx context.Context, userID string, q PageQuery) (Page[models.Organisation], error) {
+	return r.page(r.forUser(ctx, userID), q)
+}
+
+func (r *gormOrganisationRepository) Page(ctx context.Context, q PageQuery) (Page[models.Organisation], error) {
+	return r.page(r.db.WithContext(ctx), q)
+}
+
+func (r *gormOrganisationRepository) page(tx *gorm.DB, q PageQuery) (Page[models.Organisation], error) {
+	tx = tx.Model(&models.Organisation{})
+	if q.Search != "" {
+		tx = tx.Where("LOWER(organisations.name)"+likeEscaped, likePattern(q.Search))
+	}
+	return paginate(tx, "organisations", q, func(org models.Organisation) Cursor {
+		return Cursor{CreatedAt: org.CreatedAt, Name: org.Name, ID: org.ID}
+	})
+}
+
+func (r *gormOrganisationRepository) forUser(ctx context.Context, userID string) *gorm.DB {
+	return r.db.WithContext(ctx).Joins("JOIN organisation_users on organisation_users.organisation_id = organisations.id").
+		Joins("JOIN users on users.id = organisation_users.user_id").
+		Where("users.user_id = ?", userID)
+}
+
+func (r *gormOrganisationRepository) AddMember(ctx context.Context, org *models.Organisation, user *models.User, role string) error {
+	membership := models.Membership{OrganisationID: org.ID, UserID: user.ID, Role: role}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&membership).Error
+}
+
+func (r *gormOrganisationRepository) MemberRole(ctx context.Context, org *models.Organisation, userID string) (string, error) {
+	var membership models.Membership
+	err := r.db.WithContext(ctx).Joins("JOIN users on users.id = organisation_users.user_id").
+		Where("organisation_users.organisation_id = ? AND users.user_id = ?", org.ID, userID).
+		Take(&membership).Error
+	if err != nil {
+		return "", translate(err)
+	}
+	return membership.Role, nil
+}
+
+func (r *gormOrganisationRepository) PageMembers(ctx context.Context, org *models.Organisation, role string, q PageQuery) (Page[models.Member], error) {
+	tx := r.db.WithContext(ctx).Model(&models.User{}).
+		Select("users.*", "organisation_users.role").
+		Joins("JOIN organisation_users on organisation_users.user_id = users.id").
+		Where("organisation_users.organisation_id = ?", org.ID)
+	if role != "" {
+		tx = tx.Where("organisation_users.role = ?", role)
+	}
+	if q.Search != "" {
+		tx = searchUsers(tx, q.Search)
+	}
+	return paginate(tx, "users", q, func(member models.Member) Cursor {
+		return Cursor{CreatedAt: member.CreatedAt, ID: member.ID}
+	})
+}
+
+func (r *gormOrganisationRepository) SetMemberRole(ctx context.Context, org *models.Organisation, userID, role string) error {
+	return r.db.WithContext(ctx).Model(&models.Membership{}).
+		Where("organisation_id = ? AND user_id = (?)", org.ID, r.db.Model(&models.User{}).Select("id").Where("user_id = ?", userID)).
+		Update("role", role).Error
+}
+
+func (r *gormOrganisationRepository) CountMembersWithRole(ctx context.Context, org *models.Organisation, role string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Membership{}).
+		Where("organisation_id = ? AND role = ?", org.ID, role).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,243 @@
+package repositories
+
+import (
+	"context"
+	"slices"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/joshua468/user-authentication/models"
+)
+
+type gormRelationshipRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRelationshipRepository(db *gorm.DB) RelationshipRepository {
+	return &gormRelationshipRepository{db}
+}
+
+func (r *gormRelationshipRepository) Write(ctx context.Context, touch, remove []models.Relationship) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(touch) > 0 {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&touch).Error; err != nil {
+				return err
+			}
+		}
+		for _, rel := range remove {
+			err := tx.Where("namespace = ? AND object_id = ? AND relation = ? AND subject_namespace = ? AND subject_id = ? AND subject_relation = ?",
+				rel.Namespace, rel.ObjectID, rel.Relation, rel.SubjectNamespace, rel.SubjectID, rel.SubjectRelation).
+				Delete(&models.Relationship{}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *gormRelationshipRepository) Find(ctx context.Context, filter RelationshipFilter) ([]models.Relationship, error) {
+	tx := r.db.WithContext(ctx).Order("id")
+	for _, field := range []struct{ column, value string }{
+		{"namespace", filter.Namespace},
+		{"object_id", filter.ObjectID},
+		{"relation", filter.Relation},
+		{"subject_namespace", filter.SubjectNamespace},
+		{"subject_id", filter.SubjectID},
+	} {
+		if field.value != "" {
+			tx = tx.Where(field.column+" = ?", field.value)
+		}
+	}
+	if filter.SubjectRelation != nil {
+		tx = tx.Where("subject_relation = ?", *filter.SubjectRelation)
+	}
+	var rels []models.Relationship
+	if err := limit(tx, filter).Find(&rels).Error; err != nil {
+		return nil, err
+	}
+
+	memberships, err := r.memberships(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	teams, err := r.teams(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	all := slices.Concat(memberships, teams, rels)
+	if filter.Limit > 0 && len(all) > filter.Limit {
+		all = all[:filter.Limit]
+	}
+	return all, nil
+}
+
+// limit caps the rows tx reads at filter.Limit, if positive. Each query
+// Find makes is capped, so that no source is read beyond what the combined
+// result can hold.
+func limit(tx *gorm.DB, filter RelationshipFilter) *gorm.DB {
+	if filter.Limit > 0 {
+		return tx.Limit(filter.Limit)
+	}
+	return tx
+}
+
+// memberships returns the relationships derived from organisation_users
+// that match filter.
+func (r *gormRelationshipRepository) memberships(ctx context.Context, filter RelationshipFilter) ([]models.Relationship, error) {
+	if filter.Namespace != "" && filter.Namespace != models.NamespaceOrganisation ||
+		filter.Relation != "" && !slices.Contains(models.MembershipRelations, filter.Relation) ||
+		filter.SubjectNamespace != "" && filter.SubjectNamespace != models.NamespaceUser ||
+		filter.SubjectRelation != nil && *filter.SubjectRelation != "" {
+		return nil, nil
+	}
+
+	tx := r.db.WithContext(ctx).Model(&models.Membership{}).
+		Select("organisations.org_id, users.user_id, organisation_users.role").
+		Joins("JOIN organisations on organisations.id = organisation_users.organisation_id").
+		Joins("JOIN users on users.id = organisation_users.user_id").
+		Order("organisations.id, users.id")
+	if filter.ObjectID != "" {
+		tx = tx.Where("organisations.org_id = ?", filter.ObjectID)
+	}
+	if filter.SubjectID != "" {
+		tx = tx.Where("users.user_id = ?", filter.SubjectID)
+	}
+	if filter.Relation != "" && filter.Relation != models.RoleMember {
+		tx = tx.Where("organisation_users.role = ?", filter.Relation)
+	}
+	var rows []struct {
+		OrgID  string
+		UserID string
+		Role   string
+	}
+	if err := limit(tx, filter).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	var rels []models.Relationship
+	add := func(orgID, relation, userID string) {
+		rels = append(rels, models.Relationship{
+			Namespace:        models.NamespaceOrganisation,
+			ObjectID:         orgID,
+			Relation:         relation,
+			SubjectNamespace: models.NamespaceUser,
+			SubjectID:        userID,
+		})
+	}
+	for _, row := range rows {
+		if filter.Relation == "" || filter.Relation == models.RoleMember {
+			add(row.OrgID, models.RoleMember, row.UserID)
+		}
+		if row.Role != models.RoleMember && slices.Contains(models.MembershipRelations, row.Role) &&
+			(filter.Relation == "" || filter.Relation == row.Role) {
+			add(row.OrgID, row.Role, row.UserID)
+		}
+	}
+	return rels, nil
+}
+
+// teams returns the relationships derived from teams and team_users that
+// match filter.
+func (r *gormRelationshipRepository) teams(ctx context.Context, filter RelationshipFilter) ([]models.Relationship, error) {
+	if filter.Namespace != "" && filter.Namespace != models.NamespaceTeam {
+		return nil, nil
+	}
+
+	var rels []models.Relationship
+	// derive adds the relationships of relation read by tx, with the object
+	// and subject IDs in the object and subject columns, unless filter
+	// excludes them all.
+	derive := func(relation, subjectNamespace, subjectRelation string, tx *gorm.DB, object, subject string) error {
+		if filter.Relation != "" && filter.Relation != relation ||
+			filter.SubjectNamespace != "" && filter.SubjectNamespace != subjectNamespace ||
+			filter.SubjectRelation != nil && *filter.SubjectRelation != subjectRelation {
+			return nil
+		}
+		if filter.ObjectID != "" {
+			tx = tx.Where(object+" = ?", filter.ObjectID)
+		}
+		if filter.SubjectID != "" {
+			tx = tx.Where(subject+" = ?", filter.SubjectID)
+		}
+		var rows []struct {
+			ObjectID  string
+			SubjectID string
+		}
+		if err := limit(tx, filter).Select(object + " AS object_id, " + subject + " AS subject_id").Scan(&rows).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			rels = append(rels, models.Relationship{
+				Namespace:        models.NamespaceTeam,
+				ObjectID:         row.ObjectID,
+				Relation:         relation,
+				SubjectNamespace: subjectNamespace,
+				SubjectID:        row.SubjectID,
+				SubjectRelation:  subjectRelation,
+			})
+		}
+		return nil
+	}
+
+	teams := func() *gorm.DB {
+		return r.db.WithContext(ctx).Table("teams").Order("teams.id")
+	}
+	nested := func() *gorm.DB {
+		return teams().Joins("JOIN teams parents on parents.id = teams.parent_id")
+	}
+	members := func() *gorm.DB {
+		return teams().Joins("JOIN team_users on team_users.team_id = teams.id").
+			Joins("JOIN users on users.id = team_users.user_id").
+			Order("users.id")
+	}
+	err := derive(models.TeamRelationOrganisation, models.NamespaceOrganisation, "",
+		teams().Joins("JOIN organisations on organisations.id = teams.organisation_id"), "teams.team_id", "organisations.org_id")
+	if err == nil {
+		err = derive(models.TeamRelationParent, models.NamespaceTeam, "", nested(), "teams.team_id", "parents.team_id")
+	}
+	if err == nil {
+		err = derive(models.TeamRelationMaintainer, models.NamespaceUser, "",
+			members().Where("team_users.maintainer = ?", true), "teams.team_id", "users.user_id")
+	}
+	if err == nil {
+		err = derive(models.TeamRelationMember, models.NamespaceUser, "", members(), "teams.team_id", "users.user_id")
+	}
+	if err == nil {
+		// The members of a nested team are members of its parent.
+		err = derive(models.TeamRelationMember, models.NamespaceTeam, models.TeamRelationMember, nested(), "parents.team_id", "teams.team_id")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rels, nil
+}
+
+func (r *gormRelationshipRepository) Relations(ctx context.Context) ([]NamespaceRelation, error) {
+	var relations []NamespaceRelation
+	err := r.db.WithContext(ctx).Model(&models.Relationship{}).
+		Distinct("namespace", "relation").
+		Scan(&relations).Error
+	return relations, err
+}
+
+type gormAuthzSchemaRepository struct {
+	db *gorm.DB
+}
+
+func NewGormAuthzSchemaRepository(db *gorm.DB) AuthzSchemaRepository {
+	return &gormAuthzSchemaRepository{db}
+}
+
+func (r *gormAuthzSchemaRepository) Save(ctx context.Context, schema *models.AuthzSchema) error {
+	return r.db.WithContext(ctx).Create(schema).Error
+}
+
+func (r *gormAuthzSchemaRepository) Latest(ctx context.Context) (*models.AuthzSchema, error) {
+	var schema models.AuthzSchema
+	if err := r.db.WithContext(ctx).Order("id desc").First(&schema).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &schema, nil
+}
@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/joshua468/user-authentication/models"
+)
+
+type gormRevokedTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRevokedTokenRepository(db *gorm.DB) RevokedTokenRepository {
+	return &gormRevokedTokenRepository{db}
+}
+
+func (r *gormRevokedTokenRepository) Revoke(ctx context.Context, token *models.RevokedToken) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{}).Error; err != nil {
+			return err
+		}
+		// Revoking the same token twice is not an error.
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error
+	})
+}
+
+func (r *gormRevokedTokenRepository) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&models.RevokedToken{}).Where("token_id = ?", tokenID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
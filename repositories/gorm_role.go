@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/joshua468/user-authentication/models"
+)
+
+type gormRoleRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRoleRepository(db *gorm.DB) RoleRepository {
+	return &gormRoleRepository{db}
+}
+
+func (r *gormRoleRepository) Create(ctx context.Context, role *models.Role) error {
+	return r.db.WithContext(ctx).Create(role).Error
+}
+
+func (r *gormRoleRepository) FindByRoleID(ctx context.Context, org *models.Organisation, roleID string) (*models.Role, error) {
+	return r.find(ctx, "organisation_id = ? AND role_id = ?", org.ID, roleID)
+}
+
+func (r *gormRoleRepository) FindByName(ctx context.Context, org *models.Organisation, name string) (*models.Role, error) {
+	return r.find(ctx, "organisation_id = ? AND name = ?", org.ID, name)
+}
+
+func (r *gormRoleRepository) find(ctx context.Context, query string, args ...interface{}) (*models.Role, error) {
+	var role models.Role
+	if err := r.db.WithContext(ctx).Where(query, args...).First(&role).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &role, nil
+}
+
+func (r *gormRoleRepository) ListForOrganisation(ctx context.Context, org *models.Organisation) ([]models.Role, error) {
+	var roles []models.Role
+	if err := r.db.WithContext(ctx).Where("organisation_id = ?", org.ID).Order("name").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (r *gormRoleRepository) Update(ctx context.Context, role *models.Role, previousName string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(role).Error; err != nil {
+			return err
+		}
+		if role.Name == previousName {
+			return nil
+		}
+		err := tx.Model(&models.Membership{}).
+			Where("organisation_id = ? AND role = ?", role.OrganisationID, previousName).
+			Update("role", role.Name).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.Team{}).
+			Where("organisation_id = ? AND role = ?", role.OrganisationID, previousName).
+			Update("role", role.Name).Error
+	})
+}
+
+func (r *gormRoleRepository) Delete(ctx context.Context, role *models.Role) error {
+	return r.db.WithContext(ctx).Delete(role).Error
+}
@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/joshua468/user-authentication/models"
+)
+
+type gormServiceClientRepository struct {
+	db *gorm.DB
+}
+
+func NewGormServiceClientRepository(db *gorm.DB) ServiceClientRepository {
+	return &gormServiceClientRepository{db}
+}
+
+func (r *gormServiceClientRepository) Create(ctx context.Context, client *models.ServiceClient) error {
+	return r.db.WithContext(ctx).Create(client).Error
+}
+
+func (r *gormServiceClientRepository) FindByClientID(ctx context.Context, clientID string) (*models.ServiceClient, error) {
+	var client models.ServiceClient
+	if err := r.db.WithContext(ctx).Where("client_id = ?", clientID).First(&client).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &client, nil
+}
+
+func (r *gormServiceClientRepository) List(ctx context.Context) ([]models.ServiceClient, error) {
+	var clients []models.ServiceClient
+	if err := r.db.WithContext(ctx).Order("id").Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+func (r *gormServiceClientRepository) Update(ctx context.Context, client *models.ServiceClient) error {
+	return r.db.WithContext(ctx).Save(client).Error
+}
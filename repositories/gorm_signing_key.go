@@ -0,0 +1,35 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/joshua468/user-authentication/models"
+)
+
+type gormSigningKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewGormSigningKeyRepository(db *gorm.DB) SigningKeyRepository {
+	return &gormSigningKeyRepository{db}
+}
+
+func (r *gormSigningKeyRepository) Rotate(ctx context.Context, key *models.SigningKey, retiresAt time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.SigningKey{}).Where("retires_at IS NULL").Update("retires_at", retiresAt).Error; err != nil {
+			return err
+		}
+		return tx.Create(key).Error
+	})
+}
+
+func (r *gormSigningKeyRepository) ListActive(ctx context.Context, now time.Time) ([]models.SigningKey, error) {
+	var keys []models.SigningKey
+	if err := r.db.WithContext(ctx).Where("retires_at IS NULL OR retires_at > ?", now).Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
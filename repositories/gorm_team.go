@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/joshua468/user-authentication/models"
+)
+
+type gormTeamRepository struct {
+	db *gorm.DB
+}
+
+func NewGormTeamRepository(db *gorm.DB) TeamRepository {
+	return &gormTeamRepository{db}
+}
+
+func (r *gormTeamRepository) Create(ctx context.Context, team *models.Team) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(team).Error
+}
+
+func (r *gormTeamRepository) FindByTeamID(ctx context.Context, org *models.Organisation, teamID string) (*models.Team, error) {
+	return r.find(ctx, "organisation_id = ? AND team_id = ?", org.ID, teamID)
+}
+
+func (r *gormTeamRepository) FindByName(ctx context.Context, org *models.Organisation, name string) (*models.Team, error) {
+	return r.find(ctx, "organisation_id = ? AND name = ?", org.ID, name)
+}
+
+func (r *gormTeamRepository) find(ctx context.Context, query string, args ...interface{}) (*models.Team, error) {
+	var team models.Team
+	if err := r.db.WithContext(ctx).Preload("Parent").Where(query, args...).First(&team).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &team, nil
+}
+
+func (r *gormTeamRepository) ListForOrganisation(ctx context.Context, org *models.Organisation) ([]models.Team, error) {
+	var teams []models.Team
+	err := r.db.WithContext(ctx).Preload("Parent").
+		Where("organisation_id = ?", org.ID).
+		Order("name").
+		Find(&teams).Error
+	if err != nil {
+		return nil, err
+	}
+	return teams, nil
+}
+
+func (r *gormTeamRepository) ListForUser(ctx context.Context, org *models.Organisation, userID string) ([]models.Team, error) {
+	var teams []models.Team
+	err := r.db.WithContext(ctx).Preload("Parent").
+		Joins("JOIN team_users on team_users.team_id = teams.id").
+		Joins("JOIN users on users.id = team_users.user_id").
+		Where("teams.organisation_id = ? AND users.user_id = ?", org.ID, userID).
+		Order("teams.name").
+		Find(&teams).Error
+	if err != nil {
+		return nil, err
+	}
+	return teams, nil
+}
+
+func (r *gormTeamRepository) Update(ctx context.Context, team *models.Team) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(team).Error
+}
+
+func (r *gormTeamRepository) Delete(ctx context.Context, team *models.Team) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("team_id = ?", team.ID).Delete(&models.TeamMembership{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(team).Error
+	})
+}
+
+func (r *gormTeamRepository) CountChildren(ctx context.Context, team *models.Team) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Team{}).Where("parent_id = ?", team.ID).Count(&count).Error
+	return count, err
+}
+
+func (r *gormTeamRepository) CountWithRole(ctx context.Context, org *models.Organisation, role string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Team{}).
+		Where("organisation_id = ? AND role = ?", org.ID, role).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *gormTeamRepository) SetMember(ctx context.Context, team *models.Team, user *models.User, maintainer bool) error {
+	membership := models.TeamMembership{TeamID: team.ID, UserID: user.ID, Maintainer: maintainer}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "team_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"maintainer"}),
+	}).Create(&membership).Error
+}
+
+func (r *gormTeamRepository) Membership(ctx context.Context, team *models.Team, userID string) (*models.TeamMembership, error) {
+	var membership models.TeamMembership
+	err := r.db.WithContext(ctx).Joins("JOIN users on users.id = team_users.user_id").
+		Where("team_users.team_id = ? AND users.user_id = ?", team.ID, userID).
+		Take(&membership).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &membership, nil
+}
+
+func (r *gormTeamRepository) RemoveMember(ctx context.Context, team *models.Team, userID string) error {
+	return r.db.WithContext(ctx).
+		Where("team_id = ? AND user_id = (?)", team.ID, r.db.Model(&models.User{}).Select("id").Where("user_id = ?", userID)).
+		Delete(&models.TeamMembership{}).Error
+}
+
+func (r *gormTeamRepository) ListMembers(ctx context.Context, team *models.Team) ([]models.TeamMember, error) {
+	var members []models.TeamMember
+	err := r.db.WithContext(ctx).Model(&models.User{}).
+		Select("users.*", "team_users.maintainer").
+		Joins("JOIN team_users on team_users.user_id = users.id").
+		Where("team_users.team_id = ?", team.ID).
+		Order("users.id").
+		Scan(&members).Error
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
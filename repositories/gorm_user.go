@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/joshua468/user-authentication/models"
+)
+
+type gormUserRepository struct {
+	db *gorm.DB
+}
+
+func NewGormUserRepository(db *gorm.DB) UserRepository {
+	return &gormUserRepository{db}
+}
+
+func (r *gormUserRepository) Create(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
+}
+
+func (r *gormUserRepository) FindByUserID(ctx context.Context, userID string) (*models.User, error) {
+	return r.first(ctx, "user_id = ?", userID)
+}
+
+func (r *gormUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.first(ctx, "email = ?", email)
+}
+
+func (r *gormUserRepository) first(ctx context.Context, query string, args ...interface{}) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).Where(query, args...).First(&user).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepository) List(ctx context.Context) ([]models.User, error) {
+	var users []models.User
+	if err := r.db.WithContext(ctx).Order("id").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *gormUserRepository) Page(ctx context.Context, q PageQuery) (Page[models.User], error) {
+	tx := r.db.WithContext(ctx).Model(&models.User{})
+	if q.Search != "" {
+		tx = searchUsers(tx, q.Search)
+	}
+	return paginate(tx, "users", q, func(user models.User) Cursor {
+		return Cursor{CreatedAt: user.CreatedAt, ID: user.ID}
+	})
+}
+
+// searchUsers matches users whose first name, last name or email contains
+// search.
+func searchUsers(tx *gorm.DB, search string) *gorm.DB {
+	pattern := likePattern(search)
+	return tx.Where("(LOWER(users.first_name)"+likeEscaped+" OR LOWER(users.last_name)"+likeEscaped+" OR LOWER(users.email)"+likeEscaped+")",
+		pattern, pattern, pattern)
+}
+
+func (r *gormUserRepository) Update(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Save(user).Error
+}
+
+func translate(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The keys listings sort by. Every listing sorts by SortCreatedAt; those
+// whose rows have a name column document whether they also sort by
+// SortName.
+const (
+	SortCreatedAt = "createdAt"
+	SortName      = "name"
+)
+
+// Cursor is the position after the last row of a page. Rows are ordered by
+// the sort key and then id, so a cursor stays valid when rows are inserted.
+// Sort is the PageQuery.Order the cursor was made for, since the position
+// means nothing in another order.
+type Cursor struct {
+	Sort      string    `json:"s"`
+	CreatedAt time.Time `json:"c"`
+	Name      string    `json:"n,omitempty"`
+	ID        uint      `json:"i"`
+}
+
+// PageQuery selects one page of a listing. Search matches case-insensitively
+// on the columns each listing documents; an empty Search matches everything.
+// Sort is one of the Sort constants; empty means SortCreatedAt.
+type PageQuery struct {
+	Limit      int
+	After      *Cursor
+	Search     string
+	Sort       string
+	Descending bool
+}
+
+// Order returns the sort key, prefixed with "-" when descending, as the
+// sort query parameter spells it.
+func (q PageQuery) Order() string {
+	sort := q.Sort
+	if sort == "" {
+		sort = SortCreatedAt
+	}
+	if q.Descending {
+		return "-" + sort
+	}
+	return sort
+}
+
+// Page is one page of results. Total counts every row matching the query's
+// filters, not just this page; Next is nil on the last page.
+type Page[T any] struct {
+	Items []T
+	Total int64
+	Next  *Cursor
+}
+
+// paginate counts the rows matched by tx, then loads the page selected by q
+// into a Page. table qualifies the ordering columns; cursor returns the
+// position of a loaded row. SortName orders by table.name.
+func paginate[T any](tx *gorm.DB, table string, q PageQuery, cursor func(T) Cursor) (Page[T], error) {
+	var page Page[T]
+	if err := tx.Session(&gorm.Session{}).Count(&page.Total).Error; err != nil {
+		return page, err
+	}
+
+	key, id := table+".created_at", table+".id"
+	if q.Sort == SortName {
+		key = table + ".name"
+	}
+	order, cmp := "ASC", ">"
+	if q.Descending {
+		order, cmp = "DESC", "<"
+	}
+	if q.After != nil {
+		var after any = q.After.CreatedAt
+		if q.Sort == SortName {
+			after = q.After.Name
+		}
+		tx = tx.Where(
+			"("+key+" "+cmp+" ? OR ("+key+" = ? AND "+id+" "+cmp+" ?))",
+			after, after, q.After.ID,
+		)
+	}
+	// One extra row tells us whether there is a next page.
+	if err := tx.Order(key + " " + order).Order(id + " " + order).
+		Limit(q.Limit + 1).Find(&page.Items).Error; err != nil {
+		return page, err
+	}
+	if len(page.Items) > q.Limit {
+		page.Items = page.Items[:q.Limit]
+		next := cursor(page.Items[q.Limit-1])
+		next.Sort = q.Order()
+		page.Next = &next
+	}
+	return page, nil
+}
+
+// likeEscaped is a LIKE condition on a lowercased column for use with
+// likePattern. The escape character is "!" because backslash is quoted
+// differently by each SQL dialect.
+const likeEscaped = " LIKE ? ESCAPE '!'"
+
+// likePattern returns a lowercase LIKE pattern matching s anywhere, with
+// LIKE's wildcards escaped.
+func likePattern(s string) string {
+	s = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(strings.ToLower(s))
+	return "%" + s + "%"
+}
@@ -0,0 +1,170 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/joshua468/user-authentication/models"
+)
+
+// ErrNotFound is returned by lookups that match no record.
+var ErrNotFound = errors.New("record not found")
+
+type UserRepository interface {
+	Create(ctx context.Context, user *models.User) error
+	FindByUserID(ctx context.Context, userID string) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	List(ctx context.Context) ([]models.User, error)
+	// Page pages through every user. q.Search matches first name, last name
+	// or email.
+	Page(ctx context.Context, q PageQuery) (Page[models.User], error)
+	Update(ctx context.Context, user *models.User) error
+}
+
+type OrganisationRepository interface {
+	// Create stores org and makes owner its first member atomically.
+	Create(ctx context.Context, org *models.Organisation, owner *models.User) error
+	FindByOrgID(ctx context.Context, orgID string) (*models.Organisation, error)
+	List(ctx context.Context) ([]models.Organisation, error)
+	// Page pages through every organisation. q.Search matches the name;
+	// q.Sort may be SortName.
+	Page(ctx context.Context, q PageQuery) (Page[models.Organisation], error)
+	ListForUser(ctx context.Context, userID string) ([]models.Organisation, error)
+	// PageForUser pages through userID's organisations. q.Search matches the
+	// organisation name; q.Sort may be SortName.
+	PageForUser(ctx context.Context, userID string, q PageQuery) (Page[models.Organisation], error)
+	// AddMember adds user to org with role, or does nothing if user is
+	// already a member.
+	AddMember(ctx context.Context, org *models.Organisation, user *models.User, role string) error
+	// MemberRole returns userID's role in org, or ErrNotFound if they are
+	// not a member.
+	MemberRole(ctx context.Context, org *models.Organisation, userID string) (string, error)
+	// PageMembers pages through org's members. q.Search matches first name,
+	// last name or email; role, if not empty, keeps only members with it.
+	PageMembers(ctx context.Context, org *models.Organisation, role string, q PageQuery) (Page[models.Member], error)
+	// SetMemberRole changes userID's role in org.
+	SetMemberRole(ctx context.Context, org *models.Organisation, userID, role string) error
+	// CountMembersWithRole counts org's members whose role is role.
+	CountMembersWithRole(ctx context.Context, org *models.Organisation, role string) (int64, error)
+}
+
+type RoleRepository interface {
+	Create(ctx context.Context, role *models.Role) error
+	FindByRoleID(ctx context.Context, org *models.Organisation, roleID string) (*models.Role, error)
+	FindByName(ctx context.Context, org *models.Organisation, name string) (*models.Role, error)
+	ListForOrganisation(ctx context.Context, org *models.Organisation) ([]models.Role, error)
+	// Update saves role and, if it was renamed from previousName, moves
+	// the members and teams holding it to the new name, atomically.
+	Update(ctx context.Context, role *models.Role, previousName string) error
+	Delete(ctx context.Context, role *models.Role) error
+}
+
+// TeamRepository loads teams with their Parent.
+type TeamRepository interface {
+	Create(ctx context.Context, team *models.Team) error
+	FindByTeamID(ctx context.Context, org *models.Organisation, teamID string) (*models.Team, error)
+	FindByName(ctx context.Context, org *models.Organisation, name string) (*models.Team, error)
+	ListForOrganisation(ctx context.Context, org *models.Organisation) ([]models.Team, error)
+	// ListForUser returns the teams of org userID belongs to directly.
+	ListForUser(ctx context.Context, org *models.Organisation, userID string) ([]models.Team, error)
+	Update(ctx context.Context, team *models.Team) error
+	// Delete removes team and its memberships atomically.
+	Delete(ctx context.Context, team *models.Team) error
+	// CountChildren counts the teams nested directly in team.
+	CountChildren(ctx context.Context, team *models.Team) (int64, error)
+	// CountWithRole counts org's teams granted role.
+	CountWithRole(ctx context.Context, org *models.Organisation, role string) (int64, error)
+	// SetMember adds user to team, or updates their membership if they
+	// already belong to it.
+	SetMember(ctx context.Context, team *models.Team, user *models.User, maintainer bool) error
+	// Membership returns userID's membership of team, or ErrNotFound if
+	// they do not belong to it.
+	Membership(ctx context.Context, team *models.Team, userID string) (*models.TeamMembership, error)
+	RemoveMember(ctx context.Context, team *models.Team, userID string) error
+	ListMembers(ctx context.Context, team *models.Team) ([]models.TeamMember, error)
+}
+
+type SigningKeyRepository interface {
+	// Rotate stores key and sets retiresAt on every key not yet scheduled to
+	// retire, atomically.
+	Rotate(ctx context.Context, key *models.SigningKey, retiresAt time.Time) error
+	// ListActive returns the keys that have not retired by now.
+	ListActive(ctx context.Context, now time.Time) ([]models.SigningKey, error)
+}
+
+type ServiceClientRepository interface {
+	Create(ctx context.Context, client *models.ServiceClient) error
+	FindByClientID(ctx context.Context, clientID string) (*models.ServiceClient, error)
+	List(ctx context.Context) ([]models.ServiceClient, error)
+	Update(ctx context.Context, client *models.ServiceClient) error
+}
+
+type ExchangePolicyRepository interface {
+	// Save creates policy, or replaces the scopes and lifetime of the
+	// client's existing policy for the same audience.
+	Save(ctx context.Context, policy *models.ExchangePolicy) error
+	Find(ctx context.Context, clientID, audience string) (*models.ExchangePolicy, error)
+	Delete(ctx context.Context, clientID, audience string) error
+	ListForClient(ctx context.Context, clientID string) ([]models.ExchangePolicy, error)
+}
+
+// RelationshipFilter selects relationships; empty fields match everything.
+// SubjectRelation matches everything when nil, since an empty subject
+// relation is itself a value.
+type RelationshipFilter struct {
+	Namespace        string
+	ObjectID         string
+	Relation         string
+	SubjectNamespace string
+	SubjectID        string
+	SubjectRelation  *string
+	// Limit caps the number of relationships returned, stored and derived
+	// together, if positive.
+	Limit int
+}
+
+// NamespaceRelation is a relation of a namespace.
+type NamespaceRelation struct {
+	Namespace string
+	Relation  string
+}
+
+type RelationshipRepository interface {
+	// Write stores each of touch that is not stored yet and removes each of
+	// remove, atomically.
+	Write(ctx context.Context, touch, remove []models.Relationship) error
+	// Find returns the relationships matching filter. Besides the stored
+	// ones, these include the organisation namespace's
+	// models.MembershipRelations, derived from organisation_users, and the
+	// team namespace's models.TeamRelations, derived from teams and
+	// team_users.
+	Find(ctx context.Context, filter RelationshipFilter) ([]models.Relationship, error)
+	// Relations returns the relations that stored relationships use.
+	Relations(ctx context.Context) ([]NamespaceRelation, error)
+}
+
+type AuthzSchemaRepository interface {
+	Save(ctx context.Context, schema *models.AuthzSchema) error
+	// Latest returns the schema saved last, or ErrNotFound if there is none.
+	Latest(ctx context.Context) (*models.AuthzSchema, error)
+}
+
+type RevokedTokenRepository interface {
+	// Revoke records token, and removes rows for tokens that have expired
+	// since they no longer need to be remembered.
+	Revoke(ctx context.Context, token *models.RevokedToken) error
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+}
+
+// AuditFilter narrows an audit log listing; empty fields match everything.
+type AuditFilter struct {
+	ActorID  string
+	TargetID string
+	Action   string
+}
+
+type AuditEventRepository interface {
+	Create(ctx context.Context, event *models.AuditEvent) error
+	Page(ctx context.Context, filter AuditFilter, q PageQuery) (Page[models.AuditEvent], error)
+}
@@ -0,0 +1,127 @@
+// Package routes is the single place the HTTP API is registered, shared by
+// the server and the tests so that both see the same route table.
+package routes
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/joshua468/user-authentication/controllers"
+	"github.com/joshua468/user-authentication/docs"
+	"github.com/joshua468/user-authentication/middlewares"
+	"github.com/joshua468/user-authentication/models"
+)
+
+type Handlers struct {
+	Auth          *controllers.AuthController
+	Users         *controllers.UserController
+	Organisations *controllers.OrganisationController
+	Health        *controllers.HealthController
+	OAuth         *controllers.OAuthController
+	Admin         *controllers.AdminController
+	Teams         *controllers.TeamController
+	Authz         *controllers.AuthzController
+	// Authenticate guards every route that needs a signed-in user, usually
+	// middlewares.JWTAuthMiddlewareWithConfig.
+	Authenticate gin.HandlerFunc
+	// RequireAdmin additionally guards /api/admin, usually
+	// middlewares.RequireAdmin.
+	RequireAdmin gin.HandlerFunc
+	// AuthenticateClient guards /api/authz, which service clients call,
+	// usually middlewares.RequireClient.
+	AuthenticateClient gin.HandlerFunc
+}
+
+// Register adds every route to router. Each route must also be described in
+// docs/openapi.json; tests/openapi_test.go checks that it is.
+func Register(router *gin.Engine, h Handlers) {
+	router.GET("/healthz", h.Health.Live)
+	router.GET("/readyz", h.Health.Ready)
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	router.GET("/openapi.json", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", docs.Spec)
+	})
+	router.GET("/docs", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", docs.Page)
+	})
+
+	router.POST("/oauth/introspect", h.OAuth.Introspect)
+	router.POST("/oauth/token", h.OAuth.Token)
+
+	api := router.Group("/api")
+	{
+		authRoutes := api.Group("/auth")
+		{
+			authRoutes.POST("/register", h.Auth.Register)
+			authRoutes.POST("/login", h.Auth.Login)
+			authRoutes.POST("/logout", h.Authenticate, h.Auth.Logout)
+			authRoutes.POST("/switch-organisation", h.Authenticate, h.Auth.SwitchOrganisation)
+			// The only route open to users whose password was reset, and
+			// closed to admins impersonating them.
+			authRoutes.POST("/change-password", middlewares.AllowPasswordChange(), h.Authenticate, middlewares.RejectImpersonation(), h.Auth.ChangePassword)
+		}
+		api.GET("/permissions", h.Authenticate, h.Organisations.ListPermissions)
+		userRoutes := api.Group("/users").Use(h.Authenticate)
+		{
+			userRoutes.GET("/:id", h.Users.GetUser)
+		}
+		orgRoutes := api.Group("/organisations").Use(h.Authenticate)
+		{
+			orgRoutes.GET("/", h.Organisations.GetOrganisations)
+			orgRoutes.POST("/", h.Organisations.CreateOrganisation)
+		}
+		// Org-scoped tokens only reach their own organisation, and each route
+		// needs a permission there.
+		orgScoped := api.Group("/organisations/:orgId").Use(h.Authenticate, middlewares.RequireTokenOrg("orgId"))
+		{
+			can := h.Organisations.RequirePermission
+			orgScoped.GET("", can(models.PermOrgRead), h.Organisations.GetOrganisation)
+			orgScoped.GET("/users", can(models.PermMembersRead), h.Organisations.GetOrganisationMembers)
+			orgScoped.POST("/users", can(models.PermMembersInvite), h.Organisations.AddUserToOrganisation)
+			// Roles, and with them ownership, are not handed out while
+			// impersonating.
+			noImpersonation := middlewares.RejectImpersonation()
+			orgScoped.PUT("/users/:memberId/role", noImpersonation, can(models.PermMembersUpdate), h.Organisations.AssignRole)
+			orgScoped.GET("/roles", can(models.PermOrgRead), h.Organisations.ListRoles)
+			orgScoped.POST("/roles", can(models.PermRolesManage), h.Organisations.CreateRole)
+			orgScoped.PATCH("/roles/:roleId", can(models.PermRolesManage), h.Organisations.UpdateRole)
+			orgScoped.DELETE("/roles/:roleId", can(models.PermRolesManage), h.Organisations.DeleteRole)
+			// Maintainers change their own team's details and members, so the
+			// team service checks those routes further.
+			orgScoped.GET("/teams", can(models.PermOrgRead), h.Teams.ListTeams)
+			orgScoped.POST("/teams", can(models.PermTeamsManage), h.Teams.CreateTeam)
+			orgScoped.GET("/teams/:teamId", can(models.PermOrgRead), h.Teams.GetTeam)
+			orgScoped.PATCH("/teams/:teamId", can(models.PermOrgRead), h.Teams.UpdateTeam)
+			orgScoped.DELETE("/teams/:teamId", can(models.PermTeamsManage), h.Teams.DeleteTeam)
+			orgScoped.GET("/teams/:teamId/members", can(models.PermMembersRead), h.Teams.ListMembers)
+			orgScoped.PUT("/teams/:teamId/members/:memberId", can(models.PermOrgRead), h.Teams.SetMember)
+			orgScoped.DELETE("/teams/:teamId/members/:memberId", can(models.PermOrgRead), h.Teams.RemoveMember)
+			orgScoped.PUT("/teams/:teamId/role", noImpersonation, can(models.PermMembersUpdate), h.Teams.AssignRole)
+			orgScoped.DELETE("/teams/:teamId/role", noImpersonation, can(models.PermMembersUpdate), h.Teams.RemoveRole)
+		}
+		// Every client reads and checks; writes need the authz.write scope.
+		authzRoutes := api.Group("/authz").Use(h.AuthenticateClient)
+		{
+			write := middlewares.RequireClientScope(models.ClientScopeAuthzWrite)
+			authzRoutes.POST("/check", h.Authz.Check)
+			authzRoutes.POST("/list-objects", h.Authz.ListObjects)
+			authzRoutes.GET("/relationships", h.Authz.ReadRelationships)
+			authzRoutes.POST("/relationships", write, h.Authz.WriteRelationships)
+			authzRoutes.GET("/schema", h.Authz.GetSchema)
+			authzRoutes.PUT("/schema", write, h.Authz.WriteSchema)
+		}
+		adminRoutes := api.Group("/admin").Use(h.Authenticate, middlewares.RejectImpersonation(), h.RequireAdmin)
+		{
+			adminRoutes.GET("/users", h.Admin.ListUsers)
+			adminRoutes.POST("/users/:id/disable", h.Admin.DisableUser)
+			adminRoutes.POST("/users/:id/enable", h.Admin.EnableUser)
+			adminRoutes.POST("/users/:id/reset-password", h.Admin.ResetPassword)
+			adminRoutes.POST("/users/:id/revoke-sessions", h.Admin.RevokeSessions)
+			adminRoutes.POST("/users/:id/impersonate", h.Admin.ImpersonateUser)
+			adminRoutes.GET("/organisations", h.Admin.ListOrganisations)
+			adminRoutes.GET("/audit-events", h.Admin.ListAuditEvents)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	"github.com/joshua468/user-authentication/logging"
+	"github.com/joshua468/user-authentication/models"
+	"github.com/joshua468/user-authentication/repositories"
+)
+
+// CLIActor is the actor recorded in the audit log for commands run on the
+// server rather than through the admin API.
+const CLIActor = "cli"
+
+// ImpersonationTTL is the lifetime of impersonation tokens, kept short since
+// they cannot be refreshed.
+const ImpersonationTTL = 15 * time.Minute
+
+// Impersonation is a token letting an admin act as another user.
+type Impersonation struct {
+	Token     string
+	ExpiresAt time.Time
+	User      *models.User
+}
+
+// AdminService carries out platform operators' actions. Every change is
+// recorded in the audit log with the acting user's ID.
+type AdminService struct {
+	users     *UserService
+	userRepo  repositories.UserRepository
+	orgRepo   repositories.OrganisationRepository
+	tokens    *TokenService
+	auditRepo repositories.AuditEventRepository
+}
+
+func NewAdminService(users *UserService, userRepo repositories.UserRepository, orgRepo repositories.OrganisationRepository, tokens *TokenService, auditRepo repositories.AuditEventRepository) *AdminService {
+	return &AdminService{users, userRepo, orgRepo, tokens, auditRepo}
+}
+
+// IsAdmin reports whether userID may use the admin API. It reads the
+// database on every call so that revoking admin rights takes effect at once.
+func (s *AdminService) IsAdmin(ctx context.Context, userID string) (bool, error) {
+	user, err := s.userRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return false, notFound(err, ErrUserNotFound)
+	}
+	return user.IsAdmin && !user.Disabled(), nil
+}
+
+func (s *AdminService) ListUsers(ctx context.Context, q repositories.PageQuery) (repositories.Page[models.User], error) {
+	return s.userRepo.Page(ctx, clampPageSize(q))
+}
+
+func (s *AdminService) ListOrganisations(ctx context.Context, q repositories.PageQuery) (repositories.Page[models.Organisation], error) {
+	return s.orgRepo.Page(ctx, clampPageSize(q))
+}
+
+func (s *AdminService) AuditLog(ctx context.Context, filter repositories.AuditFilter, q repositories.PageQuery) (repositories.Page[models.AuditEvent], error) {
+	return s.auditRepo.Page(ctx, filter, clampPageSize(q))
+}
+
+// SetDisabled disables or re-enables userID's account. A disabled user can
+// neither log in nor use tokens issued earlier.
+func (s *AdminService) SetDisabled(ctx context.Context, actorID, userID string, disabled bool) (*models.User, error) {
+	user, err := s.users.SetDisabled(ctx, userID, disabled)
+	if err != nil {
+		return nil, err
+	}
+	action := models.AuditUserEnabled
+	if disabled {
+		action = models.AuditUserDisabled
+	}
+	return user, s.record(ctx, actorID, action, userID)
+}
+
+// SetAdmin grants or revokes userID's admin rights.
+func (s *AdminService) SetAdmin(ctx context.Context, actorID, userID string, admin bool) (*models.User, error) {
+	user, err := s.users.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	user.IsAdmin = admin
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	action := models.AuditUserAdminRevoked
+	if admin {
+		action = models.AuditUserAdminGranted
+	}
+	return user, s.record(ctx, actorID, action, userID)
+}
+
+// ResetPassword sets userID's password, or a generated one if password is
+// empty, and revokes their sessions, so that whoever knew the old password
+// is signed out everywhere. The user must change the new password before
+// signing in normally. It returns the generated password, if any.
+func (s *AdminService) ResetPassword(ctx context.Context, actorID, userID, password string) (string, error) {
+	generated := ""
+	if password == "" {
+		var err error
+		if generated, err = GeneratePassword(); err != nil {
+			return "", err
+		}
+		password = generated
+	}
+	if err := s.users.ResetPassword(ctx, userID, password); err != nil {
+		return "", err
+	}
+	if err := s.tokens.RevokeSessions(ctx, userID); err != nil {
+		return "", err
+	}
+	return generated, s.record(ctx, actorID, models.AuditUserPasswordReset, userID)
+}
+
+// GeneratePassword returns a random password of 24 URL-safe characters.
+func GeneratePassword() (string, error) {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// RevokeSessions invalidates every token issued to userID so far.
+func (s *AdminService) RevokeSessions(ctx context.Context, actorID, userID string) error {
+	if err := s.tokens.RevokeSessions(ctx, userID); err != nil {
+		return err
+	}
+	return s.record(ctx, actorID, models.AuditUserSessionsRevoked, userID)
+}
+
+// Impersonate issues a short-lived token for userID on behalf of actorID.
+// Admins cannot impersonate themselves, other admins or disabled users.
+func (s *AdminService) Impersonate(ctx context.Context, actorID, userID string) (*Impersonation, error) {
+	user, err := s.users.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.UserID == actorID || user.IsAdmin || user.Disabled() {
+		return nil, ErrNotImpersonable
+	}
+	token, claims, err := s.tokens.Impersonate(userID, actorID, ImpersonationTTL)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(ctx, actorID, models.AuditUserImpersonated, userID); err != nil {
+		return nil, err
+	}
+	return &Impersonation{Token: token, ExpiresAt: claims.ExpiresAt.Time, User: user}, nil
+}
+
+// RecordImpersonatedRequest records that request, a method and path, changed
+// something on behalf of userID in a session impersonatorID started.
+func (s *AdminService) RecordImpersonatedRequest(ctx context.Context, userID, impersonatorID, request string) error {
+	return s.auditRepo.Create(ctx, &models.AuditEvent{
+		ActorID:        userID,
+		Action:         models.AuditImpersonatedRequest,
+		TargetType:     "request",
+		TargetID:       request,
+		RequestID:      logging.RequestID(ctx),
+		ImpersonatorID: impersonatorID,
+	})
+}
+
+func (s *AdminService) record(ctx context.Context, actorID, action, userID string) error {
+	return s.auditRepo.Create(ctx, &models.AuditEvent{
+		ActorID:        actorID,
+		Action:         action,
+		TargetType:     "user",
+		TargetID:       userID,
+		RequestID:      logging.RequestID(ctx),
+		ImpersonatorID: logging.Impersonator(ctx),
+	})
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/joshua468/user-authentication/models"
+	"github.com/joshua468/user-authentication/tracing"
+	"github.com/joshua468/user-authentication/utils"
+)
+
+// PasswordChangeTTL is the lifetime of the tokens Login issues to users who
+// must change their password.
+const PasswordChangeTTL = 15 * time.Minute
+
+type AuthService struct {
+	users  *UserService
+	tokens utils.TokenIssuer
+}
+
+func NewAuthService(users *UserService, tokens utils.TokenIssuer) *AuthService {
+	return &AuthService{users, tokens}
+}
+
+// Register creates a user and issues their first access token.
+func (s *AuthService) Register(ctx context.Context, input CreateUserInput) (*models.User, string, error) {
+	user, err := s.users.Create(ctx, input)
+	if err != nil {
+		return nil, "", err
+	}
+	token, err := s.issueToken(ctx, user.UserID)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrTokenIssuance, err)
+	}
+	return user, token, nil
+}
+
+// Login authenticates a user and issues an access token. A user whose
+// password was reset only gets a token for ChangePassword, with
+// utils.ScopePasswordChange.
+func (s *AuthService) Login(ctx context.Context, email, password string) (*models.User, string, error) {
+	user, err := s.users.Authenticate(ctx, email, password)
+	if err != nil {
+		return nil, "", err
+	}
+	var token string
+	if user.PasswordResetRequired {
+		token, err = s.tokens.IssueClaims(&utils.Claims{
+			Scope:            utils.ScopePasswordChange,
+			RegisteredClaims: jwt.RegisteredClaims{Subject: user.UserID},
+		}, PasswordChangeTTL)
+	} else {
+		token, err = s.issueToken(ctx, user.UserID)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrTokenIssuance, err)
+	}
+	return user, token, nil
+}
+
+// ChangePassword sets userID's password after checking the current one and
+// issues an unrestricted access token.
+func (s *AuthService) ChangePassword(ctx context.Context, userID, current, password string) (*models.User, string, error) {
+	user, err := s.users.ChangePassword(ctx, userID, current, password)
+	if err != nil {
+		return nil, "", err
+	}
+	token, err := s.issueToken(ctx, user.UserID)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrTokenIssuance, err)
+	}
+	return user, token, nil
+}
+
+// Refresh issues a new access token for a user who already holds a valid
+// one, unless their account has been disabled since.
+func (s *AuthService) Refresh(ctx context.Context, userID string) (string, error) {
+	user, err := s.users.Get(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if user.Disabled() {
+		return "", ErrUserDisabled
+	}
+	token, err := s.issueToken(ctx, user.UserID)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrTokenIssuance, err)
+	}
+	return token, nil
+}
+
+func (s *AuthService) issueToken(ctx context.Context, userID string) (string, error) {
+	_, span := tracing.Start(ctx, "jwt.Issue")
+	defer span.End()
+	return s.tokens.IssueToken(userID)
+}
@@ -0,0 +1,349 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/joshua468/user-authentication/authz"
+	"github.com/joshua468/user-authentication/models"
+	"github.com/joshua468/user-authentication/repositories"
+)
+
+var objectID = regexp.MustCompile(`^[A-Za-z0-9_.@|=+/-]{1,128}$`)
+
+// AuthzService is a relationship-based authorization engine in the style of
+// Zanzibar. Relationships between objects are checked against the
+// namespaces of the latest schema, written in the authz schema language.
+// Organisation memberships and teams are relationships of the organisation
+// and team namespaces without being stored, so they never drift from the
+// organisation API.
+type AuthzService struct {
+	relationships repositories.RelationshipRepository
+	schemas       repositories.AuthzSchemaRepository
+}
+
+func NewAuthzService(relationships repositories.RelationshipRepository, schemas repositories.AuthzSchemaRepository) *AuthzService {
+	return &AuthzService{relationships, schemas}
+}
+
+// ObjectRef names an object, or with Relation, the subjects of one of its
+// relations or permissions.
+type ObjectRef struct {
+	Namespace string
+	ID        string
+	Relation  string
+}
+
+// Schema returns the source of the schema in force, authz.Default until one
+// is written.
+func (s *AuthzService) Schema(ctx context.Context) (string, error) {
+	schema, err := s.schemas.Latest(ctx)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return authz.Default, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return schema.Source, nil
+}
+
+func (s *AuthzService) schema(ctx context.Context) (*authz.Schema, error) {
+	source, err := s.Schema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return authz.Parse(source)
+}
+
+// derivedRelation is a relation the relationship repository derives from
+// memberships or teams, with the subject types it gives its relationships.
+type derivedRelation struct {
+	namespace, relation string
+	subjects            []authz.SubjectType
+}
+
+var (
+	userSubject      = authz.SubjectType{Namespace: models.NamespaceUser}
+	derivedRelations = []derivedRelation{
+		{models.NamespaceOrganisation, models.RoleOwner, []authz.SubjectType{userSubject}},
+		{models.NamespaceOrganisation, models.RoleAdmin, []authz.SubjectType{userSubject}},
+		{models.NamespaceOrganisation, models.RoleMember, []authz.SubjectType{userSubject}},
+		{models.NamespaceTeam, models.TeamRelationOrganisation, []authz.SubjectType{{Namespace: models.NamespaceOrganisation}}},
+		{models.NamespaceTeam, models.TeamRelationParent, []authz.SubjectType{{Namespace: models.NamespaceTeam}}},
+		{models.NamespaceTeam, models.TeamRelationMaintainer, []authz.SubjectType{userSubject}},
+		{models.NamespaceTeam, models.TeamRelationMember, []authz.SubjectType{userSubject, {Namespace: models.NamespaceTeam, Relation: models.TeamRelationMember}}},
+	}
+)
+
+// WriteSchema puts source in force. It returns ErrInvalidSchema if source
+// does not parse, drops a relation that stored relationships still use, or
+// drops a relation derived from memberships and teams or a subject type
+// they give it.
+func (s *AuthzService) WriteSchema(ctx context.Context, source string) error {
+	schema, err := authz.Parse(source)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	for _, d := range derivedRelations {
+		var r *authz.Relation
+		if def := schema.Definition(d.namespace); def != nil {
+			r = def.Relation(d.relation)
+		}
+		if r == nil {
+			return fmt.Errorf("%w: %s#%s is derived from memberships and teams and must stay defined", ErrInvalidSchema, d.namespace, d.relation)
+		}
+		for _, subject := range d.subjects {
+			if !r.Allows(subject) {
+				return fmt.Errorf("%w: %s#%s must allow %s, which memberships and teams give it", ErrInvalidSchema, d.namespace, d.relation, subject)
+			}
+		}
+	}
+	used, err := s.relationships.Relations(ctx)
+	if err != nil {
+		return err
+	}
+	for _, r := range used {
+		if def := schema.Definition(r.Namespace); def == nil || def.Relation(r.Relation) == nil {
+			return fmt.Errorf("%w: relationships still use %s#%s", ErrInvalidSchema, r.Namespace, r.Relation)
+		}
+	}
+	return s.schemas.Save(ctx, &models.AuthzSchema{Source: source})
+}
+
+func invalidRelationship(rel models.Relationship, format string, args ...interface{}) error {
+	return fmt.Errorf("%w %s: %s", ErrInvalidRelationship, rel, fmt.Sprintf(format, args...))
+}
+
+func checkRelationship(schema *authz.Schema, rel models.Relationship) error {
+	if models.IsDerived(rel.Namespace, rel.Relation) {
+		return ErrDerivedRelationship
+	}
+	if !objectID.MatchString(rel.ObjectID) || !objectID.MatchString(rel.SubjectID) {
+		return invalidRelationship(rel, "IDs are 1 to 128 letters, digits or any of _.@|=+/-")
+	}
+	def := schema.Definition(rel.Namespace)
+	if def == nil {
+		return invalidRelationship(rel, "unknown namespace %q", rel.Namespace)
+	}
+	r := def.Relation(rel.Relation)
+	if r == nil {
+		return invalidRelationship(rel, "%s has no relation %q", rel.Namespace, rel.Relation)
+	}
+	subject := authz.SubjectType{Namespace: rel.SubjectNamespace, Relation: rel.SubjectRelation}
+	if !r.Allows(subject) {
+		return invalidRelationship(rel, "%s#%s cannot point at %s", rel.Namespace, rel.Relation, subject)
+	}
+	return nil
+}
+
+// WriteRelationships stores touch and removes remove, atomically. Touching
+// a stored relationship or removing a missing one does nothing.
+func (s *AuthzService) WriteRelationships(ctx context.Context, touch, remove []models.Relationship) error {
+	schema, err := s.schema(ctx)
+	if err != nil {
+		return err
+	}
+	for _, rel := range touch {
+		if err := checkRelationship(schema, rel); err != nil {
+			return err
+		}
+	}
+	for _, rel := range remove {
+		if models.IsDerived(rel.Namespace, rel.Relation) {
+			return ErrDerivedRelationship
+		}
+	}
+	return s.relationships.Write(ctx, touch, remove)
+}
+
+// ReadRelationships returns the relationships matching filter, including
+// the memberships and teams.
+func (s *AuthzService) ReadRelationships(ctx context.Context, filter repositories.RelationshipFilter) ([]models.Relationship, error) {
+	return s.relationships.Find(ctx, filter)
+}
+
+// known returns ErrUnknownPermission unless the schema defines namespace
+// and, if not empty, its relation or permission called name.
+func known(schema *authz.Schema, namespace, name string) error {
+	def := schema.Definition(namespace)
+	if def == nil || name != "" && !def.Defines(name) {
+		return ErrUnknownPermission
+	}
+	return nil
+}
+
+// Check reports whether subject has permission, a relation or permission,
+// on object.
+func (s *AuthzService) Check(ctx context.Context, object ObjectRef, permission string, subject ObjectRef) (bool, error) {
+	schema, err := s.schema(ctx)
+	if err != nil {
+		return false, err
+	}
+	if err := known(schema, object.Namespace, permission); err != nil {
+		return false, err
+	}
+	if err := known(schema, subject.Namespace, subject.Relation); err != nil {
+		return false, err
+	}
+	c := checker{s.relationships, schema, subject, map[ObjectRef]bool{}}
+	return c.reaches(ctx, ObjectRef{object.Namespace, object.ID, permission})
+}
+
+// checker walks the relationships from an object's permission towards the
+// subject. Permissions are unions, so the subject has the permission
+// exactly when it can be reached.
+type checker struct {
+	relationships repositories.RelationshipRepository
+	schema        *authz.Schema
+	target        ObjectRef
+	visited       map[ObjectRef]bool
+}
+
+func (c *checker) reaches(ctx context.Context, from ObjectRef) (bool, error) {
+	if from == c.target {
+		return true, nil
+	}
+	if from.Relation == "" || c.visited[from] {
+		return false, nil
+	}
+	c.visited[from] = true
+	def := c.schema.Definition(from.Namespace)
+	if def == nil {
+		return false, nil
+	}
+
+	perm := def.Permission(from.Relation)
+	if perm == nil {
+		return c.reachesAny(ctx, from, "", func(rel models.Relationship) ObjectRef {
+			return ObjectRef{rel.SubjectNamespace, rel.SubjectID, rel.SubjectRelation}
+		})
+	}
+	for _, term := range perm.Terms {
+		var ok bool
+		var err error
+		if term.Arrow == "" {
+			ok, err = c.reaches(ctx, ObjectRef{from.Namespace, from.ID, term.Relation})
+		} else {
+			ok, err = c.reachesAny(ctx, ObjectRef{from.Namespace, from.ID, term.Relation}, term.Arrow, func(rel models.Relationship) ObjectRef {
+				return ObjectRef{rel.SubjectNamespace, rel.SubjectID, term.Arrow}
+			})
+		}
+		if ok || err != nil {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// reachesAny follows the relationships of the relation named by from, or
+// with arrow, only those to objects whose namespace defines arrow.
+func (c *checker) reachesAny(ctx context.Context, from ObjectRef, arrow string, next func(models.Relationship) ObjectRef) (bool, error) {
+	filter := repositories.RelationshipFilter{Namespace: from.Namespace, ObjectID: from.ID, Relation: from.Relation}
+	if arrow != "" {
+		direct := ""
+		filter.SubjectRelation = &direct
+	}
+	rels, err := c.relationships.Find(ctx, filter)
+	if err != nil {
+		return false, err
+	}
+	for _, rel := range rels {
+		if arrow != "" {
+			if def := c.schema.Definition(rel.SubjectNamespace); def == nil || !def.Defines(arrow) {
+				continue
+			}
+		}
+		if ok, err := c.reaches(ctx, next(rel)); ok || err != nil {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// ListObjects returns the IDs of the objects of namespace on which subject
+// has permission, sorted. It walks the relationships backwards from the
+// subject, so its cost grows with what the subject can reach rather than
+// with the size of the namespace.
+func (s *AuthzService) ListObjects(ctx context.Context, namespace, permission string, subject ObjectRef) ([]string, error) {
+	schema, err := s.schema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := known(schema, namespace, permission); err != nil {
+		return nil, err
+	}
+	if err := known(schema, subject.Namespace, subject.Relation); err != nil {
+		return nil, err
+	}
+
+	seen := map[ObjectRef]bool{subject: true}
+	queue := []ObjectRef{subject}
+	push := func(ref ObjectRef) {
+		if !seen[ref] {
+			seen[ref] = true
+			queue = append(queue, ref)
+		}
+	}
+	ids := []string{}
+	for len(queue) > 0 {
+		ref := queue[0]
+		queue = queue[1:]
+		if ref.Namespace == namespace && ref.Relation == permission {
+			ids = append(ids, ref.ID)
+		}
+
+		// Relationships pointing at ref.
+		rels, err := s.relationships.Find(ctx, repositories.RelationshipFilter{
+			SubjectNamespace: ref.Namespace,
+			SubjectID:        ref.ID,
+			SubjectRelation:  &ref.Relation,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, rel := range rels {
+			push(ObjectRef{rel.Namespace, rel.ObjectID, rel.Relation})
+		}
+		if ref.Relation == "" {
+			continue
+		}
+
+		// Permissions including ref's relation, on the same object or, by
+		// an arrow, on the objects pointing at it.
+		if def := schema.Definition(ref.Namespace); def != nil {
+			for _, perm := range def.Permissions {
+				if slices.Contains(perm.Terms, authz.Term{Relation: ref.Relation}) {
+					push(ObjectRef{ref.Namespace, ref.ID, perm.Name})
+				}
+			}
+		}
+		for _, def := range schema.Definitions {
+			for _, perm := range def.Permissions {
+				for _, term := range perm.Terms {
+					if term.Arrow != ref.Relation {
+						continue
+					}
+					direct := ""
+					rels, err := s.relationships.Find(ctx, repositories.RelationshipFilter{
+						Namespace:        def.Name,
+						Relation:         term.Relation,
+						SubjectNamespace: ref.Namespace,
+						SubjectID:        ref.ID,
+						SubjectRelation:  &direct,
+					})
+					if err != nil {
+						return nil, err
+					}
+					for _, rel := range rels {
+						push(ObjectRef{def.Name, rel.ObjectID, perm.Name})
+					}
+				}
+			}
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/joshua468/user-authentication/models"
+	"github.com/joshua468/user-authentication/repositories"
+	"github.com/joshua468/user-authentication/utils"
+)
+
+// ClientService manages the service clients that may introspect and
+// exchange tokens, and their exchange policies.
+type ClientService struct {
+	clients  repositories.ServiceClientRepository
+	policies repositories.ExchangePolicyRepository
+}
+
+func NewClientService(clients repositories.ServiceClientRepository, policies repositories.ExchangePolicyRepository) *ClientService {
+	return &ClientService{clients, policies}
+}
+
+// Create registers a client and returns its secret, which is not stored and
+// cannot be recovered later.
+func (s *ClientService) Create(ctx context.Context, name string) (*models.ServiceClient, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(raw)
+
+	client := models.ServiceClient{
+		ClientID:   utils.GenerateUUID(),
+		Name:       name,
+		SecretHash: hashClientSecret(secret),
+	}
+	if err := s.clients.Create(ctx, &client); err != nil {
+		return nil, "", err
+	}
+	return &client, secret, nil
+}
+
+func (s *ClientService) List(ctx context.Context) ([]models.ServiceClient, error) {
+	return s.clients.List(ctx)
+}
+
+// SetScope grants clientID scope, one of models.ClientScopes, or takes it
+// away.
+func (s *ClientService) SetScope(ctx context.Context, clientID, scope string, granted bool) (*models.ServiceClient, error) {
+	if !slices.Contains(models.ClientScopes, scope) {
+		return nil, ErrUnknownClientScope
+	}
+	client, err := s.clients.FindByClientID(ctx, clientID)
+	if err != nil {
+		return nil, notFound(err, ErrClientNotFound)
+	}
+	if client.HasScope(scope) == granted {
+		return client, nil
+	}
+	scopes := slices.DeleteFunc(strings.Fields(client.Scopes), func(held string) bool { return held == scope })
+	if granted {
+		scopes = append(scopes, scope)
+	}
+	client.Scopes = strings.Join(scopes, " ")
+	if err := s.clients.Update(ctx, client); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// Authenticate checks a client ID and secret pair. Unknown and disabled
+// clients and wrong secrets all return ErrInvalidClient.
+func (s *ClientService) Authenticate(ctx context.Context, clientID, secret string) (*models.ServiceClient, error) {
+	client, err := s.clients.FindByClientID(ctx, clientID)
+	if err != nil {
+		return nil, ErrInvalidClient
+	}
+	hash := hashClientSecret(secret)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(client.SecretHash)) != 1 || client.DisabledAt != nil {
+		return nil, ErrInvalidClient
+	}
+	return client, nil
+}
+
+// SetExchangePolicy lets clientID exchange tokens for audience, with at most
+// scopes and a lifetime of at most maxTTL. It replaces any existing policy
+// for the same audience. Exchanged tokens without a scope would be
+// unrestricted, so scopes must not be empty.
+func (s *ClientService) SetExchangePolicy(ctx context.Context, clientID, audience string, scopes []string, maxTTL time.Duration) (*models.ExchangePolicy, error) {
+	if audience == "" || len(scopes) == 0 || maxTTL < time.Second {
+		return nil, ErrInvalidPolicy
+	}
+	if _, err := s.clients.FindByClientID(ctx, clientID); err != nil {
+		return nil, notFound(err, ErrClientNotFound)
+	}
+	policy := models.ExchangePolicy{
+		ClientID:      clientID,
+		Audience:      audience,
+		Scopes:        strings.Join(scopes, " "),
+		MaxTTLSeconds: int(maxTTL / time.Second),
+	}
+	if err := s.policies.Save(ctx, &policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (s *ClientService) RemoveExchangePolicy(ctx context.Context, clientID, audience string) error {
+	return notFound(s.policies.Delete(ctx, clientID, audience), ErrNoExchangePolicy)
+}
+
+func (s *ClientService) ExchangePolicies(ctx context.Context, clientID string) ([]models.ExchangePolicy, error) {
+	return s.policies.ListForClient(ctx, clientID)
+}
+
+// ExchangePolicy returns ErrNoExchangePolicy unless clientID may exchange
+// tokens for audience.
+func (s *ClientService) ExchangePolicy(ctx context.Context, clientID, audience string) (*models.ExchangePolicy, error) {
+	policy, err := s.policies.Find(ctx, clientID, audience)
+	if err != nil {
+		return nil, notFound(err, ErrNoExchangePolicy)
+	}
+	return policy, nil
+}
+
+// hashClientSecret uses SHA-256 rather than bcrypt: secrets are 256 random
+// bits, so a slow hash adds latency to every introspection call without
+// making them harder to guess.
+func hashClientSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/joshua468/user-authentication/repositories"
+)
+
+var (
+	ErrUserNotFound         = errors.New("user not found")
+	ErrOrganisationNotFound = errors.New("organisation not found")
+	ErrNotMember            = errors.New("user is not a member of the organisation")
+	ErrStaleOrgRole         = errors.New("token's organisation role is out of date")
+	ErrInvalidCredentials   = errors.New("invalid email or password")
+	ErrUserDisabled         = errors.New("user is disabled")
+	ErrPasswordUnchanged    = errors.New("the new password must differ from the current one")
+	ErrTokenIssuance        = errors.New("failed to issue token")
+	ErrInvalidClient        = errors.New("invalid client credentials")
+	ErrTokenNotRevocable    = errors.New("token has no ID and cannot be revoked")
+	ErrNotImpersonable      = errors.New("user cannot be impersonated")
+	ErrClientNotFound       = errors.New("service client not found")
+	ErrUnknownClientScope   = errors.New("unknown service client scope")
+	ErrNoExchangePolicy     = errors.New("client may not exchange tokens for this audience")
+	ErrInvalidPolicy        = errors.New("an exchange policy needs an audience, at least one scope and a lifetime of at least one second")
+	ErrInvalidSubjectToken  = errors.New("subject token is invalid")
+	ErrImpersonatedSubject  = errors.New("impersonation tokens cannot be exchanged")
+	ErrInvalidScope         = errors.New("requested scope is not allowed")
+	ErrRoleNotFound         = errors.New("role not found")
+	ErrRoleExists           = errors.New("the organisation already has a role with this name")
+	ErrInvalidRole          = errors.New("a role needs a name of 2 to 32 lowercase letters, digits or dashes that is not a built-in role, and permissions from the catalogue")
+	ErrRoleInUse            = errors.New("role is assigned to members or teams")
+	ErrLastOwner            = errors.New("an organisation must keep at least one owner")
+	ErrPermissionEscalation = errors.New("cannot grant or change permissions the caller does not hold")
+	ErrTeamNotFound         = errors.New("team not found")
+	ErrTeamExists           = errors.New("the organisation already has a team with this name")
+	ErrInvalidTeam          = errors.New("a team needs a name of 1 to 64 characters without surrounding spaces")
+	ErrTeamDescription      = errors.New("a team's description can be at most 255 characters")
+	ErrTeamCycle            = errors.New("a team cannot be nested in itself or in a team nested in it")
+	ErrTeamHasChildren      = errors.New("team has nested teams")
+	ErrNotTeamMember        = errors.New("user is not a member of the team")
+	ErrNotTeamManager       = errors.New("only the team's maintainers and members holding org.teams.manage can change the team")
+	ErrOwnerTeam            = errors.New("teams cannot be granted the owner role")
+	ErrInvalidSchema        = errors.New("invalid authorization schema")
+	ErrInvalidRelationship  = errors.New("invalid relationship")
+	ErrDerivedRelationship  = errors.New("organisation memberships and teams cannot be written as relationships")
+	ErrUnknownPermission    = errors.New("the schema does not define this namespace or permission")
+)
+
+// notFound replaces repositories.ErrNotFound with the service-level error.
+func notFound(err, notFoundErr error) error {
+	if errors.Is(err, repositories.ErrNotFound) {
+		return notFoundErr
+	}
+	return err
+}
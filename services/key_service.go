@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	"github.com/joshua468/user-authentication/models"
+	"github.com/joshua468/user-authentication/repositories"
+	"github.com/joshua468/user-authentication/utils"
+)
+
+// KeyActivationDelay is how long a rotated key waits before it signs tokens,
+// giving every running instance time to load it for verification first.
+const KeyActivationDelay = 2 * time.Minute
+
+type KeyService struct {
+	keys repositories.SigningKeyRepository
+}
+
+func NewKeyService(keys repositories.SigningKeyRepository) *KeyService {
+	return &KeyService{keys}
+}
+
+// Rotate creates a new signing key and schedules the keys it replaces to
+// retire once every token they signed has expired.
+func (s *KeyService) Rotate(ctx context.Context, opts utils.TokenOptions) (*models.SigningKey, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	notBefore := time.Now().Add(KeyActivationDelay)
+	key := models.SigningKey{
+		KeyID:     utils.GenerateUUID(),
+		Secret:    base64.StdEncoding.EncodeToString(secret),
+		NotBefore: notBefore,
+	}
+	if err := s.keys.Rotate(ctx, &key, notBefore.Add(opts.TTL+opts.Leeway)); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// SigningKeys returns the keys that have not yet retired.
+func (s *KeyService) SigningKeys(ctx context.Context) ([]utils.SigningKey, error) {
+	rows, err := s.keys.ListActive(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]utils.SigningKey, 0, len(rows))
+	for _, row := range rows {
+		secret, err := base64.StdEncoding.DecodeString(row.Secret)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, utils.SigningKey{ID: row.KeyID, Secret: secret, NotBefore: row.NotBefore})
+	}
+	return keys, nil
+}
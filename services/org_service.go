@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/joshua468/user-authentication/models"
+	"github.com/joshua468/user-authentication/repositories"
+	"github.com/joshua468/user-authentication/utils"
+)
+
+type OrgService struct {
+	orgs  repositories.OrganisationRepository
+	users repositories.UserRepository
+}
+
+func NewOrgService(orgs repositories.OrganisationRepository, users repositories.UserRepository) *OrgService {
+	return &OrgService{orgs, users}
+}
+
+type CreateOrgInput struct {
+	Name        string
+	Description string
+}
+
+// Create creates an organisation with ownerID as its first member.
+func (s *OrgService) Create(ctx context.Context, ownerID string, input CreateOrgInput) (*models.Organisation, error) {
+	owner, err := s.users.FindByUserID(ctx, ownerID)
+	if err != nil {
+		return nil, notFound(err, ErrUserNotFound)
+	}
+
+	org := models.Organisation{
+		OrgID:       utils.GenerateUUID(),
+		Name:        input.Name,
+		Description: input.Description,
+	}
+	if err := s.orgs.Create(ctx, &org, owner); err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (s *OrgService) Get(ctx context.Context, orgID string) (*models.Organisation, error) {
+	org, err := s.orgs.FindByOrgID(ctx, orgID)
+	if err != nil {
+		return nil, notFound(err, ErrOrganisationNotFound)
+	}
+	return org, nil
+}
+
+func (s *OrgService) List(ctx context.Context) ([]models.Organisation, error) {
+	return s.orgs.List(ctx)
+}
+
+// ListForUser returns the organisations userID is a member of.
+func (s *OrgService) ListForUser(ctx context.Context, userID string) ([]models.Organisation, error) {
+	return s.orgs.ListForUser(ctx, userID)
+}
+
+// Page sizes for paged listings when the caller asks for none or too many.
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+func clampPageSize(q repositories.PageQuery) repositories.PageQuery {
+	if q.Limit <= 0 {
+		q.Limit = DefaultPageSize
+	}
+	if q.Limit > MaxPageSize {
+		q.Limit = MaxPageSize
+	}
+	return q
+}
+
+// PageForUser returns one page of the organisations userID is a member of.
+func (s *OrgService) PageForUser(ctx context.Context, userID string, q repositories.PageQuery) (repositories.Page[models.Organisation], error) {
+	return s.orgs.PageForUser(ctx, userID, clampPageSize(q))
+}
+
+// IsMember reports whether userID belongs to orgID. It returns
+// ErrOrganisationNotFound if the organisation does not exist.
+func (s *OrgService) IsMember(ctx context.Context, orgID, userID string) (bool, error) {
+	_, err := s.MemberRole(ctx, orgID, userID)
+	if errors.Is(err, ErrNotMember) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// MemberRole returns userID's role in orgID. It returns
+// ErrOrganisationNotFound if the organisation does not exist and
+// ErrNotMember if the user does not belong to it.
+func (s *OrgService) MemberRole(ctx context.Context, orgID, userID string) (string, error) {
+	org, err := s.Get(ctx, orgID)
+	if err != nil {
+		return "", err
+	}
+	role, err := s.orgs.MemberRole(ctx, org, userID)
+	if err != nil {
+		return "", notFound(err, ErrNotMember)
+	}
+	return role, nil
+}
+
+func (s *OrgService) AddMember(ctx context.Context, orgID, userID string) error {
+	org, err := s.Get(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	user, err := s.users.FindByUserID(ctx, userID)
+	if err != nil {
+		return notFound(err, ErrUserNotFound)
+	}
+	return s.orgs.AddMember(ctx, org, user, models.RoleMember)
+}
+
+// PageMembers returns one page of orgID's members, optionally only those
+// with role.
+func (s *OrgService) PageMembers(ctx context.Context, orgID, role string, q repositories.PageQuery) (repositories.Page[models.Member], error) {
+	org, err := s.Get(ctx, orgID)
+	if err != nil {
+		return repositories.Page[models.Member]{}, err
+	}
+	return s.orgs.PageMembers(ctx, org, role, clampPageSize(q))
+}
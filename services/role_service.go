@@ -0,0 +1,364 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/joshua468/user-authentication/models"
+	"github.com/joshua468/user-authentication/repositories"
+	"github.com/joshua468/user-authentication/utils"
+)
+
+var roleName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,31}$`)
+
+// RoleService manages organisations' custom roles and answers which
+// permissions a member holds, through their own role and the roles granted
+// to their teams. Members only ever grant permissions they hold themselves,
+// and only owners grant or take away the owner role.
+type RoleService struct {
+	roles repositories.RoleRepository
+	orgs  repositories.OrganisationRepository
+	teams repositories.TeamRepository
+}
+
+func NewRoleService(roles repositories.RoleRepository, orgs repositories.OrganisationRepository, teams repositories.TeamRepository) *RoleService {
+	return &RoleService{roles, orgs, teams}
+}
+
+type RoleInput struct {
+	Name        string
+	Description string
+	Permissions []string
+}
+
+// RoleUpdate changes the fields that are not nil.
+type RoleUpdate struct {
+	Name        *string
+	Description *string
+	Permissions []string
+}
+
+func (s *RoleService) org(ctx context.Context, orgID string) (*models.Organisation, error) {
+	org, err := s.orgs.FindByOrgID(ctx, orgID)
+	if err != nil {
+		return nil, notFound(err, ErrOrganisationNotFound)
+	}
+	return org, nil
+}
+
+// TokenPermissions returns the permissions userID holds in orgID when
+// their token says their role is role: those of the role and those granted
+// to their teams. It returns ErrNotMember if userID has left orgID and
+// ErrStaleOrgRole if their role has changed since the token was issued.
+func (s *RoleService) TokenPermissions(ctx context.Context, orgID, userID, role string) ([]string, error) {
+	org, err := s.org(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.orgs.MemberRole(ctx, org, userID)
+	if err != nil {
+		return nil, notFound(err, ErrNotMember)
+	}
+	if current != role {
+		return nil, ErrStaleOrgRole
+	}
+	return s.heldPermissions(ctx, org, userID)
+}
+
+// MemberPermissions returns the permissions userID holds in orgID. It
+// returns ErrNotMember if the user does not belong to it.
+func (s *RoleService) MemberPermissions(ctx context.Context, orgID, userID string) ([]string, error) {
+	org, err := s.org(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return s.heldPermissions(ctx, org, userID)
+}
+
+func (s *RoleService) rolePermissions(ctx context.Context, org *models.Organisation, role string) ([]string, error) {
+	if perms, ok := models.BuiltinPermissions[role]; ok || role == "" {
+		return perms, nil
+	}
+	custom, err := s.roles.FindByName(ctx, org, role)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return custom.PermissionList(), nil
+}
+
+// memberPermissions returns userID's role in org and the permissions it
+// grants, leaving out their teams.
+func (s *RoleService) memberPermissions(ctx context.Context, org *models.Organisation, userID string) (string, []string, error) {
+	role, err := s.orgs.MemberRole(ctx, org, userID)
+	if err != nil {
+		return "", nil, notFound(err, ErrNotMember)
+	}
+	perms, err := s.rolePermissions(ctx, org, role)
+	return role, perms, err
+}
+
+// heldPermissions returns the permissions userID holds in org through
+// their role and their teams.
+func (s *RoleService) heldPermissions(ctx context.Context, org *models.Organisation, userID string) ([]string, error) {
+	_, perms, err := s.memberPermissions(ctx, org, userID)
+	if err != nil {
+		return nil, err
+	}
+	teamPerms, err := s.userTeamPermissions(ctx, org, userID)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Concat(perms, teamPerms), nil
+}
+
+func (s *RoleService) userTeamPermissions(ctx context.Context, org *models.Organisation, userID string) ([]string, error) {
+	teams, err := s.teams.ListForUser(ctx, org, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.teamPermissions(ctx, org, teams)
+}
+
+// teamPermissions returns the permissions granted to the members of teams
+// by their roles and those of the teams they are nested in.
+func (s *RoleService) teamPermissions(ctx context.Context, org *models.Organisation, teams []models.Team) ([]string, error) {
+	if len(teams) == 0 {
+		return nil, nil
+	}
+	all, err := s.teams.ListForOrganisation(ctx, org)
+	if err != nil {
+		return nil, err
+	}
+	var perms []string
+	for _, role := range grantedRoles(all, teams) {
+		rolePerms, err := s.rolePermissions(ctx, org, role)
+		if err != nil {
+			return nil, err
+		}
+		perms = append(perms, rolePerms...)
+	}
+	return perms, nil
+}
+
+// holds returns ErrPermissionEscalation unless actorID holds every one of
+// perms in org.
+func (s *RoleService) holds(ctx context.Context, org *models.Organisation, actorID string, perms []string) error {
+	held, err := s.heldPermissions(ctx, org, actorID)
+	if err != nil {
+		return err
+	}
+	for _, perm := range perms {
+		if !slices.Contains(held, perm) {
+			return ErrPermissionEscalation
+		}
+	}
+	return nil
+}
+
+// List returns orgID's custom roles by name.
+func (s *RoleService) List(ctx context.Context, orgID string) ([]models.Role, error) {
+	org, err := s.org(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return s.roles.ListForOrganisation(ctx, org)
+}
+
+func validRole(name string, perms []string) bool {
+	if !roleName.MatchString(name) || slices.Contains(models.Roles, name) {
+		return false
+	}
+	for _, perm := range perms {
+		if !models.IsPermission(perm) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalisePermissions sorts perms and drops duplicates.
+func normalisePermissions(perms []string) string {
+	perms = slices.Clone(perms)
+	slices.Sort(perms)
+	return strings.Join(slices.Compact(perms), " ")
+}
+
+// Create defines a role in orgID on behalf of actorID.
+func (s *RoleService) Create(ctx context.Context, orgID, actorID string, input RoleInput) (*models.Role, error) {
+	if !validRole(input.Name, input.Permissions) {
+		return nil, ErrInvalidRole
+	}
+	org, err := s.org(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.holds(ctx, org, actorID, input.Permissions); err != nil {
+		return nil, err
+	}
+	if err := s.nameFree(ctx, org, input.Name); err != nil {
+		return nil, err
+	}
+
+	role := models.Role{
+		RoleID:         utils.GenerateUUID(),
+		OrganisationID: org.ID,
+		Name:           input.Name,
+		Description:    input.Description,
+		Permissions:    normalisePermissions(input.Permissions),
+	}
+	if err := s.roles.Create(ctx, &role); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (s *RoleService) nameFree(ctx context.Context, org *models.Organisation, name string) error {
+	_, err := s.roles.FindByName(ctx, org, name)
+	switch {
+	case err == nil:
+		return ErrRoleExists
+	case errors.Is(err, repositories.ErrNotFound):
+		return nil
+	default:
+		return err
+	}
+}
+
+// find returns roleID in orgID, and checks that actorID holds every
+// permission it grants.
+func (s *RoleService) find(ctx context.Context, orgID, actorID, roleID string) (*models.Organisation, *models.Role, error) {
+	org, err := s.org(ctx, orgID)
+	if err != nil {
+		return nil, nil, err
+	}
+	role, err := s.roles.FindByRoleID(ctx, org, roleID)
+	if err != nil {
+		return nil, nil, notFound(err, ErrRoleNotFound)
+	}
+	if err := s.holds(ctx, org, actorID, role.PermissionList()); err != nil {
+		return nil, nil, err
+	}
+	return org, role, nil
+}
+
+// Update changes roleID in orgID on behalf of actorID. Members holding the
+// role follow a rename.
+func (s *RoleService) Update(ctx context.Context, orgID, actorID, roleID string, update RoleUpdate) (*models.Role, error) {
+	org, role, err := s.find(ctx, orgID, actorID, roleID)
+	if err != nil {
+		return nil, err
+	}
+	previousName := role.Name
+	if update.Name != nil {
+		role.Name = *update.Name
+	}
+	if update.Description != nil {
+		role.Description = *update.Description
+	}
+	if update.Permissions != nil {
+		role.Permissions = normalisePermissions(update.Permissions)
+	}
+	if !validRole(role.Name, role.PermissionList()) {
+		return nil, ErrInvalidRole
+	}
+	if err := s.holds(ctx, org, actorID, role.PermissionList()); err != nil {
+		return nil, err
+	}
+	if role.Name != previousName {
+		if err := s.nameFree(ctx, org, role.Name); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.roles.Update(ctx, role, previousName); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// Delete removes roleID from orgID on behalf of actorID. It returns
+// ErrRoleInUse while any member or team holds the role.
+func (s *RoleService) Delete(ctx context.Context, orgID, actorID, roleID string) error {
+	org, role, err := s.find(ctx, orgID, actorID, roleID)
+	if err != nil {
+		return err
+	}
+	holders, err := s.orgs.CountMembersWithRole(ctx, org, role.Name)
+	if err != nil {
+		return err
+	}
+	teams, err := s.teams.CountWithRole(ctx, org, role.Name)
+	if err != nil {
+		return err
+	}
+	if holders+teams > 0 {
+		return ErrRoleInUse
+	}
+	return s.roles.Delete(ctx, role)
+}
+
+// AssignRole gives userID the built-in or custom role in orgID on behalf of
+// actorID, who must hold every permission of both the member's current
+// role and the new one.
+func (s *RoleService) AssignRole(ctx context.Context, orgID, actorID, userID, role string) error {
+	return s.assignRole(ctx, orgID, actorID, userID, role)
+}
+
+// SetRole gives userID the built-in or custom role in orgID on an
+// operator's authority, as the CLI does. Only the last owner rule applies.
+func (s *RoleService) SetRole(ctx context.Context, orgID, userID, role string) error {
+	return s.assignRole(ctx, orgID, "", userID, role)
+}
+
+// assignRole checks actorID's right to the change unless actorID is empty.
+func (s *RoleService) assignRole(ctx context.Context, orgID, actorID, userID, role string) error {
+	org, err := s.org(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if _, builtin := models.BuiltinPermissions[role]; !builtin {
+		if _, err := s.roles.FindByName(ctx, org, role); err != nil {
+			return notFound(err, ErrRoleNotFound)
+		}
+	}
+	current, currentPerms, err := s.memberPermissions(ctx, org, userID)
+	if err != nil {
+		return err
+	}
+	if current == role {
+		return nil
+	}
+	if actorID != "" {
+		newPerms, err := s.rolePermissions(ctx, org, role)
+		if err != nil {
+			return err
+		}
+		if err := s.holds(ctx, org, actorID, slices.Concat(currentPerms, newPerms)); err != nil {
+			return err
+		}
+		if current == models.RoleOwner || role == models.RoleOwner {
+			actorRole, err := s.orgs.MemberRole(ctx, org, actorID)
+			if err != nil {
+				return notFound(err, ErrNotMember)
+			}
+			if actorRole != models.RoleOwner {
+				return ErrPermissionEscalation
+			}
+		}
+	}
+	if current == models.RoleOwner {
+		owners, err := s.orgs.CountMembersWithRole(ctx, org, models.RoleOwner)
+		if err != nil {
+			return err
+		}
+		if owners <= 1 {
+			return ErrLastOwner
+		}
+	}
+	return s.orgs.SetMemberRole(ctx, org, userID, role)
+}
@@ -0,0 +1,353 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/joshua468/user-authentication/models"
+	"github.com/joshua468/user-authentication/repositories"
+	"github.com/joshua468/user-authentication/utils"
+)
+
+// TeamService manages the teams of organisations. A team's maintainers and
+// the members holding org.teams.manage change its members; only the latter
+// move it. Putting someone in a team, or a team in a parent, gives them the
+// permissions granted to the team and its parents, so the actor must hold
+// those permissions too.
+type TeamService struct {
+	teams repositories.TeamRepository
+	orgs  repositories.OrganisationRepository
+	users repositories.UserRepository
+	roles *RoleService
+}
+
+func NewTeamService(teams repositories.TeamRepository, orgs repositories.OrganisationRepository, users repositories.UserRepository, roles *RoleService) *TeamService {
+	return &TeamService{teams, orgs, users, roles}
+}
+
+type TeamInput struct {
+	Name        string
+	Description string
+	// ParentID, if not empty, is the team to nest the new team in.
+	ParentID string
+}
+
+// TeamUpdate changes the fields that are not nil. An empty ParentID moves
+// the team to the top level.
+type TeamUpdate struct {
+	Name        *string
+	Description *string
+	ParentID    *string
+}
+
+// grantedRoles returns the roles granted to teams and to the teams they are
+// nested in, each once. all holds every team of the organisation.
+func grantedRoles(all, teams []models.Team) []string {
+	byID := make(map[uint]models.Team, len(all))
+	for _, team := range all {
+		byID[team.ID] = team
+	}
+	var roles []string
+	seen := map[uint]bool{}
+	for _, team := range teams {
+		for id := &team.ID; id != nil && !seen[*id]; {
+			current, ok := byID[*id]
+			if !ok {
+				break
+			}
+			seen[*id] = true
+			if current.Role != "" && !slices.Contains(roles, current.Role) {
+				roles = append(roles, current.Role)
+			}
+			id = current.ParentID
+		}
+	}
+	return roles
+}
+
+func validTeamName(name string) bool {
+	return name != "" && strings.TrimSpace(name) == name && utf8.RuneCountInString(name) <= 64
+}
+
+// validTeamDescription keeps descriptions within the narrowest column the
+// migrations create for them, MySQL's VARCHAR(255).
+func validTeamDescription(description string) bool {
+	return utf8.RuneCountInString(description) <= 255
+}
+
+func (s *TeamService) find(ctx context.Context, orgID, teamID string) (*models.Organisation, *models.Team, error) {
+	org, err := s.roles.org(ctx, orgID)
+	if err != nil {
+		return nil, nil, err
+	}
+	team, err := s.teams.FindByTeamID(ctx, org, teamID)
+	if err != nil {
+		return nil, nil, notFound(err, ErrTeamNotFound)
+	}
+	return org, team, nil
+}
+
+// manages returns ErrNotTeamManager unless actorID maintains team or holds
+// org.teams.manage.
+func (s *TeamService) manages(ctx context.Context, org *models.Organisation, team *models.Team, actorID string) error {
+	membership, err := s.teams.Membership(ctx, team, actorID)
+	if err == nil && membership.Maintainer {
+		return nil
+	}
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return err
+	}
+	return s.canManageTeams(ctx, org, actorID)
+}
+
+func (s *TeamService) canManageTeams(ctx context.Context, org *models.Organisation, actorID string) error {
+	held, err := s.roles.heldPermissions(ctx, org, actorID)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(held, models.PermTeamsManage) {
+		return ErrNotTeamManager
+	}
+	return nil
+}
+
+func (s *TeamService) nameFree(ctx context.Context, org *models.Organisation, name string) error {
+	_, err := s.teams.FindByName(ctx, org, name)
+	switch {
+	case err == nil:
+		return ErrTeamExists
+	case errors.Is(err, repositories.ErrNotFound):
+		return nil
+	default:
+		return err
+	}
+}
+
+// List returns orgID's teams by name.
+func (s *TeamService) List(ctx context.Context, orgID string) ([]models.Team, error) {
+	org, err := s.roles.org(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return s.teams.ListForOrganisation(ctx, org)
+}
+
+func (s *TeamService) Get(ctx context.Context, orgID, teamID string) (*models.Team, error) {
+	_, team, err := s.find(ctx, orgID, teamID)
+	return team, err
+}
+
+// Create adds a team to orgID. The team starts without members or role.
+func (s *TeamService) Create(ctx context.Context, orgID string, input TeamInput) (*models.Team, error) {
+	if !validTeamName(input.Name) {
+		return nil, ErrInvalidTeam
+	}
+	if !validTeamDescription(input.Description) {
+		return nil, ErrTeamDescription
+	}
+	org, err := s.roles.org(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	team := models.Team{
+		TeamID:         utils.GenerateUUID(),
+		OrganisationID: org.ID,
+		Name:           input.Name,
+		Description:    input.Description,
+	}
+	if input.ParentID != "" {
+		parent, err := s.teams.FindByTeamID(ctx, org, input.ParentID)
+		if err != nil {
+			return nil, notFound(err, ErrTeamNotFound)
+		}
+		team.ParentID, team.Parent = &parent.ID, parent
+	}
+	if err := s.nameFree(ctx, org, team.Name); err != nil {
+		return nil, err
+	}
+	if err := s.teams.Create(ctx, &team); err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+// Update changes teamID in orgID on behalf of actorID. Maintainers may
+// change the name and description; moving the team needs org.teams.manage
+// and every permission the new parents grant.
+func (s *TeamService) Update(ctx context.Context, orgID, actorID, teamID string, update TeamUpdate) (*models.Team, error) {
+	if update.Description != nil && !validTeamDescription(*update.Description) {
+		return nil, ErrTeamDescription
+	}
+	org, team, err := s.find(ctx, orgID, teamID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.manages(ctx, org, team, actorID); err != nil {
+		return nil, err
+	}
+	if update.ParentID != nil {
+		if err := s.move(ctx, org, actorID, team, *update.ParentID); err != nil {
+			return nil, err
+		}
+	}
+	if update.Description != nil {
+		team.Description = *update.Description
+	}
+	if update.Name != nil && *update.Name != team.Name {
+		if !validTeamName(*update.Name) {
+			return nil, ErrInvalidTeam
+		}
+		if err := s.nameFree(ctx, org, *update.Name); err != nil {
+			return nil, err
+		}
+		team.Name = *update.Name
+	}
+	if err := s.teams.Update(ctx, team); err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+// move nests team in parentID, or moves it to the top level if parentID is
+// empty.
+func (s *TeamService) move(ctx context.Context, org *models.Organisation, actorID string, team *models.Team, parentID string) error {
+	if err := s.canManageTeams(ctx, org, actorID); err != nil {
+		return err
+	}
+	if parentID == "" {
+		team.ParentID, team.Parent = nil, nil
+		return nil
+	}
+	parent, err := s.teams.FindByTeamID(ctx, org, parentID)
+	if err != nil {
+		return notFound(err, ErrTeamNotFound)
+	}
+	all, err := s.teams.ListForOrganisation(ctx, org)
+	if err != nil {
+		return err
+	}
+	byID := make(map[uint]models.Team, len(all))
+	for _, t := range all {
+		byID[t.ID] = t
+	}
+	for id := &parent.ID; id != nil; id = byID[*id].ParentID {
+		if *id == team.ID {
+			return ErrTeamCycle
+		}
+	}
+	granted, err := s.roles.teamPermissions(ctx, org, []models.Team{*parent})
+	if err != nil {
+		return err
+	}
+	if err := s.roles.holds(ctx, org, actorID, granted); err != nil {
+		return err
+	}
+	team.ParentID, team.Parent = &parent.ID, parent
+	return nil
+}
+
+// Delete removes teamID from orgID with its memberships. It returns
+// ErrTeamHasChildren while teams are nested in it.
+func (s *TeamService) Delete(ctx context.Context, orgID, teamID string) error {
+	_, team, err := s.find(ctx, orgID, teamID)
+	if err != nil {
+		return err
+	}
+	children, err := s.teams.CountChildren(ctx, team)
+	if err != nil {
+		return err
+	}
+	if children > 0 {
+		return ErrTeamHasChildren
+	}
+	return s.teams.Delete(ctx, team)
+}
+
+// Members returns the direct members of teamID, not those of the teams
+// nested in it.
+func (s *TeamService) Members(ctx context.Context, orgID, teamID string) ([]models.TeamMember, error) {
+	_, team, err := s.find(ctx, orgID, teamID)
+	if err != nil {
+		return nil, err
+	}
+	return s.teams.ListMembers(ctx, team)
+}
+
+// SetMember adds userID, who must belong to orgID, to teamID on behalf of
+// actorID, or changes whether they maintain it.
+func (s *TeamService) SetMember(ctx context.Context, orgID, actorID, teamID, userID string, maintainer bool) error {
+	org, team, err := s.find(ctx, orgID, teamID)
+	if err != nil {
+		return err
+	}
+	if err := s.manages(ctx, org, team, actorID); err != nil {
+		return err
+	}
+	if _, err := s.orgs.MemberRole(ctx, org, userID); err != nil {
+		return notFound(err, ErrNotMember)
+	}
+	user, err := s.users.FindByUserID(ctx, userID)
+	if err != nil {
+		return notFound(err, ErrUserNotFound)
+	}
+	granted, err := s.roles.teamPermissions(ctx, org, []models.Team{*team})
+	if err != nil {
+		return err
+	}
+	if err := s.roles.holds(ctx, org, actorID, granted); err != nil {
+		return err
+	}
+	return s.teams.SetMember(ctx, team, user, maintainer)
+}
+
+// RemoveMember takes userID out of teamID on behalf of actorID.
+func (s *TeamService) RemoveMember(ctx context.Context, orgID, actorID, teamID, userID string) error {
+	org, team, err := s.find(ctx, orgID, teamID)
+	if err != nil {
+		return err
+	}
+	if err := s.manages(ctx, org, team, actorID); err != nil {
+		return err
+	}
+	if _, err := s.teams.Membership(ctx, team, userID); err != nil {
+		return notFound(err, ErrNotTeamMember)
+	}
+	return s.teams.RemoveMember(ctx, team, userID)
+}
+
+// AssignRole grants teamID's members the built-in or custom role on behalf
+// of actorID, who must hold every permission of both the team's current
+// role and the new one. An empty role takes the team's role away.
+func (s *TeamService) AssignRole(ctx context.Context, orgID, actorID, teamID, role string) error {
+	if role == models.RoleOwner {
+		return ErrOwnerTeam
+	}
+	org, team, err := s.find(ctx, orgID, teamID)
+	if err != nil {
+		return err
+	}
+	if _, builtin := models.BuiltinPermissions[role]; !builtin && role != "" {
+		if _, err := s.roles.roles.FindByName(ctx, org, role); err != nil {
+			return notFound(err, ErrRoleNotFound)
+		}
+	}
+	if team.Role == role {
+		return nil
+	}
+	currentPerms, err := s.roles.rolePermissions(ctx, org, team.Role)
+	if err != nil {
+		return err
+	}
+	newPerms, err := s.roles.rolePermissions(ctx, org, role)
+	if err != nil {
+		return err
+	}
+	if err := s.roles.holds(ctx, org, actorID, slices.Concat(currentPerms, newPerms)); err != nil {
+		return err
+	}
+	team.Role = role
+	return s.teams.Update(ctx, team)
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/joshua468/user-authentication/models"
+	"github.com/joshua468/user-authentication/repositories"
+	"github.com/joshua468/user-authentication/utils"
+)
+
+// TokenService answers questions about access tokens beyond their
+// signature: whether they were revoked and what the subject may access.
+type TokenService struct {
+	signer  utils.TokenSigner
+	revoked repositories.RevokedTokenRepository
+	users   repositories.UserRepository
+	orgs    repositories.OrganisationRepository
+}
+
+func NewTokenService(signer utils.TokenSigner, revoked repositories.RevokedTokenRepository, users repositories.UserRepository, orgs repositories.OrganisationRepository) *TokenService {
+	return &TokenService{signer, revoked, users, orgs}
+}
+
+// Introspection describes an active token. Inactive tokens are reported as
+// nil, since RFC 7662 says nothing else about them should be disclosed.
+type Introspection struct {
+	Claims *utils.Claims
+	OrgIDs []string
+}
+
+// Revoke stops claims' token from being accepted before it expires.
+func (s *TokenService) Revoke(ctx context.Context, claims *utils.Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return ErrTokenNotRevocable
+	}
+	return s.revoked.Revoke(ctx, &models.RevokedToken{
+		TokenID:   claims.ID,
+		UserID:    claims.UserID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	})
+}
+
+// CheckToken reports why claims' token must no longer be accepted even
+// though its signature is valid: utils.ErrTokenRevoked if it was revoked or
+// issued before its user's sessions were revoked,
+// utils.ErrTokenSubjectDisabled or utils.ErrTokenSubjectUnknown if its user
+// was disabled or deleted, and utils.ErrTokenActorRevoked if it is an
+// impersonation token whose admin has since lost the right to impersonate.
+// It returns nil for a token that is still good, and any other error if the
+// check itself failed.
+func (s *TokenService) CheckToken(ctx context.Context, claims *utils.Claims) error {
+	// Tokens without an ID predate revocation support and can only be
+	// invalidated by revoking the user's sessions.
+	if claims.ID != "" {
+		revoked, err := s.revoked.IsRevoked(ctx, claims.ID)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return utils.ErrTokenRevoked
+		}
+	}
+
+	user, err := s.users.FindByUserID(ctx, claims.UserID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return utils.ErrTokenSubjectUnknown
+	}
+	if err != nil {
+		return err
+	}
+	if user.Disabled() {
+		return utils.ErrTokenSubjectDisabled
+	}
+	// iat has whole-second precision, so a token issued in the same second
+	// as the revocation is rejected too, whichever came first.
+	if user.SessionsRevokedAt != nil && (claims.IssuedAt == nil || claims.IssuedAt.Before(*user.SessionsRevokedAt)) {
+		return utils.ErrTokenRevoked
+	}
+	if actorID := claims.ActorID(); actorID != "" {
+		return s.checkActor(ctx, actorID, claims)
+	}
+	return nil
+}
+
+// checkActor ends an impersonation session as soon as the admin is disabled,
+// loses admin rights or has their own sessions revoked.
+func (s *TokenService) checkActor(ctx context.Context, actorID string, claims *utils.Claims) error {
+	actor, err := s.users.FindByUserID(ctx, actorID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return utils.ErrTokenActorRevoked
+	}
+	if err != nil {
+		return err
+	}
+	if !actor.IsAdmin || actor.Disabled() {
+		return utils.ErrTokenActorRevoked
+	}
+	if actor.SessionsRevokedAt != nil && (claims.IssuedAt == nil || claims.IssuedAt.Before(*actor.SessionsRevokedAt)) {
+		return utils.ErrTokenActorRevoked
+	}
+	return nil
+}
+
+// Introspect returns nil for a token that is invalid, expired or rejected
+// by CheckToken, or that only allows changing the password here.
+func (s *TokenService) Introspect(ctx context.Context, token string) (*Introspection, error) {
+	// Tokens from Exchange are addressed to the services that introspect
+	// them rather than to this API.
+	claims, err := s.signer.VerifyTokenAnyAudience(token)
+	if err != nil || claims.PasswordChangeOnly() {
+		return nil, nil
+	}
+	if err := s.CheckToken(ctx, claims); err != nil {
+		if utils.TokenRejected(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	orgs, err := s.orgs.ListForUser(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	result := &Introspection{Claims: claims, OrgIDs: make([]string, 0, len(orgs))}
+	for _, org := range orgs {
+		result.OrgIDs = append(result.OrgIDs, org.OrgID)
+	}
+	return result, nil
+}
+
+// Impersonate issues a token for userID carrying actorID in its "act"
+// claim. Callers must check that actorID may impersonate userID.
+func (s *TokenService) Impersonate(userID, actorID string, ttl time.Duration) (string, *utils.Claims, error) {
+	claims := &utils.Claims{
+		Actor:            &utils.Actor{Subject: actorID},
+		RegisteredClaims: jwt.RegisteredClaims{Subject: userID},
+	}
+	token, err := s.signer.IssueClaims(claims, ttl)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrTokenIssuance, err)
+	}
+	return token, claims, nil
+}
+
+// SwitchOrganisation issues a token like claims' scoped to orgID, carrying
+// the user's current role there, or an unscoped token when orgID is empty.
+// It returns ErrOrganisationNotFound or ErrNotMember if the user cannot
+// switch to orgID. Impersonation tokens keep their admin and expiry.
+func (s *TokenService) SwitchOrganisation(ctx context.Context, claims *utils.Claims, orgID string) (string, *utils.Claims, error) {
+	switched := &utils.Claims{
+		Scope:            claims.Scope,
+		Actor:            claims.Actor,
+		OrgID:            orgID,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: claims.Subject},
+	}
+	if orgID != "" {
+		org, err := s.orgs.FindByOrgID(ctx, orgID)
+		if err != nil {
+			return "", nil, notFound(err, ErrOrganisationNotFound)
+		}
+		if switched.OrgRole, err = s.orgs.MemberRole(ctx, org, claims.Subject); err != nil {
+			return "", nil, notFound(err, ErrNotMember)
+		}
+	}
+
+	var ttl time.Duration
+	if claims.Actor != nil {
+		ttl = time.Until(claims.ExpiresAt.Time).Truncate(time.Second)
+		if ttl < time.Second {
+			return "", nil, ErrInvalidSubjectToken
+		}
+	}
+	token, err := s.signer.IssueClaims(switched, ttl)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrTokenIssuance, err)
+	}
+	return token, switched, nil
+}
+
+// Exchanged is a token issued by Exchange.
+type Exchanged struct {
+	Token  string
+	Claims *utils.Claims
+}
+
+// Exchange trades subjectToken, an access token for this API, for one
+// addressed to policy's audience (RFC 8693). The new token keeps the
+// subject and its organisation, if any, and carries the requested scopes,
+// or every scope allowed when scope is empty. Scopes must be allowed by
+// policy and, if the subject token is scoped, by it too. The lifetime is the
+// policy's maximum, cut short to end with the subject token's. Impersonation
+// tokens are refused with ErrImpersonatedSubject, and an exchange that would
+// carry no scope with ErrInvalidScope. This API refuses the new token even
+// if policy names its audience.
+func (s *TokenService) Exchange(ctx context.Context, subjectToken string, policy *models.ExchangePolicy, scope string) (*Exchanged, error) {
+	subject, err := s.signer.VerifyToken(subjectToken)
+	if err != nil {
+		return nil, ErrInvalidSubjectToken
+	}
+	if err := s.CheckToken(ctx, subject); err != nil {
+		if utils.TokenRejected(err) {
+			return nil, ErrInvalidSubjectToken
+		}
+		return nil, err
+	}
+	if subject.ActorID() != "" {
+		return nil, ErrImpersonatedSubject
+	}
+	if subject.PasswordChangeOnly() {
+		return nil, ErrInvalidSubjectToken
+	}
+
+	allowed := strings.Fields(policy.Scopes)
+	if subject.Scope != "" {
+		allowed = intersectScopes(allowed, strings.Fields(subject.Scope))
+	}
+	scopes := strings.Fields(scope)
+	if len(scopes) == 0 {
+		scopes = allowed
+	}
+	// A token without a scope is unrestricted, which no exchange grants.
+	if len(scopes) == 0 {
+		return nil, ErrInvalidScope
+	}
+	for _, requested := range scopes {
+		if !slices.Contains(allowed, requested) {
+			return nil, ErrInvalidScope
+		}
+	}
+
+	ttl := min(policy.MaxTTL(), time.Until(subject.ExpiresAt.Time).Truncate(time.Second))
+	if ttl < time.Second {
+		return nil, ErrInvalidSubjectToken
+	}
+	// The organisation scope carries over, so that an exchanged token never
+	// reaches more organisations than the subject token did.
+	claims := &utils.Claims{
+		Scope:    strings.Join(scopes, " "),
+		ClientID: policy.ClientID,
+		OrgID:    subject.OrgID,
+		OrgRole:  subject.OrgRole,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:  subject.Subject,
+			Audience: jwt.ClaimStrings{policy.Audience},
+		},
+	}
+	token, err := s.signer.IssueClaims(claims, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenIssuance, err)
+	}
+	return &Exchanged{Token: token, Claims: claims}, nil
+}
+
+func intersectScopes(a, b []string) []string {
+	var both []string
+	for _, scope := range a {
+		if slices.Contains(b, scope) {
+			both = append(both, scope)
+		}
+	}
+	return both
+}
+
+// RevokeSessions invalidates every token issued to userID so far.
+func (s *TokenService) RevokeSessions(ctx context.Context, userID string) error {
+	user, err := s.users.FindByUserID(ctx, userID)
+	if err != nil {
+		return notFound(err, ErrUserNotFound)
+	}
+	now := time.Now()
+	user.SessionsRevokedAt = &now
+	return s.users.Update(ctx, user)
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/joshua468/user-authentication/models"
+	"github.com/joshua468/user-authentication/repositories"
+	"github.com/joshua468/user-authentication/tracing"
+	"github.com/joshua468/user-authentication/utils"
+)
+
+type UserService struct {
+	users repositories.UserRepository
+}
+
+func NewUserService(users repositories.UserRepository) *UserService {
+	return &UserService{users}
+}
+
+type CreateUserInput struct {
+	FirstName string
+	LastName  string
+	Email     string
+	Password  string
+	Phone     string
+}
+
+func (s *UserService) Create(ctx context.Context, input CreateUserInput) (*models.User, error) {
+	hashedPassword, err := hashPassword(ctx, input.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := models.User{
+		UserID:    utils.GenerateUUID(),
+		FirstName: input.FirstName,
+		LastName:  input.LastName,
+		Email:     input.Email,
+		Password:  hashedPassword,
+		Phone:     input.Phone,
+	}
+	if err := s.users.Create(ctx, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *UserService) Get(ctx context.Context, userID string) (*models.User, error) {
+	user, err := s.users.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, notFound(err, ErrUserNotFound)
+	}
+	return user, nil
+}
+
+func (s *UserService) List(ctx context.Context) ([]models.User, error) {
+	return s.users.List(ctx)
+}
+
+// Authenticate checks an email and password pair. Unknown emails and wrong
+// passwords both return ErrInvalidCredentials.
+func (s *UserService) Authenticate(ctx context.Context, email, password string) (*models.User, error) {
+	user, err := s.users.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if err := comparePassword(ctx, user.Password, password); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if user.Disabled() {
+		return nil, ErrUserDisabled
+	}
+	return user, nil
+}
+
+func (s *UserService) SetDisabled(ctx context.Context, userID string, disabled bool) (*models.User, error) {
+	user, err := s.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	user.DisabledAt = nil
+	if disabled {
+		now := time.Now()
+		user.DisabledAt = &now
+	}
+	if err := s.users.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ResetPassword sets userID's password and requires them to change it
+// before they can sign in normally again.
+func (s *UserService) ResetPassword(ctx context.Context, userID, password string) error {
+	user, err := s.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := hashPassword(ctx, password)
+	if err != nil {
+		return err
+	}
+	user.Password = hashedPassword
+	user.PasswordResetRequired = true
+	return s.users.Update(ctx, user)
+}
+
+// ChangePassword replaces userID's password after checking the current one,
+// which satisfies a forced reset.
+func (s *UserService) ChangePassword(ctx context.Context, userID, current, password string) (*models.User, error) {
+	user, err := s.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := comparePassword(ctx, user.Password, current); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if password == current {
+		return nil, ErrPasswordUnchanged
+	}
+	hashedPassword, err := hashPassword(ctx, password)
+	if err != nil {
+		return nil, err
+	}
+	user.Password = hashedPassword
+	user.PasswordResetRequired = false
+	if err := s.users.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// hashPassword and comparePassword wrap bcrypt in spans; at the default cost
+// they are usually the slowest step of registration and login.
+func hashPassword(ctx context.Context, password string) (string, error) {
+	_, span := tracing.Start(ctx, "bcrypt.Hash")
+	defer span.End()
+	return utils.HashPassword(password)
+}
+
+func comparePassword(ctx context.Context, hashedPassword, password string) error {
+	_, span := tracing.Start(ctx, "bcrypt.Compare")
+	defer span.End()
+	return utils.ComparePassword(hashedPassword, password)
+}
@@ -0,0 +1,227 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshua468/user-authentication/middlewares"
+	"github.com/joshua468/user-authentication/models"
+	"github.com/joshua468/user-authentication/routes"
+	"github.com/joshua468/user-authentication/services"
+	"github.com/joshua468/user-authentication/utils"
+)
+
+func TestAdminAPI(t *testing.T) {
+	db := openTestDB()
+	router := gin.New()
+	router.Use(middlewares.RequestID())
+	routes.Register(router, apiHandlers(db))
+
+	do := func(method, path, token string, body interface{}) (int, map[string]interface{}) {
+		raw, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var decoded map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &decoded)
+		return w.Code, decoded
+	}
+	login := func(email, password string) (int, string) {
+		code, body := do("POST", "/api/auth/login", "", map[string]string{"email": email, "password": password})
+		if code != http.StatusOK {
+			return code, ""
+		}
+		return code, body["data"].(map[string]interface{})["accessToken"].(string)
+	}
+
+	for _, user := range []models.User{
+		{FirstName: "Ada", LastName: "Admin", Email: "ada@example.com", Password: "password123"},
+		{FirstName: "Jane", LastName: "Doe", Email: "jane.doe@example.com", Password: "password123"},
+	} {
+		code, _ := registerUser(router, user)
+		assert.Equal(t, http.StatusCreated, code)
+	}
+	var jane models.User
+	db.Where("email = ?", "jane.doe@example.com").First(&jane)
+	_, adminToken := login("ada@example.com", "password123")
+	_, janeToken := login("jane.doe@example.com", "password123")
+
+	code, _ := do("GET", "/api/admin/users", adminToken, nil)
+	assert.Equal(t, http.StatusForbidden, code, "admin rights must be granted first")
+	db.Model(&models.User{}).Where("email = ?", "ada@example.com").Update("is_admin", true)
+
+	code, body := do("GET", "/api/admin/users?q=doe", adminToken, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(1), body["pagination"].(map[string]interface{})["total"])
+
+	// Disabling takes effect on existing tokens immediately.
+	code, _ = do("POST", "/api/admin/users/"+jane.UserID+"/disable", adminToken, nil)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = do("GET", "/api/users/"+jane.UserID, janeToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = login("jane.doe@example.com", "password123")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, _ = do("POST", "/api/admin/users/"+jane.UserID+"/enable", adminToken, nil)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = do("GET", "/api/users/"+jane.UserID, janeToken, nil)
+	assert.Equal(t, http.StatusOK, code)
+
+	// A forced reset replaces the password and signs the user out.
+	code, body = do("POST", "/api/admin/users/"+jane.UserID+"/reset-password", adminToken, nil)
+	assert.Equal(t, http.StatusOK, code)
+	temporary := body["data"].(map[string]interface{})["temporaryPassword"].(string)
+	code, _ = do("GET", "/api/users/"+jane.UserID, janeToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = login("jane.doe@example.com", "password123")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// Until she changes it, Jane's token only reaches change-password. iat
+	// has whole-second precision, so the revocation is moved back a second
+	// for her next token to outlive it.
+	db.Model(&models.User{}).Where("user_id = ?", jane.UserID).Update("sessions_revoked_at", time.Now().Add(-time.Second))
+	code, body = do("POST", "/api/auth/login", "", map[string]string{"email": "jane.doe@example.com", "password": temporary})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, body["data"].(map[string]interface{})["passwordResetRequired"])
+	janeToken = body["data"].(map[string]interface{})["accessToken"].(string)
+	code, _ = do("GET", "/api/users/"+jane.UserID, janeToken, nil)
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = do("POST", "/api/auth/switch-organisation", janeToken, map[string]string{})
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = do("POST", "/api/auth/change-password", janeToken, map[string]string{"currentPassword": "wrong", "newPassword": "password456"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = do("POST", "/api/auth/change-password", janeToken, map[string]string{"currentPassword": temporary, "newPassword": temporary})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, body = do("POST", "/api/auth/change-password", janeToken, map[string]string{"currentPassword": temporary, "newPassword": "password456"})
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = do("GET", "/api/users/"+jane.UserID, body["data"].(map[string]interface{})["accessToken"].(string), nil)
+	assert.Equal(t, http.StatusOK, code)
+	code, body = do("POST", "/api/auth/login", "", map[string]string{"email": "jane.doe@example.com", "password": "password456"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, false, body["data"].(map[string]interface{})["passwordResetRequired"])
+
+	code, _ = do("POST", "/api/admin/users/missing/revoke-sessions", adminToken, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, body = do("GET", "/api/admin/audit-events?target="+jane.UserID, adminToken, nil)
+	assert.Equal(t, http.StatusOK, code)
+	var actions []string
+	for _, event := range body["data"].([]interface{}) {
+		actions = append(actions, event.(map[string]interface{})["action"].(string))
+	}
+	assert.Equal(t, []string{models.AuditUserDisabled, models.AuditUserEnabled, models.AuditUserPasswordReset}, actions)
+
+	// Admin rights are checked on every request.
+	db.Model(&models.User{}).Where("email = ?", "ada@example.com").Update("is_admin", false)
+	code, _ = do("GET", "/api/admin/audit-events", adminToken, nil)
+	assert.Equal(t, http.StatusForbidden, code)
+}
+
+func TestAdminImpersonation(t *testing.T) {
+	db := openTestDB()
+	router := gin.New()
+	router.Use(middlewares.RequestID())
+	routes.Register(router, apiHandlers(db))
+
+	do := func(method, path, token string) (int, map[string]interface{}) {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var decoded map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &decoded)
+		return w.Code, decoded
+	}
+
+	var tokens []string
+	for _, user := range []models.User{
+		{FirstName: "Ada", LastName: "Admin", Email: "ada@example.com", Password: "password123"},
+		{FirstName: "Jane", LastName: "Doe", Email: "jane.doe@example.com", Password: "password123"},
+		{FirstName: "Bob", LastName: "Admin", Email: "bob@example.com", Password: "password123"},
+	} {
+		_, body := registerUser(router, user)
+		tokens = append(tokens, body["data"].(map[string]interface{})["accessToken"].(string))
+	}
+	var ada, jane, bob models.User
+	db.Where("email = ?", "ada@example.com").First(&ada)
+	db.Where("email = ?", "jane.doe@example.com").First(&jane)
+	db.Where("email = ?", "bob@example.com").First(&bob)
+	db.Model(&models.User{}).Where("user_id IN ?", []string{ada.UserID, bob.UserID}).Update("is_admin", true)
+	adminToken := tokens[0]
+
+	code, body := do("POST", "/api/admin/users/"+jane.UserID+"/impersonate", adminToken)
+	assert.Equal(t, http.StatusOK, code)
+	token := body["data"].(map[string]interface{})["accessToken"].(string)
+
+	claims, err := utils.ParseToken(token, "test-secret")
+	assert.Nil(t, err)
+	assert.Equal(t, jane.UserID, claims.Subject)
+	assert.Equal(t, ada.UserID, claims.ActorID())
+	assert.WithinDuration(t, time.Now().Add(services.ImpersonationTTL), claims.ExpiresAt.Time, time.Minute)
+
+	// The token acts as Jane but cannot reach the admin API.
+	code, body = do("GET", "/api/users/"+jane.UserID, token)
+	assert.Equal(t, http.StatusOK, code)
+	code, body = do("GET", "/api/admin/users", token)
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Equal(t, "Not allowed while impersonating a user", body["error"])
+	code, body = do("POST", "/api/auth/change-password", token)
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Equal(t, "Not allowed while impersonating a user", body["error"])
+
+	for _, target := range []string{ada.UserID, bob.UserID} {
+		code, _ = do("POST", "/api/admin/users/"+target+"/impersonate", adminToken)
+		assert.Equal(t, http.StatusForbidden, code, "admins cannot be impersonated")
+	}
+
+	code, body = do("GET", "/api/admin/audit-events?action="+models.AuditUserImpersonated, adminToken)
+	assert.Equal(t, http.StatusOK, code)
+	events := body["data"].([]interface{})
+	if assert.Len(t, events, 1) {
+		event := events[0].(map[string]interface{})
+		assert.Equal(t, ada.UserID, event["actorId"])
+		assert.Equal(t, jane.UserID, event["targetId"])
+	}
+
+	// Changes made while impersonating are audited, but roles are not
+	// handed out.
+	req, _ := http.NewRequest("POST", "/api/organisations/", bytes.NewBufferString(`{"name":"Jane's Org"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	orgID := created["data"].(map[string]interface{})["orgId"].(string)
+	req, _ = http.NewRequest("PUT", "/api/organisations/"+orgID+"/users/"+jane.UserID+"/role", bytes.NewBufferString(`{"role":"admin"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	code, body = do("GET", "/api/admin/audit-events?action="+models.AuditImpersonatedRequest, adminToken)
+	assert.Equal(t, http.StatusOK, code)
+	events = body["data"].([]interface{})
+	if assert.Len(t, events, 1) {
+		event := events[0].(map[string]interface{})
+		assert.Equal(t, jane.UserID, event["actorId"])
+		assert.Equal(t, ada.UserID, event["impersonatorId"])
+		assert.Equal(t, "POST /api/organisations/", event["targetId"])
+	}
+
+	// Losing admin rights ends the impersonation at once.
+	db.Model(&models.User{}).Where("user_id = ?", ada.UserID).Update("is_admin", false)
+	code, _ = do("GET", "/api/users/"+jane.UserID, token)
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
@@ -0,0 +1,241 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshua468/user-authentication/middlewares"
+	"github.com/joshua468/user-authentication/models"
+	"github.com/joshua468/user-authentication/repositories"
+	"github.com/joshua468/user-authentication/routes"
+	"github.com/joshua468/user-authentication/services"
+)
+
+const documentsSchema = `
+definition user {}
+
+definition organisation {
+    relation owner: user
+    relation admin: user
+    relation member: user
+    permission manage = owner + admin
+    permission view = member
+}
+
+definition team {
+    relation organisation: organisation
+    relation parent: team
+    relation maintainer: user
+    relation member: user | team#member
+}
+
+// Folders inherit viewers from their parent.
+definition folder {
+    relation org: organisation
+    relation parent: folder
+    relation viewer: user | team#member
+    permission view = viewer + org->manage + parent->view
+}
+
+definition document {
+    relation folder: folder
+    permission view = folder->view
+}
+`
+
+func TestRelationshipAuthorization(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB()
+	router := gin.New()
+	router.Use(middlewares.RequestID())
+	routes.Register(router, apiHandlers(db))
+
+	clients := services.NewClientService(repositories.NewGormServiceClientRepository(db), repositories.NewGormExchangePolicyRepository(db))
+	docs, secret, err := clients.Create(ctx, "documents")
+	assert.Nil(t, err)
+	_, err = clients.SetScope(ctx, docs.ClientID, models.ClientScopeAuthzWrite, true)
+	assert.Nil(t, err)
+	_, err = clients.SetScope(ctx, docs.ClientID, "authz.admin", true)
+	assert.ErrorIs(t, err, services.ErrUnknownClientScope)
+
+	call := func(method, path, token string, body interface{}) (int, map[string]interface{}) {
+		payload, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		} else {
+			req.SetBasicAuth(docs.ClientID, secret)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+	register := func(first, email string) (string, string) {
+		_, body := registerUser(router, models.User{FirstName: first, LastName: "Doe", Email: email, Password: "password123"})
+		data := body["data"].(map[string]interface{})
+		return data["accessToken"].(string), data["user"].(map[string]interface{})["userId"].(string)
+	}
+	johnToken, john := register("John", "john.doe@example.com")
+	_, jane := register("Jane", "jane.doe@example.com")
+	_, bob := register("Bob", "bob.doe@example.com")
+	_, body := call("POST", "/api/organisations/", johnToken, map[string]string{"name": "Acme"})
+	acme := body["data"].(map[string]interface{})["orgId"].(string)
+	code, _ := call("POST", "/api/organisations/"+acme+"/users", johnToken, map[string]string{"userId": jane})
+	assert.Equal(t, http.StatusOK, code)
+
+	rel := func(namespace, object, relation, subjectNamespace, subject, subjectRelation string) map[string]string {
+		return map[string]string{
+			"namespace": namespace, "objectId": object, "relation": relation,
+			"subjectNamespace": subjectNamespace, "subjectId": subject, "subjectRelation": subjectRelation,
+		}
+	}
+	write := func(operation string, rels ...map[string]string) (int, map[string]interface{}) {
+		var updates []map[string]interface{}
+		for _, r := range rels {
+			updates = append(updates, map[string]interface{}{"operation": operation, "relationship": r})
+		}
+		return call("POST", "/api/authz/relationships", "", map[string]interface{}{"updates": updates})
+	}
+	check := func(namespace, object, permission, user string) bool {
+		code, body := call("POST", "/api/authz/check", "", map[string]string{
+			"namespace": namespace, "objectId": object, "permission": permission,
+			"subjectNamespace": "user", "subjectId": user,
+		})
+		assert.Equal(t, http.StatusOK, code, body)
+		return body["data"].(map[string]interface{})["allowed"].(bool)
+	}
+	list := func(namespace, permission, user string) []interface{} {
+		code, body := call("POST", "/api/authz/list-objects", "", map[string]string{
+			"namespace": namespace, "permission": permission, "subjectNamespace": "user", "subjectId": user,
+		})
+		assert.Equal(t, http.StatusOK, code, body)
+		return body["data"].(map[string]interface{})["objectIds"].([]interface{})
+	}
+
+	// Only service clients may call the engine.
+	code, _ = call("GET", "/api/authz/schema", johnToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// Clients without authz.write only read and check.
+	reader, readerSecret, err := clients.Create(ctx, "reader")
+	assert.Nil(t, err)
+	asReader := func(method, path string, body interface{}) int {
+		payload, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewReader(payload))
+		req.SetBasicAuth(reader.ClientID, readerSecret)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, asReader("GET", "/api/authz/schema", nil))
+	assert.Equal(t, http.StatusForbidden, asReader("PUT", "/api/authz/schema", map[string]string{"schema": documentsSchema}))
+	assert.Equal(t, http.StatusForbidden, asReader("POST", "/api/authz/relationships", map[string]interface{}{"updates": []interface{}{}}))
+
+	// Memberships are relationships from the start.
+	code, body = call("GET", "/api/authz/schema", "", nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body["data"].(map[string]interface{})["schema"], "definition organisation")
+	assert.True(t, check("organisation", acme, "manage", john))
+	assert.True(t, check("organisation", acme, "view", jane))
+	assert.False(t, check("organisation", acme, "manage", jane))
+	assert.False(t, check("organisation", acme, "view", bob))
+	code, body = call("GET", "/api/authz/relationships?namespace=organisation&objectId="+acme, "", nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, body["data"], 3)
+
+	code, body = call("PUT", "/api/authz/schema", "", map[string]string{"schema": "definition folder { relation viewer: team }"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, body["error"], `unknown namespace "team"`)
+	code, _ = call("PUT", "/api/authz/schema", "", map[string]string{"schema": documentsSchema})
+	assert.Equal(t, http.StatusOK, code)
+
+	// Teams are relationships too.
+	team := func(name, parent string) string {
+		code, body := call("POST", "/api/organisations/"+acme+"/teams", johnToken, map[string]string{"name": name, "parentId": parent})
+		assert.Equal(t, http.StatusCreated, code, body)
+		return body["data"].(map[string]interface{})["teamId"].(string)
+	}
+	teamMember := func(method, team, user string) {
+		code, body := call(method, "/api/organisations/"+acme+"/teams/"+team+"/members/"+user, johnToken, map[string]bool{"maintainer": false})
+		assert.Equal(t, http.StatusOK, code, body)
+	}
+	eng := team("Engineering", "")
+	teamMember("PUT", eng, jane)
+
+	code, _ = write("touch",
+		rel("folder", "plans", "org", "organisation", acme, ""),
+		rel("folder", "plans", "viewer", "team", eng, "member"),
+		rel("folder", "drafts", "parent", "folder", "plans", ""),
+		rel("document", "roadmap", "folder", "folder", "drafts", ""),
+	)
+	assert.Equal(t, http.StatusOK, code)
+
+	assert.True(t, check("document", "roadmap", "view", jane))
+	assert.True(t, check("document", "roadmap", "view", john))
+	assert.False(t, check("document", "roadmap", "view", bob))
+	assert.Equal(t, []interface{}{"roadmap"}, list("document", "view", jane))
+	assert.Equal(t, []interface{}{"drafts", "plans"}, list("folder", "view", john))
+	assert.Empty(t, list("folder", "view", bob))
+
+	// Members of nested teams are members of their parents.
+	code, _ = call("POST", "/api/organisations/"+acme+"/users", johnToken, map[string]string{"userId": bob})
+	assert.Equal(t, http.StatusOK, code)
+	leads := team("Leads", eng)
+	teamMember("PUT", leads, bob)
+	assert.True(t, check("document", "roadmap", "view", bob))
+	teamMember("DELETE", eng, jane)
+	assert.False(t, check("document", "roadmap", "view", jane))
+	code, body = call("GET", "/api/authz/relationships?namespace=team&objectId="+eng+"&relation=member", "", nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"namespace": "team", "objectId": eng, "relation": "member",
+		"subjectNamespace": "team", "subjectId": leads, "subjectRelation": "member",
+	}}, body["data"])
+	assert.Equal(t, false, body["truncated"])
+
+	// The limit covers derived relationships as well as stored ones.
+	rels, err := repositories.NewGormRelationshipRepository(db).Find(ctx, repositories.RelationshipFilter{Namespace: "team", Limit: 2})
+	assert.Nil(t, err)
+	assert.Len(t, rels, 2)
+
+	// Relationships follow the schema, and memberships and teams are not
+	// written here.
+	code, _ = write("touch", rel("folder", "plans", "viewer", "organisation", acme, ""))
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = write("touch", rel("organisation", acme, "member", "user", bob, ""))
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = write("touch", rel("team", eng, "member", "user", bob, ""))
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = call("POST", "/api/authz/check", "", map[string]string{
+		"namespace": "document", "objectId": "roadmap", "permission": "edit", "subjectNamespace": "user", "subjectId": jane,
+	})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// A schema cannot drop relations that are still in use.
+	withoutFolders := documentsSchema[:strings.Index(documentsSchema, "// Folders")]
+	code, body = call("PUT", "/api/authz/schema", "", map[string]string{"schema": withoutFolders})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, body["error"], "relationships still use")
+
+	// Nor relations derived from memberships and teams.
+	withoutMember := strings.Replace(documentsSchema, "    relation member: user\n    permission manage = owner + admin\n    permission view = member\n",
+		"    permission manage = owner + admin\n    permission view = admin\n", 1)
+	code, body = call("PUT", "/api/authz/schema", "", map[string]string{"schema": withoutMember})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, body["error"], "organisation#member")
+	withoutNesting := strings.Replace(documentsSchema, "relation member: user | team#member", "relation member: user", 1)
+	code, body = call("PUT", "/api/authz/schema", "", map[string]string{"schema": withoutNesting})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, body["error"], "team#member must allow team#member")
+}
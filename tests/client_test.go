@@ -0,0 +1,204 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/joshua468/user-authentication/client"
+	"github.com/joshua468/user-authentication/controllers"
+	"github.com/joshua468/user-authentication/middlewares"
+	"github.com/joshua468/user-authentication/repositories"
+	"github.com/joshua468/user-authentication/routes"
+	"github.com/joshua468/user-authentication/services"
+	"github.com/joshua468/user-authentication/utils"
+)
+
+// apiHandlers wires the real controllers over db.
+func apiHandlers(db *gorm.DB) routes.Handlers {
+	userRepo := repositories.NewGormUserRepository(db)
+	orgRepo := repositories.NewGormOrganisationRepository(db)
+	users := services.NewUserService(userRepo)
+	orgs := services.NewOrgService(orgRepo, userRepo)
+	teamRepo := repositories.NewGormTeamRepository(db)
+	roles := services.NewRoleService(repositories.NewGormRoleRepository(db), orgRepo, teamRepo)
+	tokens := utils.NewJWTService("test-secret", utils.DefaultTokenOptions())
+	tokenService := services.NewTokenService(tokens, repositories.NewGormRevokedTokenRepository(db), userRepo, orgRepo)
+	clients := services.NewClientService(repositories.NewGormServiceClientRepository(db), repositories.NewGormExchangePolicyRepository(db))
+	admin := services.NewAdminService(users, userRepo, orgRepo, tokenService, repositories.NewGormAuditEventRepository(db))
+
+	auth := controllers.NewAuthController(services.NewAuthService(users, tokens))
+	auth.Tokens = tokenService
+	return routes.Handlers{
+		Auth:          auth,
+		Users:         controllers.NewUserController(users),
+		Organisations: controllers.NewOrganisationController(orgs, roles),
+		Teams:         controllers.NewTeamController(services.NewTeamService(teamRepo, orgRepo, userRepo, roles)),
+		Health:        controllers.NewHealthController(),
+		OAuth:         controllers.NewOAuthController(clients, tokenService),
+		Admin:         controllers.NewAdminController(admin),
+		Authz:         controllers.NewAuthzController(services.NewAuthzService(repositories.NewGormRelationshipRepository(db), repositories.NewGormAuthzSchemaRepository(db))),
+		Authenticate: middlewares.JWTAuthMiddlewareWithConfig(middlewares.JWTConfig{
+			Verifier: tokens,
+			Checker:  tokenService,
+			Auditor:  admin,
+		}),
+		RequireAdmin:       middlewares.RequireAdmin(admin),
+		AuthenticateClient: middlewares.RequireClient(clients, "authz"),
+	}
+}
+
+// newAPIServer serves the real route table over a fresh test database.
+func newAPIServer(t *testing.T, wrap func(http.Handler) http.Handler) *httptest.Server {
+	router := gin.New()
+	router.Use(middlewares.RequestID())
+	routes.Register(router, apiHandlers(openTestDB()))
+
+	var handler http.Handler = router
+	if wrap != nil {
+		handler = wrap(handler)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClientAgainstRouter(t *testing.T) {
+	ctx := context.Background()
+	server := newAPIServer(t, nil)
+	c := client.New(server.URL, client.Options{})
+
+	registered, err := c.Register(ctx, client.RegisterRequest{
+		FirstName: "John", LastName: "Doe", Email: "john.doe@example.com", Password: "password123",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, registered.AccessToken, c.Token())
+
+	other, err := client.New(server.URL, client.Options{}).Register(ctx, client.RegisterRequest{
+		FirstName: "Jane", LastName: "Doe", Email: "jane.doe@example.com", Password: "password123",
+	})
+	assert.Nil(t, err)
+
+	user, err := c.GetUser(ctx, registered.User.UserID)
+	assert.Nil(t, err)
+	assert.Equal(t, "john.doe@example.com", user.Email)
+
+	org, err := c.CreateOrganisation(ctx, client.CreateOrganisationRequest{Name: "Acme"})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, org.OrgID)
+	assert.Nil(t, c.AddOrganisationMember(ctx, org.OrgID, other.User.UserID))
+
+	orgs, err := c.ListOrganisations(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, orgs, 1)
+
+	fetched, err := c.GetOrganisation(ctx, org.OrgID)
+	assert.Nil(t, err)
+	assert.Equal(t, "Acme", fetched.Name)
+
+	_, err = c.GetOrganisation(ctx, "missing")
+	var apiErr *client.APIError
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+		assert.Equal(t, "Organisation not found", apiErr.Message)
+		assert.NotEmpty(t, apiErr.RequestID)
+	}
+
+	_, err = c.Login(ctx, "john.doe@example.com", "wrong")
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+	}
+}
+
+func TestClientRefreshesRejectedToken(t *testing.T) {
+	ctx := context.Background()
+	server := newAPIServer(t, nil)
+	registered, err := client.New(server.URL, client.Options{}).Register(ctx, client.RegisterRequest{
+		FirstName: "John", LastName: "Doe", Email: "john.doe@example.com", Password: "password123",
+	})
+	assert.Nil(t, err)
+
+	c := client.New(server.URL, client.Options{
+		Token:    "not-a-valid-token",
+		Email:    "john.doe@example.com",
+		Password: "password123",
+	})
+	user, err := c.GetUser(ctx, registered.User.UserID)
+	assert.Nil(t, err)
+	assert.Equal(t, registered.User.UserID, user.UserID)
+	assert.NotEqual(t, "not-a-valid-token", c.Token())
+
+	_, err = client.New(server.URL, client.Options{}).ListOrganisations(ctx)
+	assert.ErrorIs(t, err, client.ErrNoCredentials)
+}
+
+func TestClientRetriesServerErrors(t *testing.T) {
+	ctx := context.Background()
+	var failures atomic.Int32
+	failures.Store(2)
+	server := newAPIServer(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet && failures.Add(-1) >= 0 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	c := client.New(server.URL, client.Options{MaxRetries: 2, RetryBackoff: 1})
+	_, err := c.Register(ctx, client.RegisterRequest{
+		FirstName: "John", LastName: "Doe", Email: "john.doe@example.com", Password: "password123",
+	})
+	assert.Nil(t, err)
+	_, err = c.ListOrganisations(ctx)
+	assert.Nil(t, err)
+
+	failures.Store(3)
+	_, err = c.ListOrganisations(ctx)
+	var apiErr *client.APIError
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+	}
+}
+
+func TestClientRetriesNetworkErrorsOnlyForReads(t *testing.T) {
+	ctx := context.Background()
+	var drops atomic.Int32
+	server := newAPIServer(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if drops.Add(-1) < 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			// Handle the request, then drop the connection before replying.
+			next.ServeHTTP(httptest.NewRecorder(), r)
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if assert.Nil(t, err) {
+				conn.Close()
+			}
+		})
+	})
+
+	c := client.New(server.URL, client.Options{MaxRetries: 2, RetryBackoff: 1})
+	drops.Store(1)
+	_, err := c.Register(ctx, client.RegisterRequest{
+		FirstName: "John", LastName: "Doe", Email: "john.doe@example.com", Password: "password123",
+	})
+	var apiErr *client.APIError
+	assert.NotNil(t, err)
+	assert.False(t, errors.As(err, &apiErr), "the registration should not have been replayed")
+
+	login, err := c.Login(ctx, "john.doe@example.com", "password123")
+	assert.Nil(t, err)
+	drops.Store(1)
+	_, err = c.GetUser(ctx, login.User.UserID)
+	assert.Nil(t, err)
+}
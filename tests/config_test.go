@@ -0,0 +1,47 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshua468/user-authentication/config"
+)
+
+func TestConfigLoadPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	os.WriteFile(file, []byte("server:\n  port: 9000\ndatabase:\n  host: db.internal\n  password: from-file\njwt:\n  secret: file-secret\n  ttl: 1h\n"), 0o600)
+	envFile := filepath.Join(dir, ".env")
+	os.WriteFile(envFile, []byte("DB_HOST=dotenv-host\nJWT_ISSUER=dotenv-issuer\n"), 0o600)
+
+	t.Setenv("DB_HOST", "env-host")
+	t.Setenv("JWT_LEEWAY", "5s")
+	// godotenv.Load sets JWT_ISSUER on the process; don't leak it.
+	t.Cleanup(func() { os.Unsetenv("JWT_ISSUER") })
+
+	cfg, err := config.Load(file, envFile)
+	assert.Nil(t, err)
+	assert.Equal(t, 9000, cfg.Server.Port)
+	assert.Equal(t, "env-host", cfg.Database.Host)
+	assert.Equal(t, "dotenv-issuer", cfg.JWT.Issuer)
+	assert.Equal(t, time.Hour, cfg.JWT.TTL.Duration())
+	assert.Equal(t, 5*time.Second, cfg.JWT.Leeway.Duration())
+	assert.Equal(t, "prefer", cfg.Database.SSLMode)
+
+	printed := cfg.String()
+	assert.NotContains(t, printed, "from-file")
+	assert.NotContains(t, printed, "file-secret")
+	assert.Contains(t, printed, "[REDACTED]")
+}
+
+func TestConfigRequiresJWTSecret(t *testing.T) {
+	t.Setenv("JWT_SECRET", "")
+	os.Unsetenv("JWT_SECRET")
+
+	_, err := config.Load("", filepath.Join(t.TempDir(), "missing.env"))
+	assert.ErrorContains(t, err, "JWT_SECRET is required")
+}
@@ -0,0 +1,189 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/joshua468/user-authentication/grpcapi"
+	"github.com/joshua468/user-authentication/models"
+	userauthv1 "github.com/joshua468/user-authentication/proto/userauth/v1"
+	"github.com/joshua468/user-authentication/repositories"
+	"github.com/joshua468/user-authentication/services"
+	"github.com/joshua468/user-authentication/utils"
+)
+
+func TestGRPCAPI(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB()
+	userRepo := repositories.NewGormUserRepository(db)
+	users := services.NewUserService(userRepo)
+	orgRepo := repositories.NewGormOrganisationRepository(db)
+	orgs := services.NewOrgService(orgRepo, userRepo)
+	roles := services.NewRoleService(repositories.NewGormRoleRepository(db), orgRepo, repositories.NewGormTeamRepository(db))
+	admins := services.NewAdminService(users, userRepo, orgRepo, nil, nil)
+	tokens := utils.NewJWTService("test-secret", utils.DefaultTokenOptions())
+	auth := services.NewAuthService(users, tokens)
+
+	owner, _, err := auth.Register(ctx, services.CreateUserInput{
+		FirstName: "John", LastName: "Doe", Email: "john.doe@example.com", Password: "password123",
+	})
+	assert.Nil(t, err)
+	org, err := orgs.Create(ctx, owner.UserID, services.CreateOrgInput{Name: "Acme"})
+	assert.Nil(t, err)
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpcapi.NewServer(grpcapi.Services{
+		Auth: auth, Users: users, Orgs: orgs, Roles: roles, Admins: admins, Verifier: tokens,
+	})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+	authClient := userauthv1.NewAuthServiceClient(conn)
+	userClient := userauthv1.NewUserServiceClient(conn)
+	orgClient := userauthv1.NewOrganisationServiceClient(conn)
+
+	_, err = authClient.Login(ctx, &userauthv1.LoginRequest{Email: "john.doe@example.com", Password: "wrong"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	login, err := authClient.Login(ctx, &userauthv1.LoginRequest{Email: "john.doe@example.com", Password: "password123"})
+	assert.Nil(t, err)
+	assert.Equal(t, owner.UserID, login.GetUser().GetUserId())
+
+	verified, err := authClient.VerifyToken(ctx, &userauthv1.VerifyTokenRequest{Token: login.GetAccessToken()})
+	assert.Nil(t, err)
+	assert.True(t, verified.GetValid())
+	assert.Equal(t, owner.UserID, verified.GetUserId())
+	verified, err = authClient.VerifyToken(ctx, &userauthv1.VerifyTokenRequest{Token: "garbage"})
+	assert.Nil(t, err)
+	assert.False(t, verified.GetValid())
+
+	// The interceptor applies the same rules as JWTAuthMiddleware.
+	authCalls := []struct {
+		name   string
+		header string
+		code   codes.Code
+	}{
+		{"missing", "", codes.Unauthenticated},
+		{"basic scheme", "Basic dXNlcjpwYXNz", codes.Unauthenticated},
+		{"empty credentials", "Bearer ", codes.InvalidArgument},
+		{"garbage token", "Bearer abc", codes.Unauthenticated},
+		{"lower case scheme", "bearer " + login.GetAccessToken(), codes.OK},
+	}
+	for _, tt := range authCalls {
+		t.Run(tt.name, func(t *testing.T) {
+			callCtx := ctx
+			if tt.header != "" {
+				callCtx = metadata.AppendToOutgoingContext(ctx, "authorization", tt.header)
+			}
+			_, err := userClient.GetUser(callCtx, &userauthv1.GetUserRequest{UserId: owner.UserID})
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+
+	// Tokens that only allow a password change are refused, and login sends
+	// users who must change theirs to the HTTP API.
+	passwordChange, err := tokens.IssueClaims(&utils.Claims{
+		Scope:            utils.ScopePasswordChange,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: owner.UserID},
+	}, 0)
+	assert.Nil(t, err)
+	verified, err = authClient.VerifyToken(ctx, &userauthv1.VerifyTokenRequest{Token: passwordChange})
+	assert.Nil(t, err)
+	assert.False(t, verified.GetValid())
+	_, err = userClient.GetUser(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+passwordChange), &userauthv1.GetUserRequest{UserId: owner.UserID})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	reset, _, err := auth.Register(ctx, services.CreateUserInput{
+		FirstName: "Jane", LastName: "Doe", Email: "jane.doe@example.com", Password: "password123",
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, users.ResetPassword(ctx, reset.UserID, "temporary123"))
+	_, err = authClient.Login(ctx, &userauthv1.LoginRequest{Email: "jane.doe@example.com", Password: "temporary123"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	authed := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+login.GetAccessToken())
+	refreshed, err := authClient.RefreshToken(authed, &userauthv1.RefreshTokenRequest{})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, refreshed.GetAccessToken())
+
+	list, err := orgClient.ListOrganisations(authed, &userauthv1.ListOrganisationsRequest{})
+	assert.Nil(t, err)
+	if assert.Len(t, list.GetOrganisations(), 1) {
+		assert.Equal(t, org.OrgID, list.GetOrganisations()[0].GetOrgId())
+	}
+
+	membership, err := orgClient.CheckMembership(authed, &userauthv1.CheckMembershipRequest{OrgId: org.OrgID, UserId: owner.UserID})
+	assert.Nil(t, err)
+	assert.True(t, membership.GetMember())
+	_, err = orgClient.GetOrganisation(authed, &userauthv1.GetOrganisationRequest{OrgId: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// Outsiders see neither the organisation nor its members, and only
+	// admins list other users' organisations.
+	stranger, strangerToken, err := auth.Register(ctx, services.CreateUserInput{
+		FirstName: "Eve", LastName: "Doe", Email: "eve.doe@example.com", Password: "password123",
+	})
+	assert.Nil(t, err)
+	outsider := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+strangerToken)
+	_, err = orgClient.GetOrganisation(outsider, &userauthv1.GetOrganisationRequest{OrgId: org.OrgID})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = orgClient.CheckMembership(outsider, &userauthv1.CheckMembershipRequest{OrgId: org.OrgID, UserId: owner.UserID})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	membership, err = orgClient.CheckMembership(outsider, &userauthv1.CheckMembershipRequest{OrgId: org.OrgID, UserId: stranger.UserID})
+	assert.Nil(t, err)
+	assert.False(t, membership.GetMember())
+	_, err = orgClient.ListOrganisations(outsider, &userauthv1.ListOrganisationsRequest{UserId: owner.UserID})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	stranger.IsAdmin = true
+	assert.Nil(t, userRepo.Update(ctx, stranger))
+	list, err = orgClient.ListOrganisations(outsider, &userauthv1.ListOrganisationsRequest{UserId: owner.UserID})
+	assert.Nil(t, err)
+	assert.Len(t, list.GetOrganisations(), 1)
+
+	// A token scoped to one organisation reaches no other, even one its user
+	// belongs to.
+	other, err := orgs.Create(ctx, owner.UserID, services.CreateOrgInput{Name: "Globex"})
+	assert.Nil(t, err)
+	scopedToken, err := tokens.IssueClaims(&utils.Claims{
+		OrgID:            org.OrgID,
+		OrgRole:          models.RoleOwner,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: owner.UserID},
+	}, 0)
+	assert.Nil(t, err)
+	scoped := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+scopedToken)
+	_, err = orgClient.GetOrganisation(scoped, &userauthv1.GetOrganisationRequest{OrgId: org.OrgID})
+	assert.Nil(t, err)
+	_, err = orgClient.GetOrganisation(scoped, &userauthv1.GetOrganisationRequest{OrgId: other.OrgID})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = orgClient.CheckMembership(scoped, &userauthv1.CheckMembershipRequest{OrgId: other.OrgID, UserId: owner.UserID})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Impersonation tokens cannot be swapped for an ordinary token.
+	impersonation, err := tokens.IssueClaims(&utils.Claims{
+		Actor:            &utils.Actor{Subject: "admin"},
+		RegisteredClaims: jwt.RegisteredClaims{Subject: owner.UserID},
+	}, 0)
+	assert.Nil(t, err)
+	impersonated := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+impersonation)
+	_, err = authClient.RefreshToken(impersonated, &userauthv1.RefreshTokenRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = users.SetDisabled(ctx, owner.UserID, true)
+	assert.Nil(t, err)
+	_, err = authClient.RefreshToken(authed, &userauthv1.RefreshTokenRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
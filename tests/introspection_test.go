@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshua468/user-authentication/middlewares"
+	"github.com/joshua468/user-authentication/models"
+	"github.com/joshua468/user-authentication/repositories"
+	"github.com/joshua468/user-authentication/routes"
+	"github.com/joshua468/user-authentication/services"
+)
+
+func introspect(router *gin.Engine, clientID, secret, token string) (int, map[string]interface{}) {
+	form := url.Values{"token": {token}}
+	req, _ := http.NewRequest("POST", "/oauth/introspect", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if clientID != "" {
+		req.SetBasicAuth(clientID, secret)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var body map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	return w.Code, body
+}
+
+func TestIntrospection(t *testing.T) {
+	db := openTestDB()
+	router := gin.New()
+	router.Use(middlewares.RequestID())
+	routes.Register(router, apiHandlers(db))
+
+	client, secret, err := services.NewClientService(repositories.NewGormServiceClientRepository(db), repositories.NewGormExchangePolicyRepository(db)).
+		Create(context.Background(), "billing")
+	assert.Nil(t, err)
+
+	status, body := registerUser(router, models.User{
+		FirstName: "John", LastName: "Doe", Email: "john.doe@example.com", Password: "password123",
+	})
+	assert.Equal(t, http.StatusCreated, status)
+	data := body["data"].(map[string]interface{})
+	token := data["accessToken"].(string)
+	userID := data["user"].(map[string]interface{})["userId"].(string)
+
+	code, _ := introspect(router, client.ClientID, "wrong", token)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = introspect(router, "", "", token)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	org, err := services.NewOrgService(repositories.NewGormOrganisationRepository(db), repositories.NewGormUserRepository(db)).
+		Create(context.Background(), userID, services.CreateOrgInput{Name: "Acme"})
+	assert.Nil(t, err)
+
+	code, result := introspect(router, client.ClientID, secret, token)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, result["active"])
+	assert.Equal(t, userID, result["sub"])
+	assert.NotEmpty(t, result["exp"])
+	assert.Equal(t, []interface{}{org.OrgID}, result["org_ids"])
+
+	code, result = introspect(router, client.ClientID, secret, "not-a-token")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]interface{}{"active": false}, result)
+
+	// After logout the token is inactive and rejected by the API.
+	req, _ := http.NewRequest("POST", "/api/auth/logout", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	_, result = introspect(router, client.ClientID, secret, token)
+	assert.Equal(t, false, result["active"])
+
+	req, _ = http.NewRequest("GET", "/api/users/"+userID, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
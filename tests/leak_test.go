@@ -0,0 +1,215 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshua468/user-authentication/middlewares"
+	"github.com/joshua468/user-authentication/models"
+	"github.com/joshua468/user-authentication/repositories"
+	"github.com/joshua468/user-authentication/routes"
+	"github.com/joshua468/user-authentication/services"
+)
+
+var (
+	// bcrypt hashes, and hex digests such as the SHA-256 client secret hashes.
+	hashLike = regexp.MustCompile(`\$2[abxy]\$\d{2}\$[./A-Za-z0-9]{53}|\b[0-9a-fA-F]{64}\b`)
+	// Field names that only internal structs carry.
+	internalField = regexp.MustCompile(`"(password|secretHash|SecretHash|ID|DeletedAt|users)"\s*:`)
+)
+
+type capturedResponse struct {
+	route string
+	body  string
+}
+
+type captureWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w captureWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// TestResponsesNeverLeakHashes calls every registered route and checks that
+// no response carries a password hash, secret hash or internal model field.
+// A route added without being exercised here fails the test.
+func TestResponsesNeverLeakHashes(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB()
+	var responses []capturedResponse
+
+	router := gin.New()
+	router.Use(middlewares.RequestID(), func(c *gin.Context) {
+		body := &bytes.Buffer{}
+		c.Writer = captureWriter{c.Writer, body}
+		c.Next()
+		responses = append(responses, capturedResponse{c.Request.Method + " " + c.FullPath(), body.String()})
+	})
+	routes.Register(router, apiHandlers(db))
+
+	call := func(method, path, token string, body interface{}) map[string]interface{} {
+		var reader *bytes.Reader
+		if form, ok := body.(url.Values); ok {
+			reader = bytes.NewReader([]byte(form.Encode()))
+		} else {
+			raw, _ := json.Marshal(body)
+			reader = bytes.NewReader(raw)
+		}
+		req, _ := http.NewRequest(method, path, reader)
+		if _, ok := body.(url.Values); ok {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		} else {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if strings.HasPrefix(token, "Basic ") {
+			req.Header.Set("Authorization", token)
+		} else if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Less(t, w.Code, 300, "%s %s: %s", method, path, w.Body.String())
+
+		var decoded map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &decoded)
+		return decoded
+	}
+	data := func(body map[string]interface{}) map[string]interface{} {
+		return body["data"].(map[string]interface{})
+	}
+
+	register := func(first, email string) (string, string) {
+		body := data(call("POST", "/api/auth/register", "", map[string]string{
+			"firstName": first, "lastName": "Doe", "email": email, "password": "password123",
+		}))
+		return body["accessToken"].(string), body["user"].(map[string]interface{})["userId"].(string)
+	}
+	_, janeID := register("Jane", "jane.doe@example.com")
+	register("John", "john.doe@example.com")
+	token := data(call("POST", "/api/auth/login", "", map[string]string{
+		"email": "john.doe@example.com", "password": "password123",
+	}))["accessToken"].(string)
+
+	clients := services.NewClientService(repositories.NewGormServiceClientRepository(db), repositories.NewGormExchangePolicyRepository(db))
+	client, secret, err := clients.Create(ctx, "billing")
+	assert.Nil(t, err)
+	_, err = clients.SetExchangePolicy(ctx, client.ClientID, "invoices", []string{"invoices.read"}, time.Minute)
+	assert.Nil(t, err)
+	_, err = clients.SetScope(ctx, client.ClientID, models.ClientScopeAuthzWrite, true)
+	assert.Nil(t, err)
+
+	call("GET", "/api/users/"+janeID, token, nil)
+	orgID := data(call("POST", "/api/organisations/", token, map[string]string{"name": "Acme"}))["orgId"].(string)
+	call("POST", "/api/organisations/"+orgID+"/users", token, map[string]string{"userId": janeID})
+	call("GET", "/api/organisations/", token, nil)
+	call("GET", "/api/organisations/"+orgID, token, nil)
+	call("GET", "/api/organisations/"+orgID+"/users", token, nil)
+	call("GET", "/api/permissions", token, nil)
+	roleID := data(call("POST", "/api/organisations/"+orgID+"/roles", token, map[string]interface{}{
+		"name": "auditor", "permissions": []string{"org.read"},
+	}))["roleId"].(string)
+	call("GET", "/api/organisations/"+orgID+"/roles", token, nil)
+	call("PATCH", "/api/organisations/"+orgID+"/roles/"+roleID, token, map[string]string{"description": "Read-only"})
+	call("PUT", "/api/organisations/"+orgID+"/users/"+janeID+"/role", token, map[string]string{"role": "auditor"})
+	call("PUT", "/api/organisations/"+orgID+"/users/"+janeID+"/role", token, map[string]string{"role": "member"})
+	call("DELETE", "/api/organisations/"+orgID+"/roles/"+roleID, token, nil)
+	teamPath := "/api/organisations/" + orgID + "/teams/" + data(call("POST", "/api/organisations/"+orgID+"/teams", token, map[string]string{"name": "Engineering"}))["teamId"].(string)
+	call("GET", "/api/organisations/"+orgID+"/teams", token, nil)
+	call("GET", teamPath, token, nil)
+	call("PATCH", teamPath, token, map[string]string{"description": "Builds things"})
+	call("PUT", teamPath+"/members/"+janeID, token, map[string]bool{"maintainer": true})
+	call("GET", teamPath+"/members", token, nil)
+	call("DELETE", teamPath+"/members/"+janeID, token, nil)
+	call("PUT", teamPath+"/role", token, map[string]string{"role": "member"})
+	call("DELETE", teamPath+"/role", token, nil)
+	call("DELETE", teamPath, token, nil)
+	call("POST", "/oauth/introspect", "", url.Values{
+		"token": {token}, "client_id": {client.ClientID}, "client_secret": {secret},
+	})
+	call("POST", "/oauth/token", "", url.Values{
+		"grant_type":         {"urn:ietf:params:oauth:grant-type:token-exchange"},
+		"subject_token":      {token},
+		"subject_token_type": {"urn:ietf:params:oauth:token-type:access_token"},
+		"audience":           {"invoices"},
+		"client_id":          {client.ClientID},
+		"client_secret":      {secret},
+	})
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte(client.ClientID+":"+secret))
+	schema := data(call("GET", "/api/authz/schema", basic, nil))["schema"].(string)
+	call("PUT", "/api/authz/schema", basic, map[string]string{"schema": schema + "definition document {\n relation viewer: user | organisation#member\n}\n"})
+	call("POST", "/api/authz/relationships", basic, map[string]interface{}{"updates": []map[string]interface{}{{
+		"operation": "touch",
+		"relationship": map[string]string{
+			"namespace": "document", "objectId": "readme", "relation": "viewer",
+			"subjectNamespace": "organisation", "subjectId": orgID, "subjectRelation": "member",
+		},
+	}}})
+	call("GET", "/api/authz/relationships?namespace=document", basic, nil)
+	call("POST", "/api/authz/check", basic, map[string]string{
+		"namespace": "document", "objectId": "readme", "permission": "viewer", "subjectNamespace": "user", "subjectId": janeID,
+	})
+	call("POST", "/api/authz/list-objects", basic, map[string]string{
+		"namespace": "document", "permission": "viewer", "subjectNamespace": "user", "subjectId": janeID,
+	})
+	db.Model(&models.User{}).Where("email = ?", "john.doe@example.com").Update("is_admin", true)
+	call("GET", "/api/admin/users", token, nil)
+	call("GET", "/api/admin/organisations", token, nil)
+	call("POST", "/api/admin/users/"+janeID+"/disable", token, nil)
+	call("POST", "/api/admin/users/"+janeID+"/enable", token, nil)
+	temporary := data(call("POST", "/api/admin/users/"+janeID+"/reset-password", token, nil))["temporaryPassword"].(string)
+	// Keep the revocation clear of the second Jane's next token is issued in.
+	db.Model(&models.User{}).Where("user_id = ?", janeID).Update("sessions_revoked_at", time.Now().Add(-time.Second))
+	janeToken := data(call("POST", "/api/auth/login", "", map[string]string{
+		"email": "jane.doe@example.com", "password": temporary,
+	}))["accessToken"].(string)
+	call("POST", "/api/auth/change-password", janeToken, map[string]string{
+		"currentPassword": temporary, "newPassword": "password456",
+	})
+	call("POST", "/api/admin/users/"+janeID+"/revoke-sessions", token, nil)
+	call("POST", "/api/admin/users/"+janeID+"/impersonate", token, nil)
+	call("GET", "/api/admin/audit-events", token, nil)
+	call("POST", "/api/auth/switch-organisation", token, map[string]string{"orgId": orgID})
+	call("POST", "/api/auth/logout", token, nil)
+	for _, path := range []string{"/healthz", "/readyz", "/metrics", "/openapi.json", "/docs"} {
+		call("GET", path, "", nil)
+	}
+
+	var secrets []string
+	var users []models.User
+	db.Find(&users)
+	for _, user := range users {
+		secrets = append(secrets, user.Password)
+	}
+	secrets = append(secrets, client.SecretHash)
+
+	called := map[string]bool{}
+	for _, res := range responses {
+		called[res.route] = true
+		assert.Empty(t, hashLike.FindString(res.body), "%s returned a hash-like string", res.route)
+		for _, s := range secrets {
+			assert.NotContains(t, res.body, s, "%s returned a stored hash", res.route)
+		}
+		if strings.Contains(res.route, " /api/") || strings.Contains(res.route, " /oauth/") {
+			assert.Empty(t, internalField.FindString(res.body), "%s returned an internal field", res.route)
+		}
+	}
+	for _, route := range router.Routes() {
+		op := route.Method + " " + route.Path
+		assert.True(t, called[op], "%s is not exercised by this test", op)
+	}
+}
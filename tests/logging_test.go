@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshua468/user-authentication/logging"
+	"github.com/joshua468/user-authentication/middlewares"
+	"github.com/joshua468/user-authentication/utils"
+)
+
+func TestLoggerRedactsCredentialsAndPersonalData(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, "debug", "json")
+	assert.Nil(t, err)
+
+	token, _ := utils.GenerateToken("user-1", "test-secret")
+	hash, _ := utils.HashPassword("hunter22")
+	ctx := logging.WithImpersonator(logging.WithRequestID(context.Background(), "req-1"), "admin-1")
+	logger.InfoContext(ctx, "login for jane@example.com",
+		"password", "hunter22",
+		"accessToken", token,
+		"phone", "08012345678",
+		"error", errors.New(`duplicate key (email)=(jane@example.com) hash `+hash),
+		"header", "Bearer "+token,
+	)
+
+	out := buf.String()
+	for _, leaked := range []string{"hunter22", token, "jane@example.com", "08012345678", hash} {
+		assert.NotContains(t, out, leaked)
+	}
+
+	var line map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "req-1", line["requestId"])
+	assert.Equal(t, "admin-1", line["impersonatorId"])
+	assert.Equal(t, "[REDACTED]", line["password"])
+}
+
+func TestRequestIDIsPropagatedToResponses(t *testing.T) {
+	router := gin.New()
+	router.Use(middlewares.RequestID())
+	router.GET("/protected", middlewares.JWTAuthMiddleware("test-secret"), func(c *gin.Context) {})
+
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{"caller supplied", "abc-123", true},
+		{"generated", "", false},
+		{"unsafe value replaced", "bad id\r\ninjected", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/protected", nil)
+			if tt.header != "" {
+				req.Header.Set(middlewares.RequestIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			id := w.Header().Get(middlewares.RequestIDHeader)
+			assert.NotEmpty(t, id)
+			if tt.keep {
+				assert.Equal(t, tt.header, id)
+			} else {
+				assert.NotEqual(t, tt.header, id)
+			}
+
+			var body map[string]interface{}
+			assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assert.Equal(t, id, body["requestId"])
+		})
+	}
+}
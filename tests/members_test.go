@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshua468/user-authentication/client"
+)
+
+func TestListOrganisationMembers(t *testing.T) {
+	ctx := context.Background()
+	server := newAPIServer(t, nil)
+
+	register := func(first, email string) (*client.Client, *client.AuthResult) {
+		c := client.New(server.URL, client.Options{})
+		result, err := c.Register(ctx, client.RegisterRequest{
+			FirstName: first, LastName: "Doe", Email: email, Password: "password123",
+		})
+		assert.Nil(t, err)
+		return c, result
+	}
+	owner, _ := register("John", "john.doe@example.com")
+	_, jane := register("Jane", "jane.doe@example.com")
+	outsider, _ := register("Mallory", "mallory@example.net")
+
+	org, err := owner.CreateOrganisation(ctx, client.CreateOrganisationRequest{Name: "Acme"})
+	assert.Nil(t, err)
+	assert.Nil(t, owner.AddOrganisationMember(ctx, org.OrgID, jane.User.UserID))
+
+	page, err := owner.ListOrganisationMembers(ctx, org.OrgID, client.MemberOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), page.Total)
+	if assert.Len(t, page.Items, 2) {
+		assert.Equal(t, "owner", page.Items[0].Role)
+		assert.Equal(t, "member", page.Items[1].Role)
+	}
+
+	page, err = owner.ListOrganisationMembers(ctx, org.OrgID, client.MemberOptions{Role: "member"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), page.Total)
+
+	page, err = owner.ListOrganisationMembers(ctx, org.OrgID, client.MemberOptions{PageOptions: client.PageOptions{Search: "JANE.DOE"}})
+	assert.Nil(t, err)
+	if assert.Len(t, page.Items, 1) {
+		assert.Equal(t, jane.User.UserID, page.Items[0].UserID)
+	}
+
+	_, err = outsider.ListOrganisationMembers(ctx, org.OrgID, client.MemberOptions{})
+	var apiErr *client.APIError
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+	}
+	_, err = owner.ListOrganisationMembers(ctx, "missing", client.MemberOptions{})
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	}
+
+	// The member listing never carries password hashes.
+	req, _ := http.NewRequest("GET", server.URL+"/api/organisations/"+org.OrgID+"/users", nil)
+	req.Header.Set("Authorization", "Bearer "+owner.Token())
+	res, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	assert.NotContains(t, string(body), "password")
+	assert.NotContains(t, string(body), "$2a$")
+}
@@ -0,0 +1,44 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshua468/user-authentication/metrics"
+	"github.com/joshua468/user-authentication/middlewares"
+	"github.com/joshua468/user-authentication/utils"
+)
+
+func TestMetricsLabelRequestsByRouteAndTokenFailure(t *testing.T) {
+	tokens := utils.NewJWTService("test-secret", utils.DefaultTokenOptions())
+	router := gin.New()
+	router.Use(middlewares.Metrics())
+	router.GET("/items/:id", middlewares.JWTAuthMiddlewareWithConfig(middlewares.JWTConfig{Verifier: tokens}), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	expired := utils.DefaultTokenOptions()
+	expired.TTL = -utils.DefaultTokenOptions().Leeway * 2
+	expiredToken, err := utils.NewJWTService("test-secret", expired).IssueToken("user-1")
+	assert.Nil(t, err)
+
+	before := testutil.ToFloat64(metrics.TokenValidations.WithLabelValues("expired"))
+	beforeRequests := testutil.CollectAndCount(metrics.HTTPRequestDuration)
+
+	for _, id := range []string{"1", "2"} {
+		req, _ := http.NewRequest("GET", "/items/"+id, nil)
+		req.Header.Set("Authorization", "Bearer "+expiredToken)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+
+	assert.Equal(t, before+2, testutil.ToFloat64(metrics.TokenValidations.WithLabelValues("expired")))
+	// Both requests share the /items/:id series rather than one per path.
+	assert.LessOrEqual(t, testutil.CollectAndCount(metrics.HTTPRequestDuration), beforeRequests+1)
+}
@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/joshua468/user-authentication/migrations"
+)
+
+func openMigrationTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "migrations.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Error connecting to database: %v", err)
+	}
+	return db
+}
+
+func TestMigrationsUpAndDown(t *testing.T) {
+	db := openMigrationTestDB(t)
+	ctx := context.Background()
+
+	migrator, err := migrations.New(db)
+	assert.Nil(t, err)
+
+	applied, err := migrator.Up(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, applied, len(migrator.Migrations()))
+	assert.True(t, db.Migrator().HasTable("users"))
+
+	version, err := migrator.Version(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, migrator.Latest(), version)
+
+	applied, err = migrator.Up(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, applied)
+
+	reverted, err := migrator.Down(ctx, len(migrator.Migrations()))
+	assert.Nil(t, err)
+	assert.Len(t, reverted, len(migrator.Migrations()))
+	assert.False(t, db.Migrator().HasTable("users"))
+
+	statuses, err := migrator.Status(ctx)
+	assert.Nil(t, err)
+	for _, status := range statuses {
+		assert.Nil(t, status.AppliedAt)
+	}
+}
+
+func TestMigrationsConcurrentUp(t *testing.T) {
+	db := openMigrationTestDB(t)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			migrator, err := migrations.New(db)
+			if err == nil {
+				_, err = migrator.Up(context.Background())
+			}
+			errs[i] = err
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.Nil(t, err)
+	}
+	var count int64
+	db.Table("schema_migrations").Count(&count)
+	migrator, _ := migrations.New(db)
+	assert.Equal(t, int64(len(migrator.Migrations())), count)
+}
+
+func TestMigrationsBackfillOrganisationOwners(t *testing.T) {
+	db := openMigrationTestDB(t)
+	ctx := context.Background()
+	migrator, err := migrations.New(db)
+	assert.Nil(t, err)
+	_, err = migrator.Up(ctx)
+	assert.Nil(t, err)
+
+	// Memberships as 0005 left them: everyone a plain member.
+	for _, stmt := range []string{
+		`INSERT INTO users (id, user_id, first_name, last_name, email, password) VALUES
+			(1, 'u1', 'John', 'Doe', 'john@example.com', 'x'),
+			(2, 'u2', 'Jane', 'Doe', 'jane@example.com', 'x')`,
+		`INSERT INTO organisations (id, org_id, name) VALUES (1, 'o1', 'Acme'), (2, 'o2', 'Globex')`,
+		`INSERT INTO organisation_users (organisation_id, user_id, role) VALUES
+			(1, 2, 'member'), (1, 1, 'member'), (2, 1, 'member'), (2, 2, 'owner')`,
+	} {
+		assert.Nil(t, db.Exec(stmt).Error)
+	}
+	_, err = migrator.Down(ctx, int(migrator.Latest()-11))
+	assert.Nil(t, err)
+	_, err = migrator.Up(ctx)
+	assert.Nil(t, err)
+
+	var owners []struct{ OrganisationID, UserID uint }
+	assert.Nil(t, db.Table("organisation_users").Where("role = ?", "owner").
+		Order("organisation_id").Find(&owners).Error)
+	assert.Equal(t, []struct{ OrganisationID, UserID uint }{{1, 1}, {2, 2}}, owners)
+}
@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshua468/user-authentication/controllers"
+	"github.com/joshua468/user-authentication/docs"
+	"github.com/joshua468/user-authentication/routes"
+)
+
+var ginParam = regexp.MustCompile(`:(\w+)`)
+
+func specOperations(t *testing.T) map[string]bool {
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(docs.Spec, &spec); err != nil {
+		t.Fatalf("docs/openapi.json is not valid JSON: %v", err)
+	}
+	ops := map[string]bool{}
+	for path, item := range spec.Paths {
+		for method := range item {
+			ops[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+	return ops
+}
+
+// TestOpenAPICoversEveryRoute fails when a route is registered without being
+// documented, or documented without being registered.
+func TestOpenAPICoversEveryRoute(t *testing.T) {
+	router := gin.New()
+	routes.Register(router, routes.Handlers{
+		Auth:          &controllers.AuthController{},
+		Users:         &controllers.UserController{},
+		Organisations: &controllers.OrganisationController{},
+		Health:        controllers.NewHealthController(),
+		OAuth:         &controllers.OAuthController{},
+		Admin:         &controllers.AdminController{},
+		Authenticate:  func(c *gin.Context) {},
+		RequireAdmin:  func(c *gin.Context) {},
+	})
+
+	documented := specOperations(t)
+	registered := map[string]bool{}
+	for _, route := range router.Routes() {
+		op := route.Method + " " + ginParam.ReplaceAllString(route.Path, "{$1}")
+		registered[op] = true
+		assert.True(t, documented[op], "%s is not described in docs/openapi.json", op)
+	}
+	for op := range documented {
+		assert.True(t, registered[op], "docs/openapi.json describes %s, which is not registered", op)
+	}
+
+	req, _ := http.NewRequest("GET", "/openapi.json", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+}
@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshua468/user-authentication/client"
+	"github.com/joshua468/user-authentication/models"
+	"github.com/joshua468/user-authentication/utils"
+)
+
+func TestOrganisationScopedTokens(t *testing.T) {
+	ctx := context.Background()
+	server := newAPIServer(t, nil)
+	john := client.New(server.URL, client.Options{})
+	jane := client.New(server.URL, client.Options{})
+
+	_, err := john.Register(ctx, client.RegisterRequest{
+		FirstName: "John", LastName: "Doe", Email: "john.doe@example.com", Password: "password123",
+	})
+	assert.Nil(t, err)
+	janeUser, err := jane.Register(ctx, client.RegisterRequest{
+		FirstName: "Jane", LastName: "Doe", Email: "jane.doe@example.com", Password: "password123",
+	})
+	assert.Nil(t, err)
+	acme, err := john.CreateOrganisation(ctx, client.CreateOrganisationRequest{Name: "Acme"})
+	assert.Nil(t, err)
+	globex, err := john.CreateOrganisation(ctx, client.CreateOrganisationRequest{Name: "Globex"})
+	assert.Nil(t, err)
+	assert.Nil(t, john.AddOrganisationMember(ctx, acme.OrgID, janeUser.User.UserID))
+
+	statusOf := func(err error) int {
+		var apiErr *client.APIError
+		if assert.ErrorAs(t, err, &apiErr) {
+			return apiErr.StatusCode
+		}
+		return 0
+	}
+
+	switched, err := john.SwitchOrganisation(ctx, acme.OrgID)
+	assert.Nil(t, err)
+	assert.Equal(t, models.RoleOwner, switched.Role)
+	claims, err := utils.ParseToken(john.Token(), "test-secret")
+	assert.Nil(t, err)
+	assert.Equal(t, acme.OrgID, claims.OrgID)
+	assert.Equal(t, models.RoleOwner, claims.OrgRole)
+
+	// The scoped token reaches its own organisation only.
+	_, err = john.GetOrganisation(ctx, acme.OrgID)
+	assert.Nil(t, err)
+	_, err = john.ListOrganisationMembers(ctx, acme.OrgID, client.MemberOptions{})
+	assert.Nil(t, err)
+	_, err = john.GetOrganisation(ctx, globex.OrgID)
+	assert.Equal(t, http.StatusForbidden, statusOf(err))
+	assert.Equal(t, http.StatusForbidden, statusOf(john.AddOrganisationMember(ctx, globex.OrgID, janeUser.User.UserID)))
+
+	// Switching again re-issues the token for the new organisation.
+	_, err = john.SwitchOrganisation(ctx, globex.OrgID)
+	assert.Nil(t, err)
+	_, err = john.GetOrganisation(ctx, globex.OrgID)
+	assert.Nil(t, err)
+	_, err = john.GetOrganisation(ctx, acme.OrgID)
+	assert.Equal(t, http.StatusForbidden, statusOf(err))
+
+	switched, err = john.SwitchOrganisation(ctx, "")
+	assert.Nil(t, err)
+	assert.Empty(t, switched.OrgID)
+	_, err = john.GetOrganisation(ctx, acme.OrgID)
+	assert.Nil(t, err)
+
+	switched, err = jane.SwitchOrganisation(ctx, acme.OrgID)
+	assert.Nil(t, err)
+	assert.Equal(t, models.RoleMember, switched.Role)
+	_, err = jane.SwitchOrganisation(ctx, globex.OrgID)
+	assert.Equal(t, http.StatusForbidden, statusOf(err))
+	_, err = jane.SwitchOrganisation(ctx, "missing")
+	assert.Equal(t, http.StatusNotFound, statusOf(err))
+}
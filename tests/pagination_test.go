@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshua468/user-authentication/client"
+)
+
+func TestOrganisationListingPagination(t *testing.T) {
+	ctx := context.Background()
+	server := newAPIServer(t, nil)
+	c := client.New(server.URL, client.Options{})
+	_, err := c.Register(ctx, client.RegisterRequest{
+		FirstName: "John", LastName: "Doe", Email: "john.doe@example.com", Password: "password123",
+	})
+	assert.Nil(t, err)
+
+	names := []string{"Acme", "Globex", "Acme Labs", "Initech", "ACME_West"}
+	for _, name := range names {
+		_, err := c.CreateOrganisation(ctx, client.CreateOrganisationRequest{Name: name})
+		assert.Nil(t, err)
+	}
+
+	var seen []string
+	opts := client.PageOptions{Limit: 2}
+	for {
+		page, err := c.ListOrganisationsPage(ctx, opts)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(len(names)), page.Total)
+		assert.LessOrEqual(t, len(page.Items), 2)
+		for _, org := range page.Items {
+			seen = append(seen, org.Name)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+	assert.Equal(t, names, seen)
+
+	newest, err := c.ListOrganisationsPage(ctx, client.PageOptions{Limit: 1, Sort: "-createdAt"})
+	assert.Nil(t, err)
+	assert.Equal(t, "ACME_West", newest.Items[0].Name)
+
+	// Cursors continue the sort they were issued for, and only that one.
+	var byName []string
+	opts = client.PageOptions{Limit: 2, Sort: "-name"}
+	for {
+		page, err := c.ListOrganisationsPage(ctx, opts)
+		assert.Nil(t, err)
+		for _, org := range page.Items {
+			byName = append(byName, org.Name)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+	assert.Equal(t, []string{"Initech", "Globex", "Acme Labs", "Acme", "ACME_West"}, byName)
+	first, err := c.ListOrganisationsPage(ctx, client.PageOptions{Limit: 2, Sort: "name"})
+	assert.Nil(t, err)
+	mixed := client.PageOptions{Cursor: first.NextCursor, Sort: "createdAt"}
+
+	matches, err := c.ListOrganisationsPage(ctx, client.PageOptions{Search: "acme"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), matches.Total)
+	assert.Empty(t, matches.NextCursor)
+
+	// "_" is matched literally, not as a LIKE wildcard.
+	matches, err = c.ListOrganisationsPage(ctx, client.PageOptions{Search: "e_w"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), matches.Total)
+
+	all, err := c.ListOrganisations(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, all, len(names))
+
+	for _, opts := range []client.PageOptions{{Cursor: "not-a-cursor"}, {Sort: "email"}, mixed} {
+		_, err = c.ListOrganisationsPage(ctx, opts)
+		var apiErr *client.APIError
+		if assert.ErrorAs(t, err, &apiErr) {
+			assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+		}
+	}
+}
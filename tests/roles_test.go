@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshua468/user-authentication/client"
+)
+
+func TestCustomRoles(t *testing.T) {
+	ctx := context.Background()
+	server := newAPIServer(t, nil)
+
+	register := func(first, email string) (*client.Client, string) {
+		c := client.New(server.URL, client.Options{})
+		result, err := c.Register(ctx, client.RegisterRequest{
+			FirstName: first, LastName: "Doe", Email: email, Password: "password123",
+		})
+		assert.Nil(t, err)
+		return c, result.User.UserID
+	}
+	john, johnID := register("John", "john.doe@example.com")
+	jane, janeID := register("Jane", "jane.doe@example.com")
+	bob, bobID := register("Bob", "bob.doe@example.com")
+	statusOf := func(err error) int {
+		var apiErr *client.APIError
+		if assert.ErrorAs(t, err, &apiErr) {
+			return apiErr.StatusCode
+		}
+		return 0
+	}
+	name := func(s string) *string { return &s }
+
+	org, err := john.CreateOrganisation(ctx, client.CreateOrganisationRequest{Name: "Acme"})
+	assert.Nil(t, err)
+	assert.Nil(t, john.AddOrganisationMember(ctx, org.OrgID, janeID))
+
+	// Members may look but not invite.
+	_, err = jane.GetOrganisation(ctx, org.OrgID)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusForbidden, statusOf(jane.AddOrganisationMember(ctx, org.OrgID, bobID)))
+	_, err = bob.GetOrganisation(ctx, org.OrgID)
+	assert.Equal(t, http.StatusForbidden, statusOf(err))
+
+	roles, err := john.ListRoles(ctx, org.OrgID)
+	assert.Nil(t, err)
+	if assert.Len(t, roles, 3) {
+		assert.Equal(t, "owner", roles[0].Name)
+		assert.True(t, roles[0].BuiltIn)
+	}
+
+	recruiter, err := john.CreateRole(ctx, org.OrgID, client.RoleRequest{
+		Name:        name("recruiter"),
+		Permissions: []string{"org.members.invite", "org.read", "org.members.read", "org.read"},
+	})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, recruiter.RoleID)
+	assert.Equal(t, []string{"org.members.invite", "org.members.read", "org.read"}, recruiter.Permissions)
+
+	_, err = john.CreateRole(ctx, org.OrgID, client.RoleRequest{Name: name("recruiter"), Permissions: []string{"org.read"}})
+	assert.Equal(t, http.StatusConflict, statusOf(err))
+	_, err = john.CreateRole(ctx, org.OrgID, client.RoleRequest{Name: name("admin"), Permissions: []string{"org.read"}})
+	assert.Equal(t, http.StatusBadRequest, statusOf(err))
+	_, err = john.CreateRole(ctx, org.OrgID, client.RoleRequest{Name: name("billing"), Permissions: []string{"org.billing"}})
+	assert.Equal(t, http.StatusBadRequest, statusOf(err))
+	_, err = jane.CreateRole(ctx, org.OrgID, client.RoleRequest{Name: name("billing"), Permissions: []string{"org.read"}})
+	assert.Equal(t, http.StatusForbidden, statusOf(err))
+
+	// The custom role lets Jane invite, from the database or from a token
+	// scoped to the organisation.
+	assert.Nil(t, john.AssignRole(ctx, org.OrgID, janeID, "recruiter"))
+	assert.Nil(t, jane.AddOrganisationMember(ctx, org.OrgID, bobID))
+	_, err = jane.SwitchOrganisation(ctx, org.OrgID)
+	assert.Nil(t, err)
+	_, err = jane.ListOrganisationMembers(ctx, org.OrgID, client.MemberOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusForbidden, statusOf(jane.AssignRole(ctx, org.OrgID, bobID, "recruiter")))
+
+	page, err := john.ListOrganisationMembers(ctx, org.OrgID, client.MemberOptions{Role: "recruiter"})
+	assert.Nil(t, err)
+	if assert.Len(t, page.Items, 1) {
+		assert.Equal(t, janeID, page.Items[0].UserID)
+	}
+
+	// Nobody grants permissions they do not hold.
+	_, err = john.CreateRole(ctx, org.OrgID, client.RoleRequest{
+		Name: name("role-manager"), Permissions: []string{"org.read", "org.roles.manage"},
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, john.AssignRole(ctx, org.OrgID, bobID, "role-manager"))
+	_, err = bob.CreateRole(ctx, org.OrgID, client.RoleRequest{Name: name("inviter"), Permissions: []string{"org.members.invite"}})
+	assert.Equal(t, http.StatusForbidden, statusOf(err))
+	_, err = bob.UpdateRole(ctx, org.OrgID, recruiter.RoleID, client.RoleRequest{Description: name("Hires people")})
+	assert.Equal(t, http.StatusForbidden, statusOf(err))
+	viewer, err := bob.CreateRole(ctx, org.OrgID, client.RoleRequest{Name: name("viewer"), Permissions: []string{"org.read"}})
+	assert.Nil(t, err)
+	assert.Nil(t, bob.DeleteRole(ctx, org.OrgID, viewer.RoleID))
+
+	// Renaming moves the holders; a role in use cannot be deleted.
+	renamed, err := john.UpdateRole(ctx, org.OrgID, recruiter.RoleID, client.RoleRequest{Name: name("hiring")})
+	assert.Nil(t, err)
+	assert.Equal(t, "hiring", renamed.Name)
+	page, err = john.ListOrganisationMembers(ctx, org.OrgID, client.MemberOptions{Role: "hiring"})
+	assert.Nil(t, err)
+	assert.Len(t, page.Items, 1)
+	// Jane's scoped token still names the old role, so it is refused.
+	_, err = jane.ListOrganisationMembers(ctx, org.OrgID, client.MemberOptions{})
+	assert.Equal(t, http.StatusUnauthorized, statusOf(err))
+	assert.Equal(t, http.StatusConflict, statusOf(john.DeleteRole(ctx, org.OrgID, recruiter.RoleID)))
+	assert.Equal(t, http.StatusNotFound, statusOf(john.DeleteRole(ctx, org.OrgID, "missing")))
+	assert.Equal(t, http.StatusNotFound, statusOf(john.AssignRole(ctx, org.OrgID, janeID, "missing")))
+
+	// Only owners touch the owner role, and the last owner stays.
+	_, err = jane.SwitchOrganisation(ctx, "")
+	assert.Nil(t, err)
+	assert.Nil(t, john.AssignRole(ctx, org.OrgID, janeID, "admin"))
+	assert.Equal(t, http.StatusForbidden, statusOf(jane.AssignRole(ctx, org.OrgID, bobID, "owner")))
+	assert.Equal(t, http.StatusForbidden, statusOf(jane.AssignRole(ctx, org.OrgID, johnID, "member")))
+	assert.Nil(t, jane.AssignRole(ctx, org.OrgID, bobID, "member"))
+	assert.Equal(t, http.StatusConflict, statusOf(john.AssignRole(ctx, org.OrgID, johnID, "admin")))
+	assert.Nil(t, john.AssignRole(ctx, org.OrgID, janeID, "owner"))
+	assert.Nil(t, jane.AssignRole(ctx, org.OrgID, johnID, "member"))
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshua468/user-authentication/models"
+	"github.com/joshua468/user-authentication/repositories"
+	"github.com/joshua468/user-authentication/services"
+	"github.com/joshua468/user-authentication/utils"
+)
+
+// memoryUserRepository is an in-memory repositories.UserRepository for
+// exercising services without a database.
+type memoryUserRepository struct {
+	users map[string]*models.User
+}
+
+func newMemoryUserRepository() *memoryUserRepository {
+	return &memoryUserRepository{users: map[string]*models.User{}}
+}
+
+func (r *memoryUserRepository) Create(ctx context.Context, user *models.User) error {
+	copied := *user
+	r.users[user.UserID] = &copied
+	return nil
+}
+
+func (r *memoryUserRepository) FindByUserID(ctx context.Context, userID string) (*models.User, error) {
+	user, ok := r.users[userID]
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *memoryUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, repositories.ErrNotFound
+}
+
+func (r *memoryUserRepository) List(ctx context.Context) ([]models.User, error) {
+	var users []models.User
+	for _, user := range r.users {
+		users = append(users, *user)
+	}
+	return users, nil
+}
+
+func (r *memoryUserRepository) Page(ctx context.Context, q repositories.PageQuery) (repositories.Page[models.User], error) {
+	users, _ := r.List(ctx)
+	return repositories.Page[models.User]{Items: users, Total: int64(len(users))}, nil
+}
+
+func (r *memoryUserRepository) Update(ctx context.Context, user *models.User) error {
+	return r.Create(ctx, user)
+}
+
+func TestAuthServiceWithoutDatabase(t *testing.T) {
+	ctx := context.Background()
+	tokens := utils.NewJWTService("secret", utils.DefaultTokenOptions())
+	users := services.NewUserService(newMemoryUserRepository())
+	auth := services.NewAuthService(users, tokens)
+
+	user, token, err := auth.Register(ctx, services.CreateUserInput{
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "john.doe@example.com",
+		Password:  "password123",
+	})
+	assert.Nil(t, err)
+	claims, err := tokens.VerifyToken(token)
+	assert.Nil(t, err)
+	assert.Equal(t, user.UserID, claims.UserID)
+
+	_, _, err = auth.Login(ctx, "john.doe@example.com", "wrong")
+	assert.ErrorIs(t, err, services.ErrInvalidCredentials)
+
+	_, err = users.SetDisabled(ctx, user.UserID, true)
+	assert.Nil(t, err)
+	_, _, err = auth.Login(ctx, "john.doe@example.com", "password123")
+	assert.ErrorIs(t, err, services.ErrUserDisabled)
+
+	_, err = users.Get(ctx, "missing")
+	assert.ErrorIs(t, err, services.ErrUserNotFound)
+}
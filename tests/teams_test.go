@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshua468/user-authentication/client"
+)
+
+func TestTeams(t *testing.T) {
+	ctx := context.Background()
+	server := newAPIServer(t, nil)
+
+	register := func(first, email string) (*client.Client, string) {
+		c := client.New(server.URL, client.Options{})
+		result, err := c.Register(ctx, client.RegisterRequest{
+			FirstName: first, LastName: "Doe", Email: email, Password: "password123",
+		})
+		assert.Nil(t, err)
+		return c, result.User.UserID
+	}
+	john, _ := register("John", "john.doe@example.com")
+	jane, janeID := register("Jane", "jane.doe@example.com")
+	bob, bobID := register("Bob", "bob.doe@example.com")
+	_, eveID := register("Eve", "eve.doe@example.com")
+	statusOf := func(err error) int {
+		var apiErr *client.APIError
+		if assert.ErrorAs(t, err, &apiErr) {
+			return apiErr.StatusCode
+		}
+		return 0
+	}
+	str := func(s string) *string { return &s }
+
+	org, err := john.CreateOrganisation(ctx, client.CreateOrganisationRequest{Name: "Acme"})
+	assert.Nil(t, err)
+	for _, id := range []string{janeID, bobID, eveID} {
+		assert.Nil(t, john.AddOrganisationMember(ctx, org.OrgID, id))
+	}
+
+	// Teams nest, and only members who manage teams create them.
+	_, err = jane.CreateTeam(ctx, org.OrgID, client.TeamRequest{Name: str("Engineering")})
+	assert.Equal(t, http.StatusForbidden, statusOf(err))
+	eng, err := john.CreateTeam(ctx, org.OrgID, client.TeamRequest{Name: str("Engineering")})
+	assert.Nil(t, err)
+	assert.Nil(t, eng.ParentID)
+	platform, err := john.CreateTeam(ctx, org.OrgID, client.TeamRequest{Name: str("Platform"), ParentID: &eng.TeamID})
+	assert.Nil(t, err)
+	if assert.NotNil(t, platform.ParentID) {
+		assert.Equal(t, eng.TeamID, *platform.ParentID)
+	}
+	_, err = john.CreateTeam(ctx, org.OrgID, client.TeamRequest{Name: str("Platform")})
+	assert.Equal(t, http.StatusConflict, statusOf(err))
+	_, err = john.CreateTeam(ctx, org.OrgID, client.TeamRequest{Name: str(" Platform")})
+	assert.Equal(t, http.StatusBadRequest, statusOf(err))
+	_, err = john.CreateTeam(ctx, org.OrgID, client.TeamRequest{Name: str("Design"), Description: str(strings.Repeat("é", 256))})
+	assert.Equal(t, http.StatusBadRequest, statusOf(err))
+	_, err = john.UpdateTeam(ctx, org.OrgID, eng.TeamID, client.TeamRequest{Description: str(strings.Repeat("é", 256))})
+	assert.Equal(t, http.StatusBadRequest, statusOf(err))
+	_, err = john.UpdateTeam(ctx, org.OrgID, eng.TeamID, client.TeamRequest{Description: str(strings.Repeat("é", 255))})
+	assert.Nil(t, err)
+	teams, err := jane.ListTeams(ctx, org.OrgID)
+	assert.Nil(t, err)
+	assert.Len(t, teams, 2)
+
+	// A role granted to a team reaches the members of the teams nested in it,
+	// whether their token is scoped to the organisation or not.
+	_, err = john.CreateRole(ctx, org.OrgID, client.RoleRequest{
+		Name: str("recruiter"), Permissions: []string{"org.read", "org.members.read", "org.members.invite"},
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, john.AssignTeamRole(ctx, org.OrgID, eng.TeamID, "recruiter"))
+	assert.Equal(t, http.StatusBadRequest, statusOf(john.AssignTeamRole(ctx, org.OrgID, eng.TeamID, "owner")))
+	assert.Equal(t, http.StatusNotFound, statusOf(john.AssignTeamRole(ctx, org.OrgID, eng.TeamID, "missing")))
+	_, newcomerID := register("Newcomer", "newcomer@example.com")
+	assert.Equal(t, http.StatusForbidden, statusOf(bob.AddOrganisationMember(ctx, org.OrgID, newcomerID)))
+	assert.Nil(t, john.SetTeamMember(ctx, org.OrgID, platform.TeamID, bobID, false))
+	_, err = bob.SwitchOrganisation(ctx, org.OrgID)
+	assert.Nil(t, err)
+	assert.Nil(t, bob.AddOrganisationMember(ctx, org.OrgID, newcomerID))
+	_, err = bob.SwitchOrganisation(ctx, "")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusConflict, statusOf(john.DeleteRole(ctx, org.OrgID, mustRoleID(t, john, org.OrgID, "recruiter"))))
+
+	// Maintainers manage their team's members and details, but do not move it
+	// or hand out permissions they lack.
+	assert.Nil(t, john.SetTeamMember(ctx, org.OrgID, platform.TeamID, janeID, true))
+	assert.Nil(t, jane.SetTeamMember(ctx, org.OrgID, platform.TeamID, eveID, false))
+	assert.Equal(t, http.StatusForbidden, statusOf(bob.SetTeamMember(ctx, org.OrgID, platform.TeamID, bobID, true)))
+	assert.Equal(t, http.StatusNotFound, statusOf(jane.SetTeamMember(ctx, org.OrgID, platform.TeamID, "missing", false)))
+	renamed, err := jane.UpdateTeam(ctx, org.OrgID, platform.TeamID, client.TeamRequest{Name: str("Infrastructure")})
+	assert.Nil(t, err)
+	assert.Equal(t, "Infrastructure", renamed.Name)
+	_, err = jane.UpdateTeam(ctx, org.OrgID, platform.TeamID, client.TeamRequest{ParentID: str("")})
+	assert.Equal(t, http.StatusForbidden, statusOf(err))
+	assert.Equal(t, http.StatusForbidden, statusOf(jane.AssignTeamRole(ctx, org.OrgID, platform.TeamID, "admin")))
+
+	members, err := bob.ListTeamMembers(ctx, org.OrgID, platform.TeamID)
+	assert.Nil(t, err)
+	if assert.Len(t, members, 3) {
+		assert.Equal(t, janeID, members[0].UserID)
+		assert.True(t, members[0].Maintainer)
+		assert.False(t, members[1].Maintainer)
+	}
+	assert.Nil(t, jane.RemoveTeamMember(ctx, org.OrgID, platform.TeamID, eveID))
+	assert.Equal(t, http.StatusNotFound, statusOf(jane.RemoveTeamMember(ctx, org.OrgID, platform.TeamID, eveID)))
+
+	// Nesting stays a tree, and parents outlive their children.
+	_, err = john.UpdateTeam(ctx, org.OrgID, eng.TeamID, client.TeamRequest{ParentID: &platform.TeamID})
+	assert.Equal(t, http.StatusConflict, statusOf(err))
+	assert.Equal(t, http.StatusConflict, statusOf(john.DeleteTeam(ctx, org.OrgID, eng.TeamID)))
+	moved, err := john.UpdateTeam(ctx, org.OrgID, platform.TeamID, client.TeamRequest{ParentID: str("")})
+	assert.Nil(t, err)
+	assert.Nil(t, moved.ParentID)
+	assert.Equal(t, http.StatusForbidden, statusOf(bob.AddOrganisationMember(ctx, org.OrgID, newcomerID)))
+	assert.Nil(t, john.DeleteTeam(ctx, org.OrgID, eng.TeamID))
+	_, err = john.GetTeam(ctx, org.OrgID, eng.TeamID)
+	assert.Equal(t, http.StatusNotFound, statusOf(err))
+}
+
+// mustRoleID returns the ID of orgID's custom role called name.
+func mustRoleID(t *testing.T, c *client.Client, orgID, name string) string {
+	roles, err := c.ListRoles(context.Background(), orgID)
+	assert.Nil(t, err)
+	for _, role := range roles {
+		if role.Name == name {
+			return role.RoleID
+		}
+	}
+	t.Fatalf("no role %q", name)
+	return ""
+}
@@ -0,0 +1,204 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshua468/user-authentication/middlewares"
+	"github.com/joshua468/user-authentication/models"
+	"github.com/joshua468/user-authentication/repositories"
+	"github.com/joshua468/user-authentication/routes"
+	"github.com/joshua468/user-authentication/services"
+	"github.com/joshua468/user-authentication/utils"
+)
+
+func TestTokenExchange(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB()
+	router := gin.New()
+	router.Use(middlewares.RequestID())
+	routes.Register(router, apiHandlers(db))
+
+	clients := services.NewClientService(repositories.NewGormServiceClientRepository(db), repositories.NewGormExchangePolicyRepository(db))
+	billing, billingSecret, err := clients.Create(ctx, "billing")
+	assert.Nil(t, err)
+	invoices, invoicesSecret, err := clients.Create(ctx, "invoices")
+	assert.Nil(t, err)
+	_, err = clients.SetExchangePolicy(ctx, billing.ClientID, "invoices", []string{"invoices.read", "invoices.write"}, 5*time.Minute)
+	assert.Nil(t, err)
+
+	_, body := registerUser(router, models.User{
+		FirstName: "John", LastName: "Doe", Email: "john.doe@example.com", Password: "password123",
+	})
+	userToken := body["data"].(map[string]interface{})["accessToken"].(string)
+
+	exchange := func(clientID, secret string, params url.Values) (int, map[string]interface{}) {
+		form := url.Values{
+			"grant_type":         {"urn:ietf:params:oauth:grant-type:token-exchange"},
+			"subject_token":      {userToken},
+			"subject_token_type": {"urn:ietf:params:oauth:token-type:access_token"},
+			"audience":           {"invoices"},
+		}
+		for key, values := range params {
+			form[key] = values
+		}
+		req, _ := http.NewRequest("POST", "/oauth/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(clientID, secret)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var body map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code, body
+	}
+
+	code, body := exchange(billing.ClientID, billingSecret, url.Values{"scope": {"invoices.read"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Bearer", body["token_type"])
+	assert.Equal(t, "invoices.read", body["scope"])
+	assert.Equal(t, float64(300), body["expires_in"])
+	exchanged := body["access_token"].(string)
+
+	// The invoices service sees a down-scoped token for itself.
+	code, body = introspect(router, invoices.ClientID, invoicesSecret, exchanged)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, body["active"])
+	assert.Equal(t, []interface{}{"invoices"}, body["aud"])
+	assert.Equal(t, billing.ClientID, body["client_id"])
+
+	// It is addressed to another service, so this API refuses it.
+	req, _ := http.NewRequest("GET", "/api/organisations/", nil)
+	req.Header.Set("Authorization", "Bearer "+exchanged)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Without a scope, every scope the policy allows is granted.
+	code, body = exchange(billing.ClientID, billingSecret, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "invoices.read invoices.write", body["scope"])
+
+	// A scoped subject token cannot be widened.
+	claims, err := utils.ParseToken(userToken, "test-secret")
+	assert.Nil(t, err)
+	scoped, err := utils.NewJWTService("test-secret", utils.DefaultTokenOptions()).IssueClaims(&utils.Claims{
+		Scope:            "invoices.read",
+		RegisteredClaims: jwt.RegisteredClaims{Subject: claims.Subject},
+	}, time.Minute)
+	assert.Nil(t, err)
+	code, body = exchange(billing.ClientID, billingSecret, url.Values{"subject_token": {scoped}, "scope": {"invoices.write"}})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "invalid_scope", body["error"])
+	code, body = exchange(billing.ClientID, billingSecret, url.Values{"subject_token": {scoped}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "invoices.read", body["scope"])
+	assert.LessOrEqual(t, body["expires_in"], float64(60), "the exchanged token must not outlive the subject token")
+
+	// A token without a scope would be unrestricted, so none is issued.
+	disjoint, err := utils.NewJWTService("test-secret", utils.DefaultTokenOptions()).IssueClaims(&utils.Claims{
+		Scope:            "payroll.read",
+		RegisteredClaims: jwt.RegisteredClaims{Subject: claims.Subject},
+	}, time.Minute)
+	assert.Nil(t, err)
+	code, body = exchange(billing.ClientID, billingSecret, url.Values{"subject_token": {disjoint}})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "invalid_scope", body["error"])
+	_, err = clients.SetExchangePolicy(ctx, invoices.ClientID, "billing", nil, time.Minute)
+	assert.ErrorIs(t, err, services.ErrInvalidPolicy)
+
+	// Impersonation tokens are refused even while the admin may use them.
+	registerUser(router, models.User{FirstName: "Ada", LastName: "Admin", Email: "ada@example.com", Password: "password123"})
+	var ada models.User
+	db.Where("email = ?", "ada@example.com").First(&ada)
+	db.Model(&ada).Update("is_admin", true)
+	impersonation, err := utils.NewJWTService("test-secret", utils.DefaultTokenOptions()).IssueClaims(&utils.Claims{
+		Actor:            &utils.Actor{Subject: ada.UserID},
+		RegisteredClaims: jwt.RegisteredClaims{Subject: claims.Subject},
+	}, time.Minute)
+	assert.Nil(t, err)
+	code, body = exchange(billing.ClientID, billingSecret, url.Values{"subject_token": {impersonation}})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "invalid_grant", body["error"])
+	assert.Equal(t, services.ErrImpersonatedSubject.Error(), body["error_description"])
+
+	rejected := []struct {
+		name   string
+		client string
+		secret string
+		params url.Values
+		code   int
+		error  string
+	}{
+		{"wrong secret", billing.ClientID, "wrong", nil, http.StatusUnauthorized, "invalid_client"},
+		{"no policy for client", invoices.ClientID, invoicesSecret, nil, http.StatusBadRequest, "invalid_target"},
+		{"no policy for audience", billing.ClientID, billingSecret, url.Values{"audience": {"payroll"}}, http.StatusBadRequest, "invalid_target"},
+		{"scope not in policy", billing.ClientID, billingSecret, url.Values{"scope": {"invoices.delete"}}, http.StatusBadRequest, "invalid_scope"},
+		{"invalid subject token", billing.ClientID, billingSecret, url.Values{"subject_token": {"garbage"}}, http.StatusBadRequest, "invalid_grant"},
+		{"exchanged subject token", billing.ClientID, billingSecret, url.Values{"subject_token": {exchanged}}, http.StatusBadRequest, "invalid_grant"},
+		{"wrong grant type", billing.ClientID, billingSecret, url.Values{"grant_type": {"client_credentials"}}, http.StatusBadRequest, "unsupported_grant_type"},
+		{"wrong subject token type", billing.ClientID, billingSecret, url.Values{"subject_token_type": {"urn:ietf:params:oauth:token-type:id_token"}}, http.StatusBadRequest, "invalid_request"},
+		{"missing audience", billing.ClientID, billingSecret, url.Values{"audience": {""}}, http.StatusBadRequest, "invalid_request"},
+	}
+	for _, tt := range rejected {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := exchange(tt.client, tt.secret, tt.params)
+			assert.Equal(t, tt.code, code)
+			assert.Equal(t, tt.error, body["error"])
+		})
+	}
+
+	// Removing the policy stops further exchanges.
+	assert.Nil(t, clients.RemoveExchangePolicy(ctx, billing.ClientID, "invoices"))
+	code, body = exchange(billing.ClientID, billingSecret, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "invalid_target", body["error"])
+}
+
+func TestExchangedTokensKeepTheirOrganisationAndStayOffThisAPI(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB()
+	opts := utils.DefaultTokenOptions()
+	opts.Audience = "api"
+	signer := utils.NewJWTService("test-secret", opts)
+	userRepo := repositories.NewGormUserRepository(db)
+	tokens := services.NewTokenService(signer, repositories.NewGormRevokedTokenRepository(db), userRepo, repositories.NewGormOrganisationRepository(db))
+	user, err := services.NewUserService(userRepo).Create(ctx, services.CreateUserInput{
+		FirstName: "John", LastName: "Doe", Email: "john.doe@example.com", Password: "password123",
+	})
+	assert.Nil(t, err)
+
+	// An org-scoped subject token stays scoped to its organisation.
+	subject, err := signer.IssueClaims(&utils.Claims{
+		OrgID:            "org-1",
+		OrgRole:          models.RoleMember,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: user.UserID},
+	}, time.Minute)
+	assert.Nil(t, err)
+	// A policy may name this API's own audience.
+	policy := &models.ExchangePolicy{ClientID: "billing", Audience: "api", Scopes: "invoices.read", MaxTTLSeconds: 60}
+	exchanged, err := tokens.Exchange(ctx, subject, policy, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "org-1", exchanged.Claims.OrgID)
+	assert.Equal(t, models.RoleMember, exchanged.Claims.OrgRole)
+
+	// The exchanged token is still refused here, where scopes are not
+	// enforced.
+	router := setupProtectedRouter(middlewares.JWTConfig{Verifier: signer, Checker: tokens})
+	req, _ := http.NewRequest("GET", "/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+exchanged.Token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), utils.ErrTokenExchanged.Error())
+}
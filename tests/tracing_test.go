@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/joshua468/user-authentication/controllers"
+	"github.com/joshua468/user-authentication/services"
+	"github.com/joshua468/user-authentication/utils"
+)
+
+func TestRegisterIsTracedStepByStep(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	users := services.NewUserService(newMemoryUserRepository())
+	auth := controllers.NewAuthController(services.NewAuthService(users, utils.NewJWTService("secret", utils.DefaultTokenOptions())))
+	router := gin.New()
+	router.Use(otelgin.Middleware("test"))
+	router.POST("/register", auth.Register)
+
+	req, _ := http.NewRequest("POST", "/register", bytes.NewBufferString(
+		`{"firstName":"John","lastName":"Doe","email":"john.doe@example.com","password":"password123"}`))
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	}
+	controller := spans["AuthController.Register"]
+	if assert.NotNil(t, controller) {
+		assert.Equal(t, spans["/register"].SpanContext().SpanID(), controller.Parent().SpanID())
+	}
+	for _, step := range []string{"bcrypt.Hash", "jwt.Issue"} {
+		if assert.Contains(t, spans, step) {
+			assert.Equal(t, controller.SpanContext().SpanID(), spans[step].Parent().SpanID())
+		}
+	}
+}
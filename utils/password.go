@@ -1,8 +1,15 @@
 package utils
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/joshua468/user-authentication/metrics"
+)
 
 func HashPassword(password string) (string, error) {
+	defer metrics.ObservePasswordHash("hash", time.Now())
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return "", err
@@ -11,5 +18,6 @@ func HashPassword(password string) (string, error) {
 }
 
 func ComparePassword(hashedPassword, password string) error {
+	defer metrics.ObservePasswordHash("compare", time.Now())
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
@@ -1,38 +1,359 @@
 package utils
 
 import (
+	"errors"
+	"slices"
+	"sort"
+	"sync"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt/v5"
 )
 
+var (
+	ErrTokenMalformed        = errors.New("token is malformed")
+	ErrTokenAlgorithm        = errors.New("token signing algorithm is not allowed")
+	ErrTokenSignatureInvalid = errors.New("token signature is invalid")
+	ErrTokenExpired          = errors.New("token is expired")
+	ErrTokenNotValidYet      = errors.New("token is not valid yet")
+	ErrTokenIssuer           = errors.New("token issuer is invalid")
+	ErrTokenAudience         = errors.New("token audience is invalid")
+	ErrTokenLegacy           = errors.New("legacy token format is no longer accepted")
+	ErrTokenUnknownKey       = errors.New("token signing key is unknown")
+	ErrTokenExchanged        = errors.New("token was issued by token exchange for another service")
+
+	// Returned by checks made after verification, against the database.
+	ErrTokenRevoked         = errors.New("token has been revoked")
+	ErrTokenSubjectDisabled = errors.New("account is disabled")
+	ErrTokenSubjectUnknown  = errors.New("account no longer exists")
+	ErrTokenActorRevoked    = errors.New("impersonating admin is no longer authorised")
+)
+
+// ScopePasswordChange is the only scope of the tokens Login issues to users
+// who must change their password. They are refused everywhere but the
+// change-password endpoint.
+const ScopePasswordChange = "password.change"
+
+// TokenRejected reports whether err, from a check made after verification,
+// means the token must be refused rather than that the check failed.
+func TokenRejected(err error) bool {
+	return errors.Is(err, ErrTokenRevoked) ||
+		errors.Is(err, ErrTokenSubjectDisabled) ||
+		errors.Is(err, ErrTokenSubjectUnknown) ||
+		errors.Is(err, ErrTokenActorRevoked)
+}
+
+// Claims is the payload of an access token. Current tokens identify the user
+// through the registered "sub" claim; UserID is only populated by tokens
+// issued before the migration off github.com/dgrijalva/jwt-go and is copied
+// from Subject on verification so callers can keep using it.
 type Claims struct {
-	UserID string `json:"userId"`
-	jwt.StandardClaims
+	UserID string `json:"userId,omitempty"`
+	// Scope is a space-separated list of scopes, as in RFC 8693. Tokens
+	// issued by Login and Register carry none and are unrestricted, except
+	// that Login issues ScopePasswordChange alone to users who must change
+	// their password.
+	Scope string `json:"scope,omitempty"`
+	// Actor is set on impersonation tokens and identifies the admin acting
+	// as the subject, as in RFC 8693 section 4.1.
+	Actor *Actor `json:"act,omitempty"`
+	// ClientID is set on tokens issued by token exchange and names the
+	// service client that requested them (RFC 9068 section 2.2).
+	ClientID string `json:"client_id,omitempty"`
+	// OrgID and OrgRole are set on tokens scoped to one organisation and
+	// hold its orgId and the user's role there when the token was issued.
+	OrgID   string `json:"org_id,omitempty"`
+	OrgRole string `json:"org_role,omitempty"`
+	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID, secret string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
-	claims := &Claims{
-		UserID: userID,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expirationTime.Unix(),
-		},
+// Actor is the RFC 8693 "act" claim.
+type Actor struct {
+	Subject string `json:"sub"`
+}
+
+// PasswordChangeOnly reports whether the token only allows changing the
+// user's password.
+func (c *Claims) PasswordChangeOnly() bool {
+	return c.Scope == ScopePasswordChange
+}
+
+// ActorID returns the impersonating admin's userId, or "" for a token the
+// subject obtained themselves.
+func (c *Claims) ActorID() string {
+	if c.Actor == nil {
+		return ""
+	}
+	return c.Actor.Subject
+}
+
+// TokenIssuer creates signed access tokens for a user.
+type TokenIssuer interface {
+	IssueToken(userID string) (string, error)
+	IssueClaims(claims *Claims, ttl time.Duration) (string, error)
+}
+
+// TokenVerifier validates an access token and returns its claims.
+type TokenVerifier interface {
+	VerifyToken(tokenString string) (*Claims, error)
+}
+
+// TokenSigner verifies tokens and issues them with arbitrary claims, as
+// JWTService does.
+type TokenSigner interface {
+	TokenVerifier
+	// VerifyTokenAnyAudience is VerifyToken without the audience check.
+	VerifyTokenAnyAudience(tokenString string) (*Claims, error)
+	IssueClaims(claims *Claims, ttl time.Duration) (string, error)
+}
+
+// TokenOptions controls the registered claims written into issued tokens and
+// the checks applied when they are verified. Tokens in the legacy format
+// (no "sub", no "iss"/"aud") are accepted until LegacyUntil; a zero value
+// rejects them outright. Without an Audience, tokens that name one, such as
+// those from token exchange, are addressed to another service and refused.
+type TokenOptions struct {
+	Issuer            string
+	Audience          string
+	AllowedAlgorithms []string
+	Leeway            time.Duration
+	TTL               time.Duration
+	LegacyUntil       time.Time
+}
+
+// DefaultTokenOptions returns HS256-only options with a 24 hour lifetime and
+// 30 seconds of clock-skew tolerance.
+func DefaultTokenOptions() TokenOptions {
+	return TokenOptions{
+		AllowedAlgorithms: []string{jwt.SigningMethodHS256.Alg()},
+		Leeway:            30 * time.Second,
+		TTL:               24 * time.Hour,
+	}
+}
+
+// SigningKey is an HMAC key identified by the "kid" token header.
+type SigningKey struct {
+	ID        string
+	Secret    []byte
+	NotBefore time.Time
+}
+
+// JWTService issues and verifies HMAC-signed JWTs. It implements both
+// TokenIssuer and TokenVerifier.
+//
+// Tokens are signed with the newest key from SetKeys whose NotBefore has
+// passed, or with the static secret when there is none. Tokens without a
+// "kid" header are always verified against the static secret.
+type JWTService struct {
+	secret []byte
+	opts   TokenOptions
+
+	mu   sync.RWMutex
+	keys []SigningKey
+}
+
+func NewJWTService(secret string, opts TokenOptions) *JWTService {
+	return &JWTService{secret: []byte(secret), opts: opts}
+}
+
+// SetKeys replaces the rotating key set. It is safe to call while tokens are
+// being issued and verified.
+func (s *JWTService) SetKeys(keys []SigningKey) {
+	keys = append([]SigningKey(nil), keys...)
+	sort.Slice(keys, func(i, j int) bool { return keys[i].NotBefore.After(keys[j].NotBefore) })
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+// KeyCount returns the number of rotating keys currently loaded.
+func (s *JWTService) KeyCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.keys)
+}
+
+// HasSigningKey reports whether tokens can be issued, either from a loaded
+// rotating key or the static secret.
+func (s *JWTService) HasSigningKey() bool {
+	_, secret := s.signingKey(time.Now())
+	return len(secret) > 0
+}
+
+func (s *JWTService) signingKey(now time.Time) (string, []byte) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, key := range s.keys {
+		if !key.NotBefore.After(now) {
+			return key.ID, key.Secret
+		}
+	}
+	return "", s.secret
+}
+
+func (s *JWTService) verificationKey(kid string) ([]byte, error) {
+	if kid == "" {
+		return s.secret, nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, key := range s.keys {
+		if key.ID == kid {
+			return key.Secret, nil
+		}
+	}
+	return nil, ErrTokenUnknownKey
+}
+
+func (s *JWTService) IssueToken(userID string) (string, error) {
+	return s.IssueClaims(&Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: userID}}, 0)
+}
+
+// IssueClaims signs claims after filling in the registered claims: issuer,
+// issued-at, not-before, expiry ttl from now (the configured TTL if ttl is
+// zero) and a fresh token ID. The configured audience is used unless claims
+// already name one.
+func (s *JWTService) IssueClaims(claims *Claims, ttl time.Duration) (string, error) {
+	if ttl <= 0 {
+		ttl = s.opts.TTL
+	}
+	now := time.Now()
+	kid, secret := s.signingKey(now)
+	// Only legacy tokens carry userId; verified claims have it copied from sub.
+	claims.UserID = ""
+	claims.Issuer = s.opts.Issuer
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.NotBefore = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(ttl))
+	claims.ID = GenerateUUID()
+	if len(claims.Audience) == 0 && s.opts.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{s.opts.Audience}
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	return token.SignedString(secret)
 }
 
-func ParseToken(tokenString, secret string) (*Claims, error) {
-	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
-	})
+func (s *JWTService) VerifyToken(tokenString string) (*Claims, error) {
+	return s.verify(tokenString, true)
+}
+
+// VerifyTokenAnyAudience accepts tokens addressed to any audience. It is for
+// introspection, where the calling service checks "aud" itself.
+func (s *JWTService) VerifyTokenAnyAudience(tokenString string) (*Claims, error) {
+	return s.verify(tokenString, false)
+}
+
+func (s *JWTService) verify(tokenString string, checkAudience bool) (*Claims, error) {
+	peek := &Claims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, peek); err != nil {
+		return nil, ErrTokenMalformed
+	}
+	if peek.Subject == "" && peek.UserID != "" {
+		return s.verifyLegacy(tokenString)
+	}
+
+	claims, err := s.parse(tokenString, s.parserOptions(true, checkAudience))
 	if err != nil {
 		return nil, err
 	}
-	if !token.Valid {
+	if checkAudience && s.opts.Audience == "" && len(claims.Audience) > 0 {
+		return nil, ErrTokenAudience
+	}
+	claims.UserID = claims.Subject
+	return claims, nil
+}
+
+// verifyLegacy accepts tokens minted by the old GenerateToken, which carried
+// only "userId" and "exp", while the transition window is open. Such tokens
+// never named an issuer or audience, so one that does and names another is
+// refused.
+func (s *JWTService) verifyLegacy(tokenString string) (*Claims, error) {
+	if s.opts.LegacyUntil.IsZero() || time.Now().After(s.opts.LegacyUntil) {
+		return nil, ErrTokenLegacy
+	}
+	claims, err := s.parse(tokenString, s.parserOptions(false, false))
+	if err != nil {
 		return nil, err
 	}
+	if claims.Issuer != "" && claims.Issuer != s.opts.Issuer {
+		return nil, ErrTokenIssuer
+	}
+	if len(claims.Audience) > 0 && !slices.Contains(claims.Audience, s.opts.Audience) {
+		return nil, ErrTokenAudience
+	}
+	claims.Subject = claims.UserID
 	return claims, nil
 }
+
+func (s *JWTService) parserOptions(checkIssuer, checkAudience bool) []jwt.ParserOption {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(s.opts.AllowedAlgorithms),
+		jwt.WithLeeway(s.opts.Leeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if checkIssuer && s.opts.Issuer != "" {
+		options = append(options, jwt.WithIssuer(s.opts.Issuer))
+	}
+	if checkAudience && s.opts.Audience != "" {
+		options = append(options, jwt.WithAudience(s.opts.Audience))
+	}
+	return options
+}
+
+func (s *JWTService) parse(tokenString string, options []jwt.ParserOption) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrTokenAlgorithm
+		}
+		kid, _ := token.Header["kid"].(string)
+		return s.verificationKey(kid)
+	}, options...)
+	if err != nil {
+		return nil, tokenError(err)
+	}
+	return claims, nil
+}
+
+func GenerateToken(userID, secret string) (string, error) {
+	return NewJWTService(secret, DefaultTokenOptions()).IssueToken(userID)
+}
+
+func ParseToken(tokenString, secret string) (*Claims, error) {
+	return NewJWTService(secret, DefaultTokenOptions()).VerifyToken(tokenString)
+}
+
+func tokenError(err error) error {
+	switch {
+	case errors.Is(err, ErrTokenUnknownKey):
+		return ErrTokenUnknownKey
+	case errors.Is(err, ErrTokenAlgorithm), errors.Is(err, jwt.ErrTokenUnverifiable):
+		return ErrTokenAlgorithm
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return ErrTokenMalformed
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		// A disallowed "alg" is reported as ErrTokenSignatureInvalid without
+		// the underlying HMAC mismatch error.
+		if errors.Is(err, jwt.ErrSignatureInvalid) {
+			return ErrTokenSignatureInvalid
+		}
+		return ErrTokenAlgorithm
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return ErrTokenExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return ErrTokenNotValidYet
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return ErrTokenIssuer
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return ErrTokenAudience
+	case errors.Is(err, jwt.ErrTokenRequiredClaimMissing):
+		return ErrTokenMalformed
+	default:
+		return err
+	}
+}